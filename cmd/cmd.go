@@ -16,9 +16,11 @@ package cmd
 
 import (
 	"bufio"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -28,6 +30,7 @@ import (
 
 	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
 	"github.com/sftpgo/sftpgo-plugin-eventstore/db/migration"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/export"
 	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
 )
 
@@ -45,9 +48,24 @@ var (
 	driver          string
 	instanceID      string
 	dsn             string
+	dsnFile         string
+	passwordFile    string
 	customTLSConfig string
 	poolSize        int
+	tablePrefix     string
+	schemaName      string
 	retention       int
+	dbRequired      bool
+	sinkRoutes      string
+
+	exportFrom         string
+	exportTo           string
+	exportOutputDir    string
+	exportTables       cli.StringSlice
+	exportRowGroupSize int
+	exportCompression  string
+	exportRowsPerFile  int
+	exportCheckpoint   string
 
 	dbFlags = []cli.Flag{
 		&cli.StringFlag{
@@ -59,14 +77,25 @@ var (
 		},
 		&cli.StringFlag{
 			Name:        "dsn",
-			Usage:       "Data source URI (required)",
+			Usage:       "Data source URI. Required if dsn-file is not set",
 			Destination: &dsn,
 			EnvVars:     []string{envPrefix + "DSN"},
-			Required:    true,
+		},
+		&cli.StringFlag{
+			Name:        "dsn-file",
+			Usage:       "Path to a file containing the data source URI, alternative to dsn",
+			Destination: &dsnFile,
+			EnvVars:     []string{envPrefix + "DSN_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "password-file",
+			Usage:       "Path to a file containing the database password, it overrides the password in the data source URI (optional)",
+			Destination: &passwordFile,
+			EnvVars:     []string{envPrefix + "PASSWORD_FILE"},
 		},
 		&cli.StringFlag{
 			Name:        "custom-tls",
-			Usage:       "Custom TLS config for MySQL driver (optional)",
+			Usage:       "Custom TLS config for the database connections, see the README for the syntax (optional)",
 			Destination: &customTLSConfig,
 			EnvVars:     []string{envPrefix + "CUSTOM_TLS"},
 			Required:    false,
@@ -78,6 +107,19 @@ var (
 			EnvVars:     []string{envPrefix + "POOL_SIZE"},
 			Required:    false,
 		},
+		&cli.StringFlag{
+			Name:        "table-prefix",
+			Usage:       "Prefix for the table names, allows independent event stores in the same database",
+			Value:       db.DefaultTablePrefix,
+			Destination: &tablePrefix,
+			EnvVars:     []string{envPrefix + "TABLE_PREFIX"},
+		},
+		&cli.StringFlag{
+			Name:        "schema",
+			Usage:       "Schema for the tables, created if missing, PostgreSQL only. Empty means the default schema",
+			Destination: &schemaName,
+			EnvVars:     []string{envPrefix + "SCHEMA"},
+		},
 	}
 
 	serveFlags = append(dbFlags,
@@ -93,6 +135,68 @@ var (
 			Destination: &retention,
 			EnvVars:     []string{envPrefix + "RETENTION"},
 		},
+		&cli.BoolFlag{
+			Name:        "database-required",
+			Usage:       "If set, a database error fails the event notification and SFTPGo will retry it",
+			Value:       true,
+			Destination: &dbRequired,
+			EnvVars:     []string{envPrefix + "DATABASE_REQUIRED"},
+		},
+		&cli.StringFlag{
+			Name:        "sink-routes",
+			Usage:       "Events to write to each sink as a JSON object keyed by sink name. Sinks without a route get all events",
+			Destination: &sinkRoutes,
+			EnvVars:     []string{envPrefix + "SINK_ROUTES"},
+		},
+	)
+
+	exportFlags = append(dbFlags,
+		&cli.StringFlag{
+			Name:        "from",
+			Usage:       `Export events newer than or equal to this time. RFC 3339 format or "YYYY-MM-DD" (required)`,
+			Destination: &exportFrom,
+			Required:    true,
+		},
+		&cli.StringFlag{
+			Name:        "to",
+			Usage:       `Export events older than this time. RFC 3339 format or "YYYY-MM-DD" (required)`,
+			Destination: &exportTo,
+			Required:    true,
+		},
+		&cli.StringFlag{
+			Name:        "output-dir",
+			Usage:       "Root directory for the exported files (required)",
+			Destination: &exportOutputDir,
+			Required:    true,
+		},
+		&cli.StringSliceFlag{
+			Name:        "tables",
+			Usage:       `Tables to export. Supported values: "fs", "provider", "log". Empty means all tables`,
+			Destination: &exportTables,
+		},
+		&cli.IntFlag{
+			Name:        "row-group-size",
+			Usage:       "Maximum number of rows for each parquet row group",
+			Value:       10000,
+			Destination: &exportRowGroupSize,
+		},
+		&cli.StringFlag{
+			Name:        "compression",
+			Usage:       `Parquet compression codec. Supported values: "none", "snappy", "gzip", "zstd"`,
+			Value:       export.CompressionSnappy,
+			Destination: &exportCompression,
+		},
+		&cli.IntFlag{
+			Name:        "rows-per-file",
+			Usage:       "Maximum number of rows exported before closing the current files and saving the checkpoint",
+			Value:       100000,
+			Destination: &exportRowsPerFile,
+		},
+		&cli.StringFlag{
+			Name:        "checkpoint",
+			Usage:       "Path to a checkpoint file, allows to resume an interrupted export (optional)",
+			Destination: &exportCheckpoint,
+		},
 	)
 
 	rootCmd = &cli.App{
@@ -103,11 +207,19 @@ var (
 			{
 				Name:  "serve",
 				Usage: "Launch the SFTPGo plugin, it must be called from an SFTPGo instance",
-				Flags: serveFlags,
+				Flags: slices.Concat(serveFlags, instanceFlags, leaderFlags, geoIPFlags, rollupFlags, detectionFlags, rulesFlags, jsonlFlags, kafkaFlags, natsFlags, webhookFlags, syslogFlags, openSearchFlags),
 				Action: func(_ *cli.Context) error {
 					logger.AppLogger.Info("starting sftpgo-plugin-eventstore", "version", getVersionString(),
 						"database driver", driver, "instance id", instanceID, "pool size", poolSize)
-					if err := db.Initialize(driver, dsn, customTLSConfig, false, poolSize); err != nil {
+					if err := validateRollups(); err != nil {
+						logger.AppLogger.Error("invalid rollups configuration", "error", err)
+						return err
+					}
+					if err := validateLeaseTTL(); err != nil {
+						logger.AppLogger.Error("invalid lease configuration", "error", err)
+						return err
+					}
+					if err := initializeDatabase(false); err != nil {
 						logger.AppLogger.Error("unable to initialize database", "error", err)
 						return err
 					}
@@ -115,21 +227,53 @@ var (
 						logger.AppLogger.Error("unable to migrate database", "error", err)
 						return err
 					}
+					if err := watchCredentials(); err != nil {
+						logger.AppLogger.Error("unable to watch database credentials", "error", err)
+						return err
+					}
+					sinks, err := getSinks()
+					if err != nil {
+						logger.AppLogger.Error("unable to initialize sinks", "error", err)
+						return err
+					}
+					geoIP, err := getGeoIPResolver()
+					if err != nil {
+						logger.AppLogger.Error("unable to initialize GeoIP", "error", err)
+						return err
+					}
+					instance, err := registerInstance()
+					if err != nil {
+						logger.AppLogger.Error("unable to initialize instances registry", "error", err)
+						return err
+					}
+					if instance != nil {
+						go sendHeartbeats(instance)
+					}
+					startLeaderElection()
+					// the backfill could take a long time, SFTPGo must not wait for it
+					go maintainEventTime()
 					if retention > 0 {
 						go dbCleanup(retention)
 					} else {
 						logger.AppLogger.Debug("retention not set, no event will be deleted")
 					}
+					if rollups {
+						go maintainRollups()
+					}
 
 					plugin.Serve(&plugin.ServeConfig{
 						HandshakeConfig: notifier.Handshake,
 						Plugins: map[string]plugin.Plugin{
 							notifier.PluginName: &notifier.Plugin{Impl: &db.Notifier{
 								InstanceID: instanceID,
+								Sinks:      sinks,
+								GeoIP:      geoIP,
 							}},
 						},
 						GRPCServer: plugin.DefaultGRPCServer,
 					})
+					stopLeaderElection()
+					closeAll()
 
 					return errors.New("the plugin exited unexpectedly")
 				},
@@ -139,7 +283,7 @@ var (
 				Usage: "Apply database schema migrations",
 				Flags: dbFlags,
 				Action: func(_ *cli.Context) error {
-					if err := db.Initialize(driver, dsn, customTLSConfig, true, poolSize); err != nil {
+					if err := initializeDatabase(true); err != nil {
 						logger.AppLogger.Error("unable to initialize database", "error", err)
 						return err
 					}
@@ -147,7 +291,7 @@ var (
 						logger.AppLogger.Error("unable to migrate database", "error", err)
 						return err
 					}
-					return nil
+					return backfillEventTime()
 				},
 			},
 			{
@@ -156,7 +300,7 @@ var (
 				Flags: dbFlags,
 				Action: func(_ *cli.Context) error {
 					fmt.Println("You are about to delete all database data and schema", "driver", fmt.Sprintf("%#v", driver),
-						"dsn", fmt.Sprintf("%#v", dsn), "Are you sure?")
+						"dsn", fmt.Sprintf("%#v", dsn), "dsn file", fmt.Sprintf("%#v", dsnFile), "Are you sure?")
 					fmt.Println("Y/n")
 					reader := bufio.NewReader(os.Stdin)
 					answer, err := reader.ReadString('\n')
@@ -168,7 +312,7 @@ var (
 						fmt.Println("Aborted!")
 						return errors.New("command aborted")
 					}
-					if err := db.Initialize(driver, dsn, customTLSConfig, true, poolSize); err != nil {
+					if err := initializeDatabase(true); err != nil {
 						logger.AppLogger.Error("unable to initialize database", "error", err)
 						return err
 					}
@@ -179,6 +323,51 @@ var (
 					return nil
 				},
 			},
+			{
+				Name:  "export",
+				Usage: "Export the events within a time range as Hive partitioned parquet files",
+				Flags: exportFlags,
+				Action: func(_ *cli.Context) error {
+					from, err := parseTime(exportFrom)
+					if err != nil {
+						return err
+					}
+					to, err := parseTime(exportTo)
+					if err != nil {
+						return err
+					}
+					if err := initializeDatabase(false); err != nil {
+						logger.AppLogger.Error("unable to initialize database", "error", err)
+						return err
+					}
+					err = export.Run(export.Config{
+						OutputDir:      exportOutputDir,
+						From:           from,
+						To:             to,
+						Tables:         exportTables.Value(),
+						RowGroupSize:   exportRowGroupSize,
+						Compression:    exportCompression,
+						RowsPerFile:    exportRowsPerFile,
+						CheckpointFile: exportCheckpoint,
+						CreatedBy:      "sftpgo-plugin-eventstore version " + getVersionString(),
+					})
+					if err != nil {
+						logger.AppLogger.Error("unable to export events", "error", err)
+						return err
+					}
+					return nil
+				},
+			},
+			webhookCmd,
+			historyCmd,
+			snapshotCmd,
+			sessionCmd,
+			reportCmd,
+			rollupCmd,
+			alertsCmd,
+			rulesCmd,
+			backfillGeoIPCmd,
+			instancesCmd,
 		},
 	}
 )
@@ -188,14 +377,130 @@ func Execute() error {
 	return rootCmd.Run(os.Args)
 }
 
+func getSinks() ([]db.SinkConfig, error) {
+	routes, err := getSinkRoutes()
+	if err != nil {
+		return nil, err
+	}
+	sinks := []db.SinkConfig{
+		{
+			Sink:     &db.DatabaseSink{},
+			Required: dbRequired,
+		},
+	}
+	sinkGetters := []func() (*db.SinkConfig, error){
+		getJSONLSink,
+		getKafkaSink,
+		getNATSSink,
+		getWebhookSink,
+		getSyslogSink,
+		getOpenSearchSink,
+		getDetectionSink,
+		getRulesSink,
+	}
+	for _, getSink := range sinkGetters {
+		sink, err := getSink()
+		if err != nil {
+			return nil, err
+		}
+		if sink != nil {
+			sinks = append(sinks, *sink)
+		}
+	}
+	for idx := range sinks {
+		name := sinks[idx].Sink.Name()
+		if route, ok := routes[name]; ok {
+			sinks[idx].Route = route
+			delete(routes, name)
+		}
+		logger.AppLogger.Info("sink enabled", "name", name, "required", sinks[idx].Required,
+			"route", sinks[idx].Route != nil)
+	}
+	for name := range routes {
+		return nil, fmt.Errorf("route defined for sink %q not enabled", name)
+	}
+	return sinks, nil
+}
+
+func getSinkRoutes() (map[string]*db.Route, error) {
+	routes := make(map[string]*db.Route)
+	if sinkRoutes == "" {
+		return routes, nil
+	}
+	if err := json.Unmarshal([]byte(sinkRoutes), &routes); err != nil {
+		return nil, fmt.Errorf("unable to parse sink routes: %w", err)
+	}
+	for name, route := range routes {
+		if route == nil {
+			return nil, fmt.Errorf("invalid route for sink %q", name)
+		}
+		if err := route.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid route for sink %q: %w", name, err)
+		}
+	}
+	return routes, nil
+}
+
+// initializeDatabase sets the table prefix and the schema and initializes the
+// database handle
+func initializeDatabase(dbDebug bool) error {
+	if err := db.SetTablePrefix(tablePrefix); err != nil {
+		return err
+	}
+	if err := db.SetSchema(schemaName); err != nil {
+		return err
+	}
+	migration.SetTablePrefix(tablePrefix)
+	migration.SetSchema(schemaName)
+	dsnValue, password, err := readCredentials()
+	if err != nil {
+		return err
+	}
+	return db.Initialize(driver, dsnValue, password, customTLSConfig, dbDebug, poolSize)
+}
+
+func backfillEventTime() error {
+	logger.AppLogger.Debug("start event time backfill")
+	if err := migration.BackfillEventTime(db.Handle); err != nil {
+		logger.AppLogger.Error("unable to backfill event time", "error", err)
+		return err
+	}
+	logger.AppLogger.Debug("event time backfill completed")
+	return nil
+}
+
+// maintainEventTime runs the event time backfill once this instance holds the
+// maintenance lease, so only one instance updates the existing events
+func maintainEventTime() {
+	for !isMaintenanceLeader() {
+		time.Sleep(leaseTTL / 3)
+	}
+	backfillEventTime()
+}
+
 func dbCleanup(retentionHours int) {
 	logger.AppLogger.Debug("start event retention check, old events will be checked every hour",
 		"retention (hours)", retentionHours)
 	for range time.Tick(1 * time.Hour) {
+		if !isMaintenanceLeader() {
+			logger.AppLogger.Debug("maintenance lease not held, skipping event retention check")
+			continue
+		}
 		db.Cleanup(time.Now().Add(-time.Duration(retentionHours) * time.Hour))
 	}
 }
 
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return t, fmt.Errorf("invalid time %q, use RFC 3339 format or YYYY-MM-DD", value)
+	}
+	return t, nil
+}
+
 func getVersionString() string {
 	var sb strings.Builder
 	sb.WriteString(version)
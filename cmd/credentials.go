@@ -0,0 +1,161 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+// credentialsReloadDelay defines how long to wait, after the last change,
+// before reading the dsn and password files again
+const credentialsReloadDelay = 2 * time.Second
+
+var (
+	credentialsMu sync.Mutex
+	// the credentials used for the database connections, the password is
+	// empty if not overridden
+	currentDSN      string
+	currentPassword string
+)
+
+// readCredentials returns the dsn, from the flag or the file, and the
+// password from the password file, if set
+func readCredentials() (string, string, error) {
+	if dsn != "" && dsnFile != "" {
+		return "", "", errors.New("dsn and dsn-file are mutually exclusive")
+	}
+	value := dsn
+	if dsnFile != "" {
+		data, err := os.ReadFile(dsnFile)
+		if err != nil {
+			return "", "", fmt.Errorf("unable to read dsn file: %w", err)
+		}
+		value = strings.TrimSpace(string(data))
+	}
+	if value == "" {
+		return "", "", errors.New("dsn or dsn-file is required")
+	}
+	var password string
+	if passwordFile != "" {
+		data, err := os.ReadFile(passwordFile)
+		if err != nil {
+			return "", "", fmt.Errorf("unable to read password file: %w", err)
+		}
+		password = strings.TrimRight(string(data), "\r\n")
+		if password == "" {
+			return "", "", fmt.Errorf("password file %q is empty", passwordFile)
+		}
+	}
+	credentialsMu.Lock()
+	currentDSN = value
+	currentPassword = password
+	credentialsMu.Unlock()
+	return value, password, nil
+}
+
+// watchCredentials updates the database credentials when the dsn or password
+// files change. The directories are watched, so the files replaced using a
+// rename, for example by secret managers or Kubernetes, are detected too
+func watchCredentials() error {
+	var dirs []string
+	for _, p := range []string{dsnFile, passwordFile} {
+		if p == "" {
+			continue
+		}
+		dir := filepath.Dir(p)
+		if !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return err
+		}
+	}
+	logger.AppLogger.Debug("watching database credentials", "dsn file", dsnFile, "password file", passwordFile)
+	go handleCredentialsEvents(watcher)
+	return nil
+}
+
+func handleCredentialsEvents(watcher *fsnotify.Watcher) {
+	var timer *time.Timer
+	for {
+		select {
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			// the changes to other files are ignored by reloadCredentials
+			if ev.Has(fsnotify.Chmod) {
+				continue
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(credentialsReloadDelay, reloadCredentials)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.AppLogger.Warn("database credentials watcher error", "error", err)
+		}
+	}
+}
+
+// reloadCredentials updates the database credentials if the dsn or password
+// files changed, the current credentials are kept if the new ones are not
+// valid
+func reloadCredentials() {
+	credentialsMu.Lock()
+	previousDSN, previousPassword := currentDSN, currentPassword
+	credentialsMu.Unlock()
+
+	dsnValue, password, err := readCredentials()
+	if err != nil {
+		logger.AppLogger.Error("unable to read database credentials, the current ones are used", "error", err)
+		return
+	}
+	if dsnValue == previousDSN && password == previousPassword {
+		return
+	}
+	if err := db.UpdateCredentials(dsnValue, password); err != nil {
+		logger.AppLogger.Error("unable to update database credentials, the current ones are used", "error", err)
+		// restore the current credentials, so the update is retried at the
+		// next change even if the files are the same
+		credentialsMu.Lock()
+		currentDSN, currentPassword = previousDSN, previousPassword
+		credentialsMu.Unlock()
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/detection"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/sinks/webhook"
+)
+
+var (
+	detectionWindow            time.Duration
+	detectionIPThreshold       int
+	detectionUsernameThreshold int
+	detectionFsRules           string
+	alertWebhookURLs           cli.StringSlice
+
+	alertsFrom       string
+	alertsTo         string
+	alertsRules      cli.StringSlice
+	alertsSeverities cli.StringSlice
+	alertsSubject    string
+	alertsLimit      int
+
+	detectionFlags = []cli.Flag{
+		&cli.DurationFlag{
+			Name:        "detection-window",
+			Usage:       "Sliding window for the login failures thresholds",
+			Value:       10 * time.Minute,
+			Destination: &detectionWindow,
+			EnvVars:     []string{envPrefix + "DETECTION_WINDOW"},
+		},
+		&cli.IntFlag{
+			Name:        "detection-ip-threshold",
+			Usage:       "Number of login failures from the same IP within the window that raise an alert. 0 means disabled",
+			Destination: &detectionIPThreshold,
+			EnvVars:     []string{envPrefix + "DETECTION_IP_THRESHOLD"},
+		},
+		&cli.IntFlag{
+			Name:        "detection-username-threshold",
+			Usage:       "Number of login failures for the same username within the window that raise an alert. 0 means disabled",
+			Destination: &detectionUsernameThreshold,
+			EnvVars:     []string{envPrefix + "DETECTION_USERNAME_THRESHOLD"},
+		},
+		&cli.StringFlag{
+			Name:        "detection-fs-rules",
+			Usage:       "Detection rules for the filesystem events as JSON array, see the README for the supported rules",
+			Destination: &detectionFsRules,
+			EnvVars:     []string{envPrefix + "DETECTION_FS_RULES"},
+		},
+		&cli.StringSliceFlag{
+			Name:        "alert-webhook-urls",
+			Usage:       "Webhook URLs the alerts are delivered to, using the webhook secret, retries and TLS settings",
+			Destination: &alertWebhookURLs,
+			EnvVars:     []string{envPrefix + "ALERT_WEBHOOK_URLS"},
+		},
+	}
+
+	alertsCmd = &cli.Command{
+		Name:  "alerts",
+		Usage: "List the alerts raised by the detection rules",
+		Flags: append(dbFlags,
+			&cli.StringFlag{
+				Name:        "from",
+				Usage:       `List the alerts newer than or equal to this time. RFC 3339 format or "YYYY-MM-DD" (optional)`,
+				Destination: &alertsFrom,
+			},
+			&cli.StringFlag{
+				Name:        "to",
+				Usage:       `List the alerts older than this time. RFC 3339 format or "YYYY-MM-DD" (optional)`,
+				Destination: &alertsTo,
+			},
+			&cli.StringSliceFlag{
+				Name:        "rules",
+				Usage:       "Rules to list. Empty means all rules",
+				Destination: &alertsRules,
+			},
+			&cli.StringSliceFlag{
+				Name:        "severities",
+				Usage:       "Severities to list: low, medium, high, critical. Empty means all severities",
+				Destination: &alertsSeverities,
+			},
+			&cli.StringFlag{
+				Name:        "subject",
+				Usage:       "List the alerts for this subject, for example an IP address or a username (optional)",
+				Destination: &alertsSubject,
+			},
+			&cli.IntFlag{
+				Name:        "limit",
+				Usage:       "Maximum number of alerts to list, the most recent first. 0 means no limit",
+				Value:       100,
+				Destination: &alertsLimit,
+			},
+		),
+		Action: func(_ *cli.Context) error {
+			filter := db.AlertFilter{
+				Rules:      alertsRules.Value(),
+				Severities: alertsSeverities.Value(),
+				Subject:    alertsSubject,
+				Limit:      alertsLimit,
+			}
+			var err error
+			if alertsFrom != "" {
+				if filter.From, err = parseTime(alertsFrom); err != nil {
+					return err
+				}
+			}
+			if alertsTo != "" {
+				if filter.To, err = parseTime(alertsTo); err != nil {
+					return err
+				}
+			}
+			if err := initializeDatabase(false); err != nil {
+				logger.AppLogger.Error("unable to initialize database", "error", err)
+				return err
+			}
+			alerts, err := db.GetAlerts(filter)
+			if err != nil {
+				logger.AppLogger.Error("unable to get alerts", "error", err)
+				return err
+			}
+			return detection.WriteAlerts(os.Stdout, alerts)
+		},
+	}
+)
+
+func getAlertWriters() ([]detection.AlertWriter, error) {
+	if len(alertWebhookURLs.Value()) == 0 {
+		return nil, nil
+	}
+	config, err := getWebhookConfig()
+	if err != nil {
+		return nil, err
+	}
+	config.URLs = alertWebhookURLs.Value()
+	sink, err := webhook.NewSink(config)
+	if err != nil {
+		return nil, err
+	}
+	return []detection.AlertWriter{sink}, nil
+}
+
+func getDetectionSink() (*db.SinkConfig, error) {
+	fsRules, err := detection.ParseFsRules(detectionFsRules)
+	if err != nil {
+		return nil, err
+	}
+	if detectionIPThreshold == 0 && detectionUsernameThreshold == 0 && len(fsRules) == 0 {
+		return nil, nil
+	}
+	writers, err := getAlertWriters()
+	if err != nil {
+		return nil, err
+	}
+	engine, err := detection.NewEngine(detection.Config{
+		InstanceID:        instanceID,
+		Window:            detectionWindow,
+		IPThreshold:       detectionIPThreshold,
+		UsernameThreshold: detectionUsernameThreshold,
+		FsRules:           fsRules,
+		AlertWriters:      writers,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// detection errors must not fail the event notifications
+	return &db.SinkConfig{
+		Sink: engine,
+	}, nil
+}
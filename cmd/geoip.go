@@ -0,0 +1,121 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/geoip"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+var (
+	geoIPDatabases cli.StringSlice
+
+	backfillGeoIPFrom      string
+	backfillGeoIPTo        string
+	backfillGeoIPBatchSize int
+
+	geoIPFlags = []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:        "geoip-databases",
+			Usage:       "Paths to MaxMind databases, for example GeoLite2 City and ASN, used to enrich the client IPs. They are reloaded when they change on disk (optional)",
+			Destination: &geoIPDatabases,
+			EnvVars:     []string{envPrefix + "GEOIP_DATABASES"},
+		},
+	}
+
+	backfillGeoIPCmd = &cli.Command{
+		Name:  "backfill-geoip",
+		Usage: "Enrich the stored events without a geolocation using the GeoIP databases",
+		Flags: append(dbFlags,
+			&cli.StringSliceFlag{
+				Name:        "geoip-databases",
+				Usage:       "Paths to MaxMind databases, for example GeoLite2 City and ASN (required)",
+				Destination: &geoIPDatabases,
+				EnvVars:     []string{envPrefix + "GEOIP_DATABASES"},
+				Required:    true,
+			},
+			&cli.StringFlag{
+				Name:        "from",
+				Usage:       `Enrich the events newer than or equal to this time. RFC 3339 format or "YYYY-MM-DD" (optional)`,
+				Destination: &backfillGeoIPFrom,
+			},
+			&cli.StringFlag{
+				Name:        "to",
+				Usage:       `Enrich the events older than this time. RFC 3339 format or "YYYY-MM-DD" (optional)`,
+				Destination: &backfillGeoIPTo,
+			},
+			&cli.IntFlag{
+				Name:        "batch-size",
+				Usage:       "Number of events updated in each transaction",
+				Value:       1000,
+				Destination: &backfillGeoIPBatchSize,
+			},
+		),
+		Action: func(_ *cli.Context) error {
+			var from, to time.Time
+			var err error
+			if backfillGeoIPFrom != "" {
+				if from, err = parseTime(backfillGeoIPFrom); err != nil {
+					return err
+				}
+			}
+			if backfillGeoIPTo != "" {
+				if to, err = parseTime(backfillGeoIPTo); err != nil {
+					return err
+				}
+			}
+			if backfillGeoIPBatchSize <= 0 {
+				backfillGeoIPBatchSize = 1000
+			}
+			resolver, err := geoip.NewResolver(geoIPDatabases.Value())
+			if err != nil {
+				logger.AppLogger.Error("unable to load GeoIP databases", "error", err)
+				return err
+			}
+			defer resolver.Close()
+
+			if err := initializeDatabase(false); err != nil {
+				logger.AppLogger.Error("unable to initialize database", "error", err)
+				return err
+			}
+			updated, err := db.BackfillGeoIP(resolver, from, to, backfillGeoIPBatchSize)
+			if err != nil {
+				logger.AppLogger.Error("unable to backfill GeoIP data", "updated events", updated, "error", err)
+				return err
+			}
+			logger.AppLogger.Info("GeoIP backfill completed", "updated events", updated)
+			return nil
+		},
+	}
+)
+
+func getGeoIPResolver() (db.GeoIPResolver, error) {
+	if len(geoIPDatabases.Value()) == 0 {
+		return nil, nil
+	}
+	resolver, err := geoip.NewResolver(geoIPDatabases.Value())
+	if err != nil {
+		return nil, err
+	}
+	addCloser("geoip", func(_ time.Duration) error {
+		return resolver.Close()
+	})
+	return resolver, nil
+}
@@ -0,0 +1,54 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/history"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+var (
+	historyCmd = &cli.Command{
+		Name:      "history",
+		Usage:     "Print the changelog for a provider object",
+		ArgsUsage: "<object_type> <object_name>",
+		Flags:     dbFlags,
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 2 {
+				return errors.New("object type and object name are required")
+			}
+			if err := initializeDatabase(false); err != nil {
+				logger.AppLogger.Error("unable to initialize database", "error", err)
+				return err
+			}
+			events, err := db.GetProviderObjectHistory(c.Args().Get(0), c.Args().Get(1))
+			if err != nil {
+				logger.AppLogger.Error("unable to get provider object history", "error", err)
+				return err
+			}
+			if len(events) == 0 {
+				return fmt.Errorf("no event found for %s %q", c.Args().Get(0), c.Args().Get(1))
+			}
+			return history.Write(os.Stdout, events)
+		},
+	}
+)
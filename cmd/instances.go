@@ -0,0 +1,123 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+var (
+	heartbeatInterval   time.Duration
+	instancesStaleAfter time.Duration
+
+	instanceFlags = []cli.Flag{
+		&cli.DurationFlag{
+			Name:        "heartbeat-interval",
+			Usage:       "Interval for updating the last seen time in the instances registry. 0 means the instance is not registered",
+			Value:       time.Minute,
+			Destination: &heartbeatInterval,
+			EnvVars:     []string{envPrefix + "HEARTBEAT_INTERVAL"},
+		},
+	}
+
+	instancesCmd = &cli.Command{
+		Name:  "instances",
+		Usage: "List the plugin instances registered in the event store",
+		Flags: append(dbFlags,
+			&cli.DurationFlag{
+				Name:        "stale-after",
+				Usage:       "Instances not seen for this time are reported as stale",
+				Value:       5 * time.Minute,
+				Destination: &instancesStaleAfter,
+			},
+		),
+		Action: func(_ *cli.Context) error {
+			if err := initializeDatabase(false); err != nil {
+				logger.AppLogger.Error("unable to initialize database", "error", err)
+				return err
+			}
+			instances, err := db.GetInstances()
+			if err != nil {
+				logger.AppLogger.Error("unable to get instances", "error", err)
+				return err
+			}
+			return writeInstances(os.Stdout, instances, time.Now().Add(-instancesStaleAfter))
+		},
+	}
+)
+
+// getRegistryID returns the key for the instances registry, the hostname is
+// used if the instance ID is not set
+func getRegistryID(hostname string) string {
+	if instanceID != "" {
+		return instanceID
+	}
+	return hostname
+}
+
+func registerInstance() (*db.Instance, error) {
+	if heartbeatInterval <= 0 {
+		logger.AppLogger.Debug("heartbeat interval not set, the instance will not be registered")
+		return nil, nil
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		logger.AppLogger.Warn("unable to get hostname", "error", err)
+	}
+	instance := &db.Instance{
+		InstanceID: getRegistryID(hostname),
+		Version:    getVersionString(),
+		Hostname:   hostname,
+		Driver:     driver,
+		StartedAt:  time.Now().UnixNano(),
+	}
+	if err := instance.Register(); err != nil {
+		return nil, fmt.Errorf("unable to register instance: %w", err)
+	}
+	logger.AppLogger.Info("instance registered", "registry id", instance.InstanceID,
+		"heartbeat interval", heartbeatInterval)
+	return instance, nil
+}
+
+func sendHeartbeats(instance *db.Instance) {
+	for range time.Tick(heartbeatInterval) {
+		if err := instance.Heartbeat(); err != nil {
+			logger.AppLogger.Error("unable to update instance heartbeat", "error", err)
+		}
+	}
+}
+
+func writeInstances(w io.Writer, instances []db.Instance, staleThreshold time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "INSTANCE\tSTATUS\tVERSION\tHOSTNAME\tDRIVER\tSTARTED\tLAST SEEN")
+	for _, i := range instances {
+		status := "alive"
+		if i.LastSeen < staleThreshold.UnixNano() {
+			status = "stale"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", i.InstanceID, status, i.Version, i.Hostname, i.Driver,
+			time.Unix(0, i.StartedAt).UTC().Format(time.RFC3339), time.Unix(0, i.LastSeen).UTC().Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
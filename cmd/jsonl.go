@@ -0,0 +1,58 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"github.com/urfave/cli/v2"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/sinks/jsonl"
+)
+
+var (
+	jsonlPath     string
+	jsonlRequired bool
+
+	jsonlFlags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "jsonl-path",
+			Usage:       "Path to a file the events are appended to as JSON lines. Empty means disabled",
+			Destination: &jsonlPath,
+			EnvVars:     []string{envPrefix + "JSONL_PATH"},
+		},
+		&cli.BoolFlag{
+			Name:        "jsonl-required",
+			Usage:       "If set, a JSONL write error fails the event notification and SFTPGo will retry it",
+			Destination: &jsonlRequired,
+			EnvVars:     []string{envPrefix + "JSONL_REQUIRED"},
+		},
+	}
+)
+
+func getJSONLSink() (*db.SinkConfig, error) {
+	if jsonlPath == "" {
+		return nil, nil
+	}
+	sink, err := jsonl.NewSink(jsonl.Config{
+		Path: jsonlPath,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &db.SinkConfig{
+		Sink:     sink,
+		Required: jsonlRequired,
+	}, nil
+}
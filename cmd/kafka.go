@@ -0,0 +1,144 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"crypto/tls"
+	"errors"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/sinks/kafka"
+)
+
+var (
+	kafkaBrokers       cli.StringSlice
+	kafkaTopicPrefix   string
+	kafkaPartitionKey  string
+	kafkaAcks          int
+	kafkaTLS           bool
+	kafkaTLSConfig     string
+	kafkaSASLMechanism string
+	kafkaSASLUsername  string
+	kafkaSASLPassword  string
+	kafkaRequired      bool
+
+	kafkaFlags = []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:        "kafka-brokers",
+			Usage:       "Kafka bootstrap brokers as host:port. Events are published to Kafka if at least a broker is set",
+			Destination: &kafkaBrokers,
+			EnvVars:     []string{envPrefix + "KAFKA_BROKERS"},
+		},
+		&cli.StringFlag{
+			Name:        "kafka-topic-prefix",
+			Usage:       `Prefix for the Kafka topics: "fs-events", "provider-events", "log-events"`,
+			Value:       "sftpgo-",
+			Destination: &kafkaTopicPrefix,
+			EnvVars:     []string{envPrefix + "KAFKA_TOPIC_PREFIX"},
+		},
+		&cli.StringFlag{
+			Name:        "kafka-partition-key",
+			Usage:       `Kafka record key, it determines the partition. Supported values: "username", "instance_id"`,
+			Value:       kafka.PartitionKeyUsername,
+			Destination: &kafkaPartitionKey,
+			EnvVars:     []string{envPrefix + "KAFKA_PARTITION_KEY"},
+		},
+		&cli.IntFlag{
+			Name:        "kafka-acks",
+			Usage:       "Required Kafka acknowledgments. 0 none, 1 leader only, -1 all in-sync replicas",
+			Value:       -1,
+			Destination: &kafkaAcks,
+			EnvVars:     []string{envPrefix + "KAFKA_ACKS"},
+		},
+		&cli.BoolFlag{
+			Name:        "kafka-tls",
+			Usage:       "Enable TLS for Kafka connections",
+			Destination: &kafkaTLS,
+			EnvVars:     []string{envPrefix + "KAFKA_TLS"},
+		},
+		&cli.StringFlag{
+			Name:        "kafka-tls-config",
+			Usage:       "Custom TLS config for Kafka connections, same syntax as custom-tls (optional)",
+			Destination: &kafkaTLSConfig,
+			EnvVars:     []string{envPrefix + "KAFKA_TLS_CONFIG"},
+		},
+		&cli.StringFlag{
+			Name:        "kafka-sasl-mechanism",
+			Usage:       `Kafka SASL mechanism. Supported values: "PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512". Empty means no authentication`,
+			Destination: &kafkaSASLMechanism,
+			EnvVars:     []string{envPrefix + "KAFKA_SASL_MECHANISM"},
+		},
+		&cli.StringFlag{
+			Name:        "kafka-sasl-username",
+			Usage:       "Kafka SASL username",
+			Destination: &kafkaSASLUsername,
+			EnvVars:     []string{envPrefix + "KAFKA_SASL_USERNAME"},
+		},
+		&cli.StringFlag{
+			Name:        "kafka-sasl-password",
+			Usage:       "Kafka SASL password",
+			Destination: &kafkaSASLPassword,
+			EnvVars:     []string{envPrefix + "KAFKA_SASL_PASSWORD"},
+		},
+		&cli.BoolFlag{
+			Name:        "kafka-required",
+			Usage:       "If set, a Kafka publish error fails the event notification and SFTPGo will retry it. It requires kafka-acks 1 or -1",
+			Destination: &kafkaRequired,
+			EnvVars:     []string{envPrefix + "KAFKA_REQUIRED"},
+		},
+	}
+)
+
+func getKafkaSink() (*db.SinkConfig, error) {
+	if len(kafkaBrokers.Value()) == 0 {
+		return nil, nil
+	}
+	if kafkaRequired && kafkaAcks == 0 {
+		// without acknowledgments a record is never confirmed as delivered
+		return nil, errors.New("a required Kafka sink needs kafka-acks 1 or -1")
+	}
+	var tlsConfig *tls.Config
+	if kafkaTLS {
+		tlsConfig = &tls.Config{}
+		if kafkaTLSConfig != "" {
+			var err error
+			tlsConfig, err = db.GetTLSConfig(kafkaTLSConfig)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	sink, err := kafka.NewSink(kafka.Config{
+		Brokers:       kafkaBrokers.Value(),
+		TopicPrefix:   kafkaTopicPrefix,
+		PartitionKey:  kafkaPartitionKey,
+		Acks:          kafkaAcks,
+		TLSConfig:     tlsConfig,
+		SASLMechanism: kafkaSASLMechanism,
+		SASLUsername:  kafkaSASLUsername,
+		SASLPassword:  kafkaSASLPassword,
+		ClientID:      "sftpgo-plugin-eventstore-" + getVersionString(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	addCloser(sink.Name(), sink.Close)
+	return &db.SinkConfig{
+		Sink:     sink,
+		Required: kafkaRequired,
+	}, nil
+}
@@ -0,0 +1,134 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/urfave/cli/v2"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+// minLeaseTTL is the minimum lease validity, the lease is renewed every third
+// of its validity
+const minLeaseTTL = 3 * time.Second
+
+// maintenanceLease is the lease that gates the event time backfill, the
+// retention cleanup and the rollups maintenance
+const maintenanceLease = "maintenance"
+
+var (
+	leaseTTL          time.Duration
+	maintenanceLeader atomic.Bool
+	// leaseHolder is set if the leader election is started
+	leaseHolder string
+	leaseStop   chan struct{}
+	leaseDone   chan struct{}
+
+	leaderFlags = []cli.Flag{
+		&cli.DurationFlag{
+			Name:        "lease-ttl",
+			Usage:       "Validity of the database lease that allows only one instance to run the maintenance jobs, at least 3s. 0 means every instance runs them",
+			Value:       time.Minute,
+			Destination: &leaseTTL,
+			EnvVars:     []string{envPrefix + "LEASE_TTL"},
+		},
+	}
+)
+
+func validateLeaseTTL() error {
+	if leaseTTL != 0 && leaseTTL < minLeaseTTL {
+		return fmt.Errorf("the lease TTL must be 0 or at least %s", minLeaseTTL)
+	}
+	return nil
+}
+
+// startLeaderElection acquires the maintenance lease, if available, and
+// renews it in background. Instances without the lease try to acquire it at
+// each renewal so they take over if the holder stops renewing it
+func startLeaderElection() {
+	if leaseTTL <= 0 {
+		logger.AppLogger.Debug("lease TTL not set, the maintenance jobs run on every instance")
+		return
+	}
+	hostname, _ := os.Hostname()
+	// the random suffix allows to distinguish instances with the same ID and
+	// restarts of the same instance
+	holder := getRegistryID(hostname) + "-" + xid.New().String()
+	logger.AppLogger.Debug("start leader election", "holder", holder, "lease ttl", leaseTTL)
+	acquireMaintenanceLease(holder)
+	leaseHolder = holder
+	leaseStop = make(chan struct{})
+	leaseDone = make(chan struct{})
+	go func() {
+		defer close(leaseDone)
+
+		ticker := time.NewTicker(leaseTTL / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-leaseStop:
+				return
+			case <-ticker.C:
+				acquireMaintenanceLease(holder)
+			}
+		}
+	}()
+}
+
+// stopLeaderElection stops renewing the maintenance lease and releases it, so
+// another instance takes over without waiting for the lease to expire
+func stopLeaderElection() {
+	if leaseHolder == "" {
+		return
+	}
+	close(leaseStop)
+	<-leaseDone
+	maintenanceLeader.Store(false)
+	if err := db.ReleaseLease(maintenanceLease, leaseHolder); err != nil {
+		logger.AppLogger.Error("unable to release maintenance lease", "error", err)
+		return
+	}
+	logger.AppLogger.Debug("maintenance lease released", "holder", leaseHolder)
+}
+
+func acquireMaintenanceLease(holder string) {
+	held, err := db.AcquireLease(maintenanceLease, holder, leaseTTL)
+	if err != nil {
+		// the lease may expire before the next successful renewal
+		logger.AppLogger.Error("unable to acquire maintenance lease", "error", err)
+		held = false
+	}
+	if maintenanceLeader.Swap(held) != held {
+		if held {
+			logger.AppLogger.Info("maintenance lease acquired, this instance will run the maintenance jobs")
+		} else {
+			logger.AppLogger.Info("maintenance lease lost, this instance will not run the maintenance jobs")
+		}
+	}
+}
+
+// isMaintenanceLeader returns true if this instance must run the maintenance
+// jobs
+func isMaintenanceLeader() bool {
+	return leaseTTL <= 0 || maintenanceLeader.Load()
+}
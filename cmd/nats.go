@@ -0,0 +1,122 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"crypto/tls"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/sinks/nats"
+)
+
+var (
+	natsURLs          cli.StringSlice
+	natsSubjectPrefix string
+	natsCredentials   string
+	natsTLS           bool
+	natsTLSConfig     string
+	natsStream        string
+	natsCreateStream  bool
+	natsRequired      bool
+
+	natsFlags = []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:        "nats-urls",
+			Usage:       "NATS server URLs, for example nats://127.0.0.1:4222. Events are published to NATS JetStream if at least a URL is set",
+			Destination: &natsURLs,
+			EnvVars:     []string{envPrefix + "NATS_URLS"},
+		},
+		&cli.StringFlag{
+			Name:        "nats-subject-prefix",
+			Usage:       "Prefix for the NATS subjects",
+			Value:       "sftpgo",
+			Destination: &natsSubjectPrefix,
+			EnvVars:     []string{envPrefix + "NATS_SUBJECT_PREFIX"},
+		},
+		&cli.StringFlag{
+			Name:        "nats-creds",
+			Usage:       "Path to a NATS credentials file (optional)",
+			Destination: &natsCredentials,
+			EnvVars:     []string{envPrefix + "NATS_CREDS"},
+		},
+		&cli.BoolFlag{
+			Name:        "nats-tls",
+			Usage:       "Enable TLS for NATS connections",
+			Destination: &natsTLS,
+			EnvVars:     []string{envPrefix + "NATS_TLS"},
+		},
+		&cli.StringFlag{
+			Name:        "nats-tls-config",
+			Usage:       "Custom TLS config for NATS connections, same syntax as custom-tls (optional)",
+			Destination: &natsTLSConfig,
+			EnvVars:     []string{envPrefix + "NATS_TLS_CONFIG"},
+		},
+		&cli.StringFlag{
+			Name:        "nats-stream",
+			Usage:       "JetStream stream name, used if nats-create-stream is set",
+			Value:       "SFTPGO_EVENTS",
+			Destination: &natsStream,
+			EnvVars:     []string{envPrefix + "NATS_STREAM"},
+		},
+		&cli.BoolFlag{
+			Name:        "nats-create-stream",
+			Usage:       "Create the JetStream stream, capturing all the subjects with the configured prefix, if it does not exist",
+			Destination: &natsCreateStream,
+			EnvVars:     []string{envPrefix + "NATS_CREATE_STREAM"},
+		},
+		&cli.BoolFlag{
+			Name:        "nats-required",
+			Usage:       "If set, a NATS publish error fails the event notification and SFTPGo will retry it",
+			Destination: &natsRequired,
+			EnvVars:     []string{envPrefix + "NATS_REQUIRED"},
+		},
+	}
+)
+
+func getNATSSink() (*db.SinkConfig, error) {
+	if len(natsURLs.Value()) == 0 {
+		return nil, nil
+	}
+	var tlsConfig *tls.Config
+	if natsTLS {
+		tlsConfig = &tls.Config{}
+		if natsTLSConfig != "" {
+			var err error
+			tlsConfig, err = db.GetTLSConfig(natsTLSConfig)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	sink, err := nats.NewSink(nats.Config{
+		URLs:            natsURLs.Value(),
+		SubjectPrefix:   natsSubjectPrefix,
+		CredentialsFile: natsCredentials,
+		TLSConfig:       tlsConfig,
+		StreamName:      natsStream,
+		CreateStream:    natsCreateStream,
+		ClientName:      "sftpgo-plugin-eventstore-" + getVersionString(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	addCloser(sink.Name(), sink.Close)
+	return &db.SinkConfig{
+		Sink:     sink,
+		Required: natsRequired,
+	}, nil
+}
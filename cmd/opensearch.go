@@ -0,0 +1,135 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"github.com/urfave/cli/v2"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/sinks/opensearch"
+)
+
+var (
+	openSearchURLs            cli.StringSlice
+	openSearchUsername        string
+	openSearchPassword        string
+	openSearchAPIKey          string
+	openSearchIndexPrefix     string
+	openSearchReplicas        int
+	openSearchLifecyclePolicy string
+	openSearchRetention       int
+	openSearchBatchSize       int
+	openSearchTLSConfig       string
+
+	openSearchFlags = []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:        "opensearch-urls",
+			Usage:       "OpenSearch/Elasticsearch node URLs. Events are indexed if at least a URL is set",
+			Destination: &openSearchURLs,
+			EnvVars:     []string{envPrefix + "OPENSEARCH_URLS"},
+		},
+		&cli.StringFlag{
+			Name:        "opensearch-username",
+			Usage:       "Username for OpenSearch basic authentication",
+			Destination: &openSearchUsername,
+			EnvVars:     []string{envPrefix + "OPENSEARCH_USERNAME"},
+		},
+		&cli.StringFlag{
+			Name:        "opensearch-password",
+			Usage:       "Password for OpenSearch basic authentication",
+			Destination: &openSearchPassword,
+			EnvVars:     []string{envPrefix + "OPENSEARCH_PASSWORD"},
+		},
+		&cli.StringFlag{
+			Name:        "opensearch-api-key",
+			Usage:       "Elasticsearch API key, it takes precedence over basic authentication",
+			Destination: &openSearchAPIKey,
+			EnvVars:     []string{envPrefix + "OPENSEARCH_API_KEY"},
+		},
+		&cli.StringFlag{
+			Name:        "opensearch-index-prefix",
+			Usage:       `Prefix for the daily indices: "fs-YYYY.MM.DD", "provider-YYYY.MM.DD", "log-YYYY.MM.DD"`,
+			Value:       "sftpgo-",
+			Destination: &openSearchIndexPrefix,
+			EnvVars:     []string{envPrefix + "OPENSEARCH_INDEX_PREFIX"},
+		},
+		&cli.IntFlag{
+			Name:        "opensearch-replicas",
+			Usage:       "Number of replicas set in the index templates",
+			Value:       1,
+			Destination: &openSearchReplicas,
+			EnvVars:     []string{envPrefix + "OPENSEARCH_REPLICAS"},
+		},
+		&cli.StringFlag{
+			Name:        "opensearch-lifecycle-policy",
+			Usage:       "Existing Elasticsearch ILM or OpenSearch ISM policy to apply to the indices (optional)",
+			Destination: &openSearchLifecyclePolicy,
+			EnvVars:     []string{envPrefix + "OPENSEARCH_LIFECYCLE_POLICY"},
+		},
+		&cli.IntFlag{
+			Name:        "opensearch-retention",
+			Usage:       "Daily indices older than the specified number of days will be deleted. 0 means no index will be deleted",
+			Destination: &openSearchRetention,
+			EnvVars:     []string{envPrefix + "OPENSEARCH_RETENTION"},
+		},
+		&cli.IntFlag{
+			Name:        "opensearch-batch-size",
+			Usage:       "Maximum number of documents for each bulk request",
+			Value:       500,
+			Destination: &openSearchBatchSize,
+			EnvVars:     []string{envPrefix + "OPENSEARCH_BATCH_SIZE"},
+		},
+		&cli.StringFlag{
+			Name:        "opensearch-tls-config",
+			Usage:       "Custom TLS config for OpenSearch connections, same syntax as custom-tls (optional)",
+			Destination: &openSearchTLSConfig,
+			EnvVars:     []string{envPrefix + "OPENSEARCH_TLS_CONFIG"},
+		},
+	}
+)
+
+func getOpenSearchSink() (*db.SinkConfig, error) {
+	if len(openSearchURLs.Value()) == 0 {
+		return nil, nil
+	}
+	config := opensearch.Config{
+		URLs:            openSearchURLs.Value(),
+		Username:        openSearchUsername,
+		Password:        openSearchPassword,
+		APIKey:          openSearchAPIKey,
+		IndexPrefix:     openSearchIndexPrefix,
+		Replicas:        openSearchReplicas,
+		LifecyclePolicy: openSearchLifecyclePolicy,
+		RetentionDays:   openSearchRetention,
+		BatchSize:       openSearchBatchSize,
+		MaxRetries:      5,
+	}
+	if openSearchTLSConfig != "" {
+		tlsConfig, err := db.GetTLSConfig(openSearchTLSConfig)
+		if err != nil {
+			return nil, err
+		}
+		config.TLSConfig = tlsConfig
+	}
+	sink, err := opensearch.NewSink(config)
+	if err != nil {
+		return nil, err
+	}
+	addCloser(sink.Name(), sink.Close)
+	// documents are indexed asynchronously using bulk requests
+	return &db.SinkConfig{
+		Sink: sink,
+	}, nil
+}
@@ -0,0 +1,134 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/report"
+)
+
+var (
+	reportFrom     string
+	reportTo       string
+	reportFormat   string
+	reportTemplate string
+	reportDirDepth int
+	reportLimit    int
+	reportOutput   string
+
+	reportUserFlags = append(dbFlags,
+		&cli.StringFlag{
+			Name:        "from",
+			Usage:       `Include events newer than or equal to this time. RFC 3339 format or "YYYY-MM-DD" (required)`,
+			Destination: &reportFrom,
+			Required:    true,
+		},
+		&cli.StringFlag{
+			Name:        "to",
+			Usage:       `Include events older than this time. RFC 3339 format or "YYYY-MM-DD" (required)`,
+			Destination: &reportTo,
+			Required:    true,
+		},
+		&cli.StringFlag{
+			Name:        "format",
+			Usage:       `Report format. Supported values: "markdown", "html", "csv"`,
+			Value:       report.FormatMarkdown,
+			Destination: &reportFormat,
+		},
+		&cli.StringFlag{
+			Name:        "template",
+			Usage:       "Path to a Go template to use instead of the default one for the selected format (optional)",
+			Destination: &reportTemplate,
+		},
+		&cli.IntFlag{
+			Name:        "dir-depth",
+			Usage:       "Number of path components used to group the directories. 0 means the parent directory",
+			Destination: &reportDirDepth,
+		},
+		&cli.IntFlag{
+			Name:        "limit",
+			Usage:       "Maximum number of directories, failed operations and login failures to include",
+			Value:       10,
+			Destination: &reportLimit,
+		},
+		&cli.StringFlag{
+			Name:        "output",
+			Usage:       "Output file. Empty means standard output",
+			Destination: &reportOutput,
+		},
+	)
+
+	reportCmd = &cli.Command{
+		Name:  "report",
+		Usage: "Generate activity reports",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "user",
+				Usage:     "Generate the activity report for a user",
+				ArgsUsage: "<username>",
+				Flags:     reportUserFlags,
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return errors.New("a username is required")
+					}
+					from, err := parseTime(reportFrom)
+					if err != nil {
+						return err
+					}
+					to, err := parseTime(reportTo)
+					if err != nil {
+						return err
+					}
+					if err := initializeDatabase(false); err != nil {
+						logger.AppLogger.Error("unable to initialize database", "error", err)
+						return err
+					}
+					r, err := report.GetUser(report.UserConfig{
+						Username: c.Args().First(),
+						From:     from,
+						To:       to,
+						DirDepth: reportDirDepth,
+						Limit:    reportLimit,
+					})
+					if err != nil {
+						logger.AppLogger.Error("unable to generate report", "error", err)
+						return err
+					}
+					w := os.Stdout
+					if reportOutput != "" {
+						f, err := os.OpenFile(reportOutput, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+						if err != nil {
+							return err
+						}
+						defer f.Close()
+						w = f
+					}
+					if err := r.Write(w, reportFormat, reportTemplate); err != nil {
+						return err
+					}
+					if reportOutput != "" {
+						return w.Sync()
+					}
+					return nil
+				},
+			},
+		},
+	}
+)
@@ -0,0 +1,153 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+const rollupInterval = 5 * time.Minute
+
+var (
+	rollups               bool
+	rollupLateness        time.Duration
+	rollupHourlyRetention int
+	rollupDailyRetention  int
+	rollupFrom            string
+	rollupTo              string
+
+	rollupFlags = []cli.Flag{
+		&cli.BoolFlag{
+			Name:        "rollups",
+			Usage:       "If set, the hourly and daily rollups for the fs events are updated in background",
+			Destination: &rollups,
+			EnvVars:     []string{envPrefix + "ROLLUPS"},
+		},
+		&cli.DurationFlag{
+			Name:        "rollup-lateness",
+			Usage:       "The rollups are recomputed for this time window to include the events received late",
+			Value:       6 * time.Hour,
+			Destination: &rollupLateness,
+			EnvVars:     []string{envPrefix + "ROLLUP_LATENESS"},
+		},
+		&cli.IntFlag{
+			Name:        "rollup-hourly-retention",
+			Usage:       `Hourly rollups older than the specified number of hours will be deleted. 0 means no rollups will be deleted`,
+			Destination: &rollupHourlyRetention,
+			EnvVars:     []string{envPrefix + "ROLLUP_HOURLY_RETENTION"},
+		},
+		&cli.IntFlag{
+			Name:        "rollup-daily-retention",
+			Usage:       `Daily rollups older than the specified number of days will be deleted. 0 means no rollups will be deleted`,
+			Destination: &rollupDailyRetention,
+			EnvVars:     []string{envPrefix + "ROLLUP_DAILY_RETENTION"},
+		},
+	}
+
+	rollupCmd = &cli.Command{
+		Name:  "rollup",
+		Usage: "Recompute the hourly and daily rollups within a time range from the stored events",
+		Flags: append(dbFlags,
+			&cli.StringFlag{
+				Name:        "from",
+				Usage:       `Recompute the rollups from this time. RFC 3339 format or "YYYY-MM-DD" (required)`,
+				Destination: &rollupFrom,
+				Required:    true,
+			},
+			&cli.StringFlag{
+				Name:        "to",
+				Usage:       `Recompute the rollups up to this time. RFC 3339 format or "YYYY-MM-DD" (required)`,
+				Destination: &rollupTo,
+				Required:    true,
+			},
+		),
+		Action: func(_ *cli.Context) error {
+			from, err := parseTime(rollupFrom)
+			if err != nil {
+				return err
+			}
+			to, err := parseTime(rollupTo)
+			if err != nil {
+				return err
+			}
+			if err := initializeDatabase(false); err != nil {
+				logger.AppLogger.Error("unable to initialize database", "error", err)
+				return err
+			}
+			if err := db.RefreshRollups(from, to); err != nil {
+				logger.AppLogger.Error("unable to refresh rollups", "error", err)
+				return err
+			}
+			return nil
+		},
+	}
+)
+
+// validateRollups checks that the rollups are never recomputed from deleted
+// events or hourly rollups
+func validateRollups() error {
+	if !rollups {
+		return nil
+	}
+	if rollupLateness < time.Hour {
+		return errors.New("the rollup lateness must be at least 1 hour")
+	}
+	if retention > 0 && time.Duration(retention)*time.Hour <= rollupLateness {
+		return fmt.Errorf("the retention must be greater than the rollup lateness %s", rollupLateness)
+	}
+	if rollupHourlyRetention > 0 && time.Duration(rollupHourlyRetention)*time.Hour < rollupLateness+24*time.Hour {
+		return fmt.Errorf("the hourly rollup retention must be at least the rollup lateness %s plus 24 hours",
+			rollupLateness)
+	}
+	return nil
+}
+
+func getRollupsRetention() (time.Time, time.Time) {
+	var hourly, daily time.Time
+	now := time.Now()
+	if rollupHourlyRetention > 0 {
+		hourly = now.Add(-time.Duration(rollupHourlyRetention) * time.Hour)
+	}
+	if rollupDailyRetention > 0 {
+		daily = now.AddDate(0, 0, -rollupDailyRetention)
+	}
+	return hourly, daily
+}
+
+func maintainRollups() {
+	logger.AppLogger.Debug("start rollups maintenance", "lateness", rollupLateness, "interval", rollupInterval)
+	refresh := func() {
+		if !isMaintenanceLeader() {
+			logger.AppLogger.Debug("maintenance lease not held, skipping rollups maintenance")
+			return
+		}
+		now := time.Now()
+		if err := db.RefreshRollups(now.Add(-rollupLateness), now); err != nil {
+			logger.AppLogger.Error("unable to refresh rollups", "error", err)
+		}
+		db.CleanupRollups(getRollupsRetention())
+	}
+	refresh()
+	for range time.Tick(rollupInterval) {
+		refresh()
+	}
+}
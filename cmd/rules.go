@@ -0,0 +1,150 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/detection"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/sinks/webhook"
+)
+
+var (
+	rulesFile string
+
+	rulesTestFrom string
+	rulesTestTo   string
+
+	rulesFlags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "rules-file",
+			Usage:       "Path to a JSON file with the alert rules evaluated against each event (optional)",
+			Destination: &rulesFile,
+			EnvVars:     []string{envPrefix + "RULES_FILE"},
+		},
+	}
+
+	rulesTestFlags = append(dbFlags,
+		&cli.StringFlag{
+			Name:        "rules-file",
+			Usage:       "Path to a JSON file with the alert rules to test (required)",
+			Destination: &rulesFile,
+			Required:    true,
+		},
+		&cli.StringFlag{
+			Name:        "from",
+			Usage:       `Replay the events newer than or equal to this time. RFC 3339 format or "YYYY-MM-DD" (required)`,
+			Destination: &rulesTestFrom,
+			Required:    true,
+		},
+		&cli.StringFlag{
+			Name:        "to",
+			Usage:       `Replay the events older than this time. RFC 3339 format or "YYYY-MM-DD" (required)`,
+			Destination: &rulesTestTo,
+			Required:    true,
+		},
+	)
+
+	rulesCmd = &cli.Command{
+		Name:  "rules",
+		Usage: "Manage the alert rules",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "test",
+				Usage: "Replay the stored events against the alert rules, no alert is saved or sent",
+				Flags: rulesTestFlags,
+				Action: func(_ *cli.Context) error {
+					from, err := parseTime(rulesTestFrom)
+					if err != nil {
+						return err
+					}
+					to, err := parseTime(rulesTestTo)
+					if err != nil {
+						return err
+					}
+					rules, err := detection.LoadExprRules(rulesFile)
+					if err != nil {
+						logger.AppLogger.Error("unable to load rules", "error", err)
+						return err
+					}
+					if err := initializeDatabase(false); err != nil {
+						logger.AppLogger.Error("unable to initialize database", "error", err)
+						return err
+					}
+					result, err := detection.TestExprRules(rules, from, to)
+					if err != nil {
+						logger.AppLogger.Error("unable to test rules", "error", err)
+						return err
+					}
+					return result.Write(os.Stdout)
+				},
+			},
+		},
+	}
+)
+
+// getRuleCallbacks returns a webhook sink for each callback URL used in the
+// rules, using the webhook secret, retries and TLS settings
+func getRuleCallbacks(rules []detection.ExprRule) (map[string]detection.AlertWriter, error) {
+	callbacks := make(map[string]detection.AlertWriter)
+	for _, rule := range rules {
+		for _, u := range rule.CallbackURLs {
+			if _, ok := callbacks[u]; ok {
+				continue
+			}
+			config, err := getWebhookConfig()
+			if err != nil {
+				return nil, err
+			}
+			config.URLs = []string{u}
+			sink, err := webhook.NewSink(config)
+			if err != nil {
+				return nil, err
+			}
+			callbacks[u] = sink
+		}
+	}
+	return callbacks, nil
+}
+
+func getRulesSink() (*db.SinkConfig, error) {
+	if rulesFile == "" {
+		return nil, nil
+	}
+	rules, err := detection.LoadExprRules(rulesFile)
+	if err != nil {
+		return nil, err
+	}
+	callbacks, err := getRuleCallbacks(rules)
+	if err != nil {
+		return nil, err
+	}
+	engine, err := detection.NewRuleEngine(detection.RuleEngineConfig{
+		InstanceID: instanceID,
+		Rules:      rules,
+		Callbacks:  callbacks,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// rule errors must not fail the event notifications
+	return &db.SinkConfig{
+		Sink: engine,
+	}, nil
+}
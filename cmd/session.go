@@ -0,0 +1,111 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/session"
+)
+
+var (
+	sessionLogWindow time.Duration
+	sessionFrom      string
+	sessionTo        string
+	sessionOrderBy   string
+	sessionLimit     int
+
+	sessionFlags = append(dbFlags,
+		&cli.DurationFlag{
+			Name:        "log-window",
+			Usage:       "Include the log events up to this time before the session start and after the session end",
+			Value:       time.Minute,
+			Destination: &sessionLogWindow,
+		},
+		&cli.StringFlag{
+			Name:        "from",
+			Usage:       `List the sessions with events newer than or equal to this time. RFC 3339 format or "YYYY-MM-DD"`,
+			Destination: &sessionFrom,
+		},
+		&cli.StringFlag{
+			Name:        "to",
+			Usage:       `List the sessions with events older than this time. RFC 3339 format or "YYYY-MM-DD"`,
+			Destination: &sessionTo,
+		},
+		&cli.StringFlag{
+			Name:        "order-by",
+			Usage:       `Order for the listed sessions. Supported values: "bytes", "duration"`,
+			Value:       db.SessionOrderBytes,
+			Destination: &sessionOrderBy,
+		},
+		&cli.IntFlag{
+			Name:        "limit",
+			Usage:       "Maximum number of listed sessions",
+			Value:       20,
+			Destination: &sessionLimit,
+		},
+	)
+
+	sessionCmd = &cli.Command{
+		Name:      "session",
+		Usage:     "Print the timeline for a session or list the top sessions",
+		ArgsUsage: "[session_id]",
+		Flags:     sessionFlags,
+		Action: func(c *cli.Context) error {
+			if c.NArg() > 1 {
+				return errors.New("too many arguments")
+			}
+			if c.NArg() == 1 {
+				if err := initializeDatabase(false); err != nil {
+					logger.AppLogger.Error("unable to initialize database", "error", err)
+					return err
+				}
+				s, err := session.Get(c.Args().First(), sessionLogWindow)
+				if err != nil {
+					logger.AppLogger.Error("unable to get session", "error", err)
+					return err
+				}
+				return s.Write(os.Stdout)
+			}
+			if sessionFrom == "" || sessionTo == "" {
+				return errors.New("a session ID or the time range to list the sessions is required")
+			}
+			from, err := parseTime(sessionFrom)
+			if err != nil {
+				return err
+			}
+			to, err := parseTime(sessionTo)
+			if err != nil {
+				return err
+			}
+			if err := initializeDatabase(false); err != nil {
+				logger.AppLogger.Error("unable to initialize database", "error", err)
+				return err
+			}
+			sessions, err := db.GetTopSessions(from, to, sessionOrderBy, sessionLimit)
+			if err != nil {
+				logger.AppLogger.Error("unable to get sessions", "error", err)
+				return err
+			}
+			return session.WriteTop(os.Stdout, sessions)
+		},
+	}
+)
@@ -0,0 +1,59 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+// shutdownTimeout defines how long the sinks can take to deliver the pending
+// events when the plugin stops, SFTPGo kills the plugin if it does not exit
+// within 2 seconds
+const shutdownTimeout = 1500 * time.Millisecond
+
+type closer struct {
+	name  string
+	close func(timeout time.Duration) error
+}
+
+var closers []closer
+
+// addCloser registers a resource to close when the plugin stops
+func addCloser(name string, fn func(timeout time.Duration) error) {
+	closers = append(closers, closer{name: name, close: fn})
+}
+
+// closeAll closes the registered resources concurrently, each one can take at
+// most shutdownTimeout
+func closeAll() {
+	var wg sync.WaitGroup
+	for _, c := range closers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := c.close(shutdownTimeout); err != nil {
+				logger.AppLogger.Error("unable to close", "name", c.name, "error", err)
+				return
+			}
+			logger.AppLogger.Debug("closed", "name", c.name)
+		}()
+	}
+	wg.Wait()
+	closers = nil
+}
@@ -0,0 +1,120 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/snapshot"
+)
+
+// Supported snapshot formats
+const (
+	snapshotFormatJSON = "json"
+	snapshotFormatDump = "dump"
+)
+
+var (
+	snapshotAt          string
+	snapshotObjectTypes cli.StringSlice
+	snapshotObjectNames cli.StringSlice
+	snapshotFormat      string
+	snapshotDumpVersion int
+	snapshotOutput      string
+
+	snapshotFlags = append(dbFlags,
+		&cli.StringFlag{
+			Name:        "at",
+			Usage:       `Reconstruct the provider objects as of this time. RFC 3339 format or "YYYY-MM-DD" (required)`,
+			Destination: &snapshotAt,
+			Required:    true,
+		},
+		&cli.StringSliceFlag{
+			Name:        "object-types",
+			Usage:       `Object types to include, for example "user", "group", "folder". Empty means all types`,
+			Destination: &snapshotObjectTypes,
+		},
+		&cli.StringSliceFlag{
+			Name:        "object-names",
+			Usage:       "Object names to include. Empty means all names",
+			Destination: &snapshotObjectNames,
+		},
+		&cli.StringFlag{
+			Name:        "format",
+			Usage:       `Output format. Supported values: "json", "dump". "dump" uses the SFTPGo dump/restore format`,
+			Value:       snapshotFormatJSON,
+			Destination: &snapshotFormat,
+		},
+		&cli.IntFlag{
+			Name:        "dump-version",
+			Usage:       "Version to set in the dump, it should match your SFTPGo dump version. 0 means omitted",
+			Destination: &snapshotDumpVersion,
+		},
+		&cli.StringFlag{
+			Name:        "output",
+			Usage:       "Output file. Empty means standard output",
+			Destination: &snapshotOutput,
+		},
+	)
+
+	snapshotCmd = &cli.Command{
+		Name:  "snapshot",
+		Usage: "Reconstruct the provider objects as of a point in time",
+		Flags: snapshotFlags,
+		Action: func(_ *cli.Context) error {
+			at, err := parseTime(snapshotAt)
+			if err != nil {
+				return err
+			}
+			if snapshotFormat != snapshotFormatJSON && snapshotFormat != snapshotFormatDump {
+				return fmt.Errorf("unsupported format %q", snapshotFormat)
+			}
+			if err := initializeDatabase(false); err != nil {
+				logger.AppLogger.Error("unable to initialize database", "error", err)
+				return err
+			}
+			s, err := snapshot.Get(at, snapshotObjectTypes.Value(), snapshotObjectNames.Value())
+			if err != nil {
+				logger.AppLogger.Error("unable to get provider objects", "error", err)
+				return err
+			}
+			w := os.Stdout
+			if snapshotOutput != "" {
+				f, err := os.OpenFile(snapshotOutput, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+			if snapshotFormat == snapshotFormatDump {
+				err = s.WriteDump(w, snapshotDumpVersion)
+			} else {
+				err = s.Write(w)
+			}
+			if err != nil {
+				return err
+			}
+			if snapshotOutput != "" {
+				return w.Sync()
+			}
+			return nil
+		},
+	}
+)
@@ -0,0 +1,115 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"crypto/tls"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/sinks/syslog"
+)
+
+var (
+	syslogAddress    string
+	syslogNetwork    string
+	syslogFormat     string
+	syslogFacility   string
+	syslogHostname   string
+	syslogTLSConfig  string
+	syslogBufferSize int
+
+	syslogFlags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "syslog-address",
+			Usage:       "Syslog collector address as host:port. Events are forwarded to syslog if set",
+			Destination: &syslogAddress,
+			EnvVars:     []string{envPrefix + "SYSLOG_ADDRESS"},
+		},
+		&cli.StringFlag{
+			Name:        "syslog-network",
+			Usage:       `Syslog transport. Supported values: "udp", "tcp", "tls"`,
+			Value:       syslog.NetworkUDP,
+			Destination: &syslogNetwork,
+			EnvVars:     []string{envPrefix + "SYSLOG_NETWORK"},
+		},
+		&cli.StringFlag{
+			Name:        "syslog-format",
+			Usage:       `Syslog message format. Supported values: "rfc5424", "cef"`,
+			Value:       syslog.FormatRFC5424,
+			Destination: &syslogFormat,
+			EnvVars:     []string{envPrefix + "SYSLOG_FORMAT"},
+		},
+		&cli.StringFlag{
+			Name:        "syslog-facility",
+			Usage:       "Syslog facility name",
+			Value:       "local0",
+			Destination: &syslogFacility,
+			EnvVars:     []string{envPrefix + "SYSLOG_FACILITY"},
+		},
+		&cli.StringFlag{
+			Name:        "syslog-hostname",
+			Usage:       "Hostname included in the syslog messages. Empty means the OS hostname",
+			Destination: &syslogHostname,
+			EnvVars:     []string{envPrefix + "SYSLOG_HOSTNAME"},
+		},
+		&cli.StringFlag{
+			Name:        "syslog-tls-config",
+			Usage:       "Custom TLS config for the tls network, same syntax as custom-tls (optional)",
+			Destination: &syslogTLSConfig,
+			EnvVars:     []string{envPrefix + "SYSLOG_TLS_CONFIG"},
+		},
+		&cli.IntFlag{
+			Name:        "syslog-buffer-size",
+			Usage:       "Maximum number of messages buffered while the collector is unreachable, the oldest ones are dropped if the buffer is full. An unreachable collector is detected over TCP and TLS only",
+			Value:       10000,
+			Destination: &syslogBufferSize,
+			EnvVars:     []string{envPrefix + "SYSLOG_BUFFER_SIZE"},
+		},
+	}
+)
+
+func getSyslogSink() (*db.SinkConfig, error) {
+	if syslogAddress == "" {
+		return nil, nil
+	}
+	var tlsConfig *tls.Config
+	if syslogTLSConfig != "" {
+		var err error
+		tlsConfig, err = db.GetTLSConfig(syslogTLSConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+	sink, err := syslog.NewSink(syslog.Config{
+		Network:    syslogNetwork,
+		Address:    syslogAddress,
+		Format:     syslogFormat,
+		Facility:   syslogFacility,
+		Hostname:   syslogHostname,
+		Version:    getVersionString(),
+		TLSConfig:  tlsConfig,
+		BufferSize: syslogBufferSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	addCloser(sink.Name(), sink.Close)
+	// messages are sent asynchronously and buffered while the collector is unreachable
+	return &db.SinkConfig{
+		Sink: sink,
+	}, nil
+}
@@ -0,0 +1,166 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/sinks/webhook"
+)
+
+var (
+	webhookURLs        cli.StringSlice
+	webhookSecret      string
+	webhookMaxRetries  int
+	webhookQueueSize   int
+	webhookWorkers     int
+	webhookTLSConfig   string
+	webhookReplayIDs   cli.StringSlice
+	webhookReplayLimit int
+
+	webhookCommonFlags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "webhook-secret",
+			Usage:       "Secret used to sign the webhook deliveries using HMAC-SHA256",
+			Destination: &webhookSecret,
+			EnvVars:     []string{envPrefix + "WEBHOOK_SECRET"},
+		},
+		&cli.IntFlag{
+			Name:        "webhook-max-retries",
+			Usage:       "Maximum number of retries for a failed webhook delivery, with exponential backoff",
+			Value:       5,
+			Destination: &webhookMaxRetries,
+			EnvVars:     []string{envPrefix + "WEBHOOK_MAX_RETRIES"},
+		},
+		&cli.StringFlag{
+			Name:        "webhook-tls-config",
+			Usage:       "Custom TLS config for webhook deliveries, same syntax as custom-tls (optional)",
+			Destination: &webhookTLSConfig,
+			EnvVars:     []string{envPrefix + "WEBHOOK_TLS_CONFIG"},
+		},
+	}
+
+	webhookFlags = slices.Concat([]cli.Flag{
+		&cli.StringSliceFlag{
+			Name:        "webhook-urls",
+			Usage:       "Webhook URLs. Events are delivered to all these URLs if at least one is set",
+			Destination: &webhookURLs,
+			EnvVars:     []string{envPrefix + "WEBHOOK_URLS"},
+		},
+		&cli.IntFlag{
+			Name:        "webhook-queue-size",
+			Usage:       "Maximum number of pending webhook deliveries, if the queue is full the deliveries are saved as dead letters",
+			Value:       1000,
+			Destination: &webhookQueueSize,
+			EnvVars:     []string{envPrefix + "WEBHOOK_QUEUE_SIZE"},
+		},
+		&cli.IntFlag{
+			Name:        "webhook-workers",
+			Usage:       "Number of concurrent webhook deliveries",
+			Value:       4,
+			Destination: &webhookWorkers,
+			EnvVars:     []string{envPrefix + "WEBHOOK_WORKERS"},
+		},
+	}, webhookCommonFlags)
+
+	webhookReplayFlags = slices.Concat(dbFlags, webhookCommonFlags, []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:        "id",
+			Usage:       "Dead letter IDs to replay. Empty means all",
+			Destination: &webhookReplayIDs,
+		},
+		&cli.IntFlag{
+			Name:        "limit",
+			Usage:       "Maximum number of dead letters to replay. 0 means no limit",
+			Destination: &webhookReplayLimit,
+		},
+	})
+
+	webhookCmd = &cli.Command{
+		Name:  "webhook",
+		Usage: "Manage webhook deliveries",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "replay",
+				Usage: "Resend the failed webhook deliveries saved as dead letters",
+				Flags: webhookReplayFlags,
+				Action: func(_ *cli.Context) error {
+					config, err := getWebhookConfig()
+					if err != nil {
+						return err
+					}
+					if err := initializeDatabase(false); err != nil {
+						logger.AppLogger.Error("unable to initialize database", "error", err)
+						return err
+					}
+					result, err := webhook.Replay(config, webhookReplayIDs.Value(), webhookReplayLimit)
+					logger.AppLogger.Info("webhook replay completed", "delivered", result.Delivered,
+						"failed", result.Failed)
+					if err != nil {
+						logger.AppLogger.Error("unable to replay webhook dead letters", "error", err)
+						return err
+					}
+					if result.Failed > 0 {
+						return fmt.Errorf("%d webhook deliveries failed", result.Failed)
+					}
+					return nil
+				},
+			},
+		},
+	}
+)
+
+func getWebhookConfig() (webhook.Config, error) {
+	config := webhook.Config{
+		URLs:       webhookURLs.Value(),
+		Secret:     webhookSecret,
+		MaxRetries: webhookMaxRetries,
+		QueueSize:  webhookQueueSize,
+		Workers:    webhookWorkers,
+		UserAgent:  "sftpgo-plugin-eventstore/" + getVersionString(),
+	}
+	if webhookTLSConfig != "" {
+		tlsConfig, err := db.GetTLSConfig(webhookTLSConfig)
+		if err != nil {
+			return config, err
+		}
+		config.TLSConfig = tlsConfig
+	}
+	return config, nil
+}
+
+func getWebhookSink() (*db.SinkConfig, error) {
+	if len(webhookURLs.Value()) == 0 {
+		return nil, nil
+	}
+	config, err := getWebhookConfig()
+	if err != nil {
+		return nil, err
+	}
+	sink, err := webhook.NewSink(config)
+	if err != nil {
+		return nil, err
+	}
+	addCloser(sink.Name(), sink.Close)
+	// the deliveries are asynchronous, failures are saved as dead letters
+	return &db.SinkConfig{
+		Sink: sink,
+	}, nil
+}
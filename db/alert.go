@@ -0,0 +1,131 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"time"
+
+	"github.com/rs/xid"
+	"gorm.io/gorm"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+// Alert severities
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// Alert defines an alert raised by a detection rule
+type Alert struct {
+	ID        string `json:"id" gorm:"primaryKey"`
+	Timestamp int64  `json:"timestamp"`
+	// Rule is the name of the rule that raised the alert
+	Rule string `json:"rule"`
+	// Subject identifies what the alert refers to, for example an IP
+	// address or a username
+	Subject string `json:"subject"`
+	// Events is the number of events that triggered the alert
+	Events     int    `json:"events"`
+	Message    string `json:"message"`
+	InstanceID string `json:"instance_id,omitempty"`
+	Severity   string `json:"severity"`
+	// EventIDs contains the IDs of the events that triggered the alert, at
+	// most the most recent 100
+	EventIDs []string `json:"event_ids,omitempty" gorm:"serializer:json"`
+}
+
+// TableName defines the database table name
+func (a *Alert) TableName() string {
+	return tableName("alerts")
+}
+
+// BeforeCreate implements gorm hook
+func (a *Alert) BeforeCreate(_ *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = xid.New().String()
+	}
+	return nil
+}
+
+// Create persists the object
+func (a *Alert) Create(tx *gorm.DB) error {
+	return tx.Create(a).Error
+}
+
+// SaveAlert persists the given alert using the default session
+func SaveAlert(a *Alert) error {
+	sess, cancel := GetDefaultSession()
+	defer cancel()
+
+	return a.Create(sess)
+}
+
+// AlertFilter defines the filters to list the alerts, empty fields match all
+// alerts
+type AlertFilter struct {
+	// From and To define the time range, To is excluded
+	From       time.Time
+	To         time.Time
+	Rules      []string
+	Severities []string
+	Subject    string
+	Limit      int
+}
+
+// GetAlerts returns the alerts matching the specified filter, the most recent
+// first
+func GetAlerts(filter AlertFilter) ([]Alert, error) {
+	sess, cancel := GetDefaultSession()
+	defer cancel()
+
+	if !filter.From.IsZero() {
+		sess = sess.Where("timestamp >= ?", filter.From.UnixNano())
+	}
+	if !filter.To.IsZero() {
+		sess = sess.Where("timestamp < ?", filter.To.UnixNano())
+	}
+	if len(filter.Rules) > 0 {
+		sess = sess.Where("rule IN ?", filter.Rules)
+	}
+	if len(filter.Severities) > 0 {
+		sess = sess.Where("severity IN ?", filter.Severities)
+	}
+	if filter.Subject != "" {
+		sess = sess.Where("subject = ?", filter.Subject)
+	}
+	if filter.Limit > 0 {
+		sess = sess.Limit(filter.Limit)
+	}
+	var result []Alert
+	err := sess.Order("timestamp DESC, id DESC").Find(&result).Error
+	return result, err
+}
+
+func cleanupAlerts(timestamp time.Time) error {
+	logger.AppLogger.Debug("removing alerts", "timestamp", timestamp)
+	sess, cancel := getSessionWithTimeout(20 * time.Minute)
+	defer cancel()
+
+	sess = sess.Where("timestamp < ?", timestamp.UnixNano()).Delete(&Alert{})
+	err := sess.Error
+	if err == nil {
+		logger.AppLogger.Debug("alerts deleted", "num", sess.RowsAffected)
+	}
+	return err
+}
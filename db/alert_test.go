@@ -0,0 +1,117 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sftpgo/sdk/plugin/notifier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlerts(t *testing.T) {
+	now := time.Now()
+	alerts := []*Alert{
+		{Timestamp: now.Add(-2 * time.Hour).UnixNano(), Rule: "ip_login_failures", Subject: "10.0.0.1", Events: 10},
+		{Timestamp: now.Add(-time.Hour).UnixNano(), Rule: "username_login_failures", Subject: "user1", Events: 5},
+		{Timestamp: now.UnixNano(), Rule: "ip_login_failures", Subject: "10.0.0.2", Events: 10,
+			Message: "10 login failures", InstanceID: "sftpgo1", Severity: SeverityHigh,
+			EventIDs: []string{"id1", "id2"}},
+	}
+	for _, a := range alerts {
+		require.NoError(t, SaveAlert(a))
+		assert.NotEmpty(t, a.ID)
+	}
+
+	result, err := GetAlerts(AlertFilter{})
+	require.NoError(t, err)
+	require.Len(t, result, 3)
+	assert.Equal(t, *alerts[2], result[0])
+	result, err = GetAlerts(AlertFilter{Limit: 1, Rules: []string{"ip_login_failures"}})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, alerts[2].ID, result[0].ID)
+	result, err = GetAlerts(AlertFilter{From: now.Add(-90 * time.Minute), To: now})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, alerts[1].ID, result[0].ID)
+	result, err = GetAlerts(AlertFilter{Severities: []string{SeverityHigh, SeverityCritical}})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, []string{"id1", "id2"}, result[0].EventIDs)
+	result, err = GetAlerts(AlertFilter{Subject: "10.0.0.1"})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, alerts[0].ID, result[0].ID)
+
+	Cleanup(now.Add(-30 * time.Minute))
+	result, err = GetAlerts(AlertFilter{})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	Cleanup(time.Now().Add(1 * time.Hour))
+}
+
+func TestLogEventsSince(t *testing.T) {
+	n := Notifier{}
+	now := time.Now()
+	for idx, event := range []notifier.LogEventType{1, 2, 5} {
+		require.NoError(t, n.NotifyLogEvent(&notifier.LogEvent{
+			Timestamp: now.Add(-time.Duration(idx) * time.Minute).UnixNano(),
+			Event:     event,
+			Username:  "user1",
+		}))
+	}
+	events, err := GetLogEventsSince([]int{1, 2}, now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, 2, events[0].Event)
+	assert.Equal(t, 1, events[1].Event)
+	events, err = GetLogEventsSince([]int{1, 2}, now.Add(-30*time.Second))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	Cleanup(time.Now().Add(1 * time.Hour))
+}
+
+func TestFsEventsSince(t *testing.T) {
+	n := Notifier{}
+	now := time.Now()
+	events := []*notifier.FsEvent{
+		{Timestamp: now.Add(-2 * time.Hour).UnixNano(), Action: "upload", Username: "user1", FileSize: 100, Status: 1},
+		{Timestamp: now.Add(-time.Hour).UnixNano(), Action: "upload", Username: "user1", FileSize: 200, Status: 1},
+		{Timestamp: now.Add(-time.Hour).UnixNano(), Action: "upload", Username: "user1", FileSize: 300, Status: 2},
+		{Timestamp: now.Add(-time.Minute).UnixNano(), Action: "delete", Username: "user1", Status: 1},
+		{Timestamp: now.UnixNano(), Action: "download", Username: "user1", FileSize: 400, Status: 1},
+	}
+	for _, ev := range events {
+		require.NoError(t, n.NotifyFsEvent(ev))
+	}
+	result, err := GetFsEventsSince([]string{"upload", "delete"}, now.Add(-90*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, "upload", result[0].Action)
+	assert.Equal(t, "delete", result[1].Action)
+
+	bytes, err := GetUserUploadedBytes("user1", now.Add(-3*time.Hour), now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(300), bytes)
+	bytes, err = GetUserUploadedBytes("user2", now.Add(-3*time.Hour), now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), bytes)
+
+	Cleanup(time.Now().Add(1 * time.Hour))
+}
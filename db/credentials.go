@@ -0,0 +1,182 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+var (
+	// handlePool is the connection pool used by the database handle
+	handlePool    *connPool
+	credentialsMu sync.Mutex
+	customTLS     string
+	maxOpenConns  int
+	maxIdleConns  int
+)
+
+// connPool is the gorm connection pool for the database handle, it uses the
+// current database pool, replaced when the credentials are updated
+type connPool struct {
+	db atomic.Pointer[sql.DB]
+}
+
+func newConnPool(sqlDB *sql.DB) *connPool {
+	p := &connPool{}
+	p.db.Store(sqlDB)
+	return p
+}
+
+func (p *connPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.db.Load().PrepareContext(ctx, query)
+}
+
+func (p *connPool) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return p.db.Load().ExecContext(ctx, query, args...)
+}
+
+func (p *connPool) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return p.db.Load().QueryContext(ctx, query, args...)
+}
+
+func (p *connPool) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return p.db.Load().QueryRowContext(ctx, query, args...)
+}
+
+func (p *connPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return p.db.Load().BeginTx(ctx, opts)
+}
+
+// GetDBConn returns the current database pool, it is used by gorm for DB()
+func (p *connPool) GetDBConn() (*sql.DB, error) {
+	return p.db.Load(), nil
+}
+
+// swap replaces the database pool. The old pool is closed after the default
+// query timeout, so the callers that got it before the swap can still use it.
+// Closing a pool does not interrupt the connections in use, they are closed
+// when released
+func (p *connPool) swap(sqlDB *sql.DB) {
+	oldDB := p.db.Swap(sqlDB)
+	time.AfterFunc(defaultQueryTimeout, func() {
+		if err := oldDB.Close(); err != nil {
+			logger.AppLogger.Warn("unable to close the previous database pool", "error", err)
+		}
+	})
+}
+
+// setPoolLimits applies the configured limits to the specified pool
+func setPoolLimits(sqlDB *sql.DB) {
+	sqlDB.SetMaxOpenConns(maxOpenConns)
+	sqlDB.SetMaxIdleConns(maxIdleConns)
+	sqlDB.SetConnMaxIdleTime(4 * time.Minute)
+	sqlDB.SetConnMaxLifetime(2 * time.Minute)
+}
+
+// getPgxConfig parses the specified dsn, the password, if not empty,
+// overrides the one in the dsn
+func getPgxConfig(dsn, password, customTLSConfig string) (*pgx.ConnConfig, error) {
+	config, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse dsn: %w", err)
+	}
+	if password != "" {
+		config.Password = password
+	}
+	if schemaName != "" {
+		// unqualified names, including the ones in raw queries and
+		// migrations, are resolved using the search path
+		config.RuntimeParams["search_path"] = schemaName
+	}
+	if err := setPgxTLSConfig(config, customTLSConfig); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// getMySQLConfig parses the specified dsn, the password, if not empty,
+// overrides the one in the dsn
+func getMySQLConfig(dsn, password string) (*mysqldriver.Config, error) {
+	config, err := mysqldriver.ParseDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse dsn: %w", err)
+	}
+	if password != "" {
+		config.Passwd = password
+	}
+	return config, nil
+}
+
+// openMySQLDB returns a connection pool for the specified configuration
+func openMySQLDB(config *mysqldriver.Config) (*sql.DB, error) {
+	connector, err := mysqldriver.NewConnector(config)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(connector), nil
+}
+
+// UpdateCredentials replaces the dsn and the password, if not empty, used
+// for the database connections. A new connection pool is opened using the new
+// credentials and, if a connection can be established, it replaces the
+// current one. The previous pool is closed after the default query timeout,
+// so the in progress queries complete using the previous credentials
+func UpdateCredentials(dsn, password string) error {
+	credentialsMu.Lock()
+	defer credentialsMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultQueryTimeout)
+	defer cancel()
+
+	var sqlDB *sql.DB
+	switch driverName {
+	case driverNamePostgreSQL:
+		config, err := getPgxConfig(dsn, password, customTLS)
+		if err != nil {
+			return err
+		}
+		sqlDB = stdlib.OpenDB(*config)
+	case driverNameMySQL:
+		config, err := getMySQLConfig(dsn, password)
+		if err != nil {
+			return err
+		}
+		sqlDB, err = openMySQLDB(config)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported database driver %v", driverName)
+	}
+	setPoolLimits(sqlDB)
+	if err := sqlDB.PingContext(ctx); err != nil {
+		sqlDB.Close()
+		return fmt.Errorf("unable to connect using the new credentials: %w", err)
+	}
+	handlePool.swap(sqlDB)
+	logger.AppLogger.Info("database credentials updated, the previous connection pool will be closed")
+	return nil
+}
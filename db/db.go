@@ -16,16 +16,18 @@ package db
 
 import (
 	"context"
-	"crypto/tls"
-	"crypto/x509"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
-	"net/url"
 	"os"
+	"regexp"
 	"runtime"
+	"strings"
 	"time"
 
-	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -37,6 +39,12 @@ import (
 const (
 	driverNamePostgreSQL = "postgres"
 	driverNameMySQL      = "mysql"
+	// DefaultTablePrefix defines the default prefix for the table names
+	DefaultTablePrefix = "eventstore_"
+	// the longest index name is "idx_<prefix>provider_events_object_username",
+	// the PostgreSQL identifiers are limited to 63 characters
+	maxTablePrefixLen = 28
+	maxSchemaLen      = 63
 )
 
 var (
@@ -44,10 +52,53 @@ var (
 	Handle              *gorm.DB
 	defaultQueryTimeout = 20 * time.Second
 	driverName          string
+	tablePrefix         = DefaultTablePrefix
+	schemaName          string
+	identifierRegex     = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
 )
 
-// Initialize initializes the database engine
-func Initialize(driver, dsn, customTLSConfig string, dbDebug bool, poolSize int) error {
+// SetTablePrefix sets the prefix for the table names, it must be called
+// before using the database
+func SetTablePrefix(prefix string) error {
+	if err := ValidateIdentifier(prefix, maxTablePrefixLen); err != nil {
+		return fmt.Errorf("invalid table prefix: %w", err)
+	}
+	tablePrefix = prefix
+	return nil
+}
+
+// SetSchema sets the PostgreSQL schema for the tables, it must be called
+// before initializing the database. Empty means the default schema
+func SetSchema(schema string) error {
+	if schema != "" {
+		if err := ValidateIdentifier(schema, maxSchemaLen); err != nil {
+			return fmt.Errorf("invalid schema: %w", err)
+		}
+	}
+	schemaName = schema
+	return nil
+}
+
+// ValidateIdentifier checks that the specified value can be used, unquoted,
+// in table and schema names
+func ValidateIdentifier(value string, maxLen int) error {
+	if len(value) > maxLen {
+		return fmt.Errorf("%q is longer than %d characters", value, maxLen)
+	}
+	if !identifierRegex.MatchString(value) {
+		return fmt.Errorf("%q must start with a lowercase letter and contain only lowercase letters, digits and underscores",
+			value)
+	}
+	return nil
+}
+
+func tableName(name string) string {
+	return tablePrefix + name
+}
+
+// Initialize initializes the database engine, the password, if not empty,
+// overrides the one in the dsn
+func Initialize(driver, dsn, password, customTLSConfig string, dbDebug bool, poolSize int) error {
 	var err error
 
 	newLogger := gormlogger.Discard
@@ -64,52 +115,69 @@ func Initialize(driver, dsn, customTLSConfig string, dbDebug bool, poolSize int)
 	}
 
 	driverName = driver
+	customTLS = customTLSConfig
 
+	var sqlDB *sql.DB
 	switch driverName {
 	case driverNamePostgreSQL:
-		Handle, err = gorm.Open(postgres.New(postgres.Config{
-			DSN: dsn,
-		}), &gorm.Config{
-			SkipDefaultTransaction: true,
-			Logger:                 newLogger,
-		})
+		var pgxConfig *pgx.ConnConfig
+		pgxConfig, err = getPgxConfig(dsn, password, customTLSConfig)
 		if err != nil {
-			logger.AppLogger.Error("unable to create db handle", "error", err)
+			logger.AppLogger.Error("unable to get pgx config", "error", err)
 			return err
 		}
+		sqlDB = stdlib.OpenDB(*pgxConfig)
 	case driverNameMySQL:
+		if schemaName != "" {
+			return errors.New("the schema is only supported for PostgreSQL, set the database in the dsn")
+		}
 		if err := handleCustomTLSConfig(customTLSConfig); err != nil {
 			logger.AppLogger.Error("unable to register custom tls config", "error", err)
 			return err
 		}
-		Handle, err = gorm.Open(mysql.New(mysql.Config{
-			DSN: dsn,
-		}), &gorm.Config{
-			SkipDefaultTransaction: true,
-			Logger:                 newLogger,
-		})
+		mysqlConfig, err := getMySQLConfig(dsn, password)
+		if err != nil {
+			logger.AppLogger.Error("unable to get mysql config", "error", err)
+			return err
+		}
+		sqlDB, err = openMySQLDB(mysqlConfig)
 		if err != nil {
-			logger.AppLogger.Error("unable to create db handle", "error", err)
+			logger.AppLogger.Error("unable to open mysql connection pool", "error", err)
 			return err
 		}
 	default:
 		return fmt.Errorf("unsupported database driver %v", driverName)
 	}
 
-	sqlDB, err := Handle.DB()
-	if err != nil {
-		logger.AppLogger.Error("unable to get sql db handle", "error", err)
-		return err
+	maxOpenConns = poolSize
+	if poolSize > 0 {
+		maxIdleConns = poolSize
+	} else {
+		maxIdleConns = 2
 	}
+	setPoolLimits(sqlDB)
+	// the database handle uses the pool through handlePool, so the pool can
+	// be replaced when the credentials are updated
+	handlePool = newConnPool(sqlDB)
 
-	sqlDB.SetMaxOpenConns(poolSize)
-	if poolSize > 0 {
-		sqlDB.SetMaxIdleConns(poolSize)
+	var dialector gorm.Dialector
+	if driverName == driverNamePostgreSQL {
+		dialector = postgres.New(postgres.Config{
+			Conn: handlePool,
+		})
 	} else {
-		sqlDB.SetMaxIdleConns(2)
+		dialector = mysql.New(mysql.Config{
+			Conn: handlePool,
+		})
+	}
+	Handle, err = gorm.Open(dialector, &gorm.Config{
+		SkipDefaultTransaction: true,
+		Logger:                 newLogger,
+	})
+	if err != nil {
+		logger.AppLogger.Error("unable to create db handle", "error", err)
+		return err
 	}
-	sqlDB.SetConnMaxIdleTime(4 * time.Minute)
-	sqlDB.SetConnMaxLifetime(2 * time.Minute)
 
 	return sqlDB.Ping()
 }
@@ -141,52 +209,53 @@ func Cleanup(timestamp time.Time) {
 	if err := cleanupLogEvents(timestamp); err != nil {
 		logger.AppLogger.Error("unable to delete log events", "error", err)
 	}
-}
 
-func handleCustomTLSConfig(config string) error {
-	if config == "" {
-		return nil
+	if err := cleanupWebhookDeadLetters(timestamp); err != nil {
+		logger.AppLogger.Error("unable to delete webhook dead letters", "error", err)
 	}
-	values, err := url.ParseQuery(config)
-	if err != nil {
-		logger.AppLogger.Error("unable to parse custom tls config", "value", config, "error", err)
-		return fmt.Errorf("unable to parse tls config: %w", err)
+
+	if err := cleanupAlerts(timestamp); err != nil {
+		logger.AppLogger.Error("unable to delete alerts", "error", err)
 	}
-	rootCert := values.Get("root_cert")
-	clientCert := values.Get("client_cert")
-	clientKey := values.Get("client_key")
-	tlsMode := values.Get("tls_mode")
 
-	tlsConfig := &tls.Config{}
-	if rootCert != "" {
-		rootCAs, err := x509.SystemCertPool()
-		if err != nil {
-			rootCAs = x509.NewCertPool()
-		}
-		rootCrt, err := os.ReadFile(rootCert)
-		if err != nil {
-			return fmt.Errorf("unable to load root certificate %q: %v", rootCert, err)
-		}
-		if !rootCAs.AppendCertsFromPEM(rootCrt) {
-			return fmt.Errorf("unable to parse root certificate %q", rootCert)
-		}
-		tlsConfig.RootCAs = rootCAs
+	if err := cleanupInstances(timestamp); err != nil {
+		logger.AppLogger.Error("unable to delete instances", "error", err)
 	}
-	if clientCert != "" && clientKey != "" {
-		cert := make([]tls.Certificate, 0, 1)
-		tlsCert, err := tls.LoadX509KeyPair(clientCert, clientKey)
-		if err != nil {
-			return fmt.Errorf("unable to load key pair %q, %q: %v", clientCert, clientKey, err)
-		}
-		cert = append(cert, tlsCert)
-		tlsConfig.Certificates = cert
+}
+
+// WhereEventTime restricts the query to the events within the specified time
+// range, from is included and to is excluded, a zero time means no limit.
+// The limits are truncated to microseconds, like the event_time column. The
+// events not yet updated by the event time backfill are checked using the
+// timestamp column
+func WhereEventTime(sess *gorm.DB, from, to time.Time) *gorm.DB {
+	var eventTime, timestamp []string
+	var eventTimeArgs, timestampArgs []any
+	if !from.IsZero() {
+		from = getEventTime(from.UnixNano())
+		eventTime = append(eventTime, "event_time >= ?")
+		eventTimeArgs = append(eventTimeArgs, from)
+		timestamp = append(timestamp, "timestamp >= ?")
+		timestampArgs = append(timestampArgs, from.UnixNano())
 	}
-	if tlsMode == "1" {
-		tlsConfig.InsecureSkipVerify = true
+	if !to.IsZero() {
+		to = getEventTime(to.UnixNano())
+		eventTime = append(eventTime, "event_time < ?")
+		eventTimeArgs = append(eventTimeArgs, to)
+		timestamp = append(timestamp, "timestamp < ?")
+		timestampArgs = append(timestampArgs, to.UnixNano())
 	}
-
-	if err := mysqldriver.RegisterTLSConfig("custom", tlsConfig); err != nil {
-		return fmt.Errorf("unable to register tls config: %v", err)
+	if len(eventTime) == 0 {
+		return sess
 	}
-	return nil
+	query := fmt.Sprintf("((%s) OR (event_time IS NULL AND %s))", strings.Join(eventTime, " AND "),
+		strings.Join(timestamp, " AND "))
+	return sess.Where(query, append(eventTimeArgs, timestampArgs...)...)
+}
+
+// getEventTime returns the native event time for a timestamp in Unix
+// nanoseconds. The database columns have microseconds precision, the time is
+// truncated so it is always stored in the same way
+func getEventTime(timestamp int64) time.Time {
+	return time.Unix(0, timestamp).UTC().Truncate(time.Microsecond)
 }
@@ -17,7 +17,13 @@ package db
 import (
 	"fmt"
 	"os"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 
 	"github.com/sftpgo/sftpgo-plugin-eventstore/db/migration"
 )
@@ -30,7 +36,7 @@ func TestMain(m *testing.M) {
 		fmt.Println("Driver and/or DSN not set, unable to execute test")
 		os.Exit(1)
 	}
-	if err := Initialize(driver, dsn, customTLSConfig, true, 0); err != nil {
+	if err := Initialize(driver, dsn, "", customTLSConfig, true, 0); err != nil {
 		fmt.Printf("unable to initialize database: %v\n", err)
 		os.Exit(1)
 	}
@@ -41,3 +47,154 @@ func TestMain(m *testing.M) {
 	exitCode := m.Run()
 	os.Exit(exitCode)
 }
+
+func TestBackfillEventTime(t *testing.T) {
+	if name := Handle.Dialector.Name(); name != driverNamePostgreSQL && name != driverNameMySQL {
+		t.Skipf("backfill not supported for dialect %q", name)
+	}
+	ev := &FsEvent{
+		Timestamp: time.Now().UnixNano(),
+		Action:    "upload",
+		Username:  "username",
+		Protocol:  "SFTP",
+	}
+	sess, cancel := GetDefaultSession()
+	defer cancel()
+
+	require.NoError(t, ev.Create(sess))
+	// simulate an event saved before the event_time column was added
+	err := sess.Model(&FsEvent{}).Where("id = ?", ev.ID).Update("event_time", nil).Error
+	require.NoError(t, err)
+	require.NoError(t, migration.BackfillEventTime(Handle))
+	// the backfill can be safely executed again
+	require.NoError(t, migration.BackfillEventTime(Handle))
+
+	var eventRead FsEvent
+	require.NoError(t, sess.Where("id = ?", ev.ID).First(&eventRead).Error)
+	assert.True(t, ev.EventTime.Equal(eventRead.EventTime), "expected %v, got %v", ev.EventTime, eventRead.EventTime)
+
+	Cleanup(time.Now().Add(1 * time.Hour))
+}
+
+func TestTablePrefix(t *testing.T) {
+	assert.Error(t, SetTablePrefix(""))
+	assert.Error(t, SetTablePrefix("1tenant_"))
+	assert.Error(t, SetTablePrefix("Tenant_"))
+	assert.Error(t, SetTablePrefix(strings.Repeat("a", maxTablePrefixLen+1)))
+	assert.Error(t, SetSchema("tenant-1"))
+	assert.NoError(t, SetSchema(""))
+
+	defaultHandle := Handle
+	t.Cleanup(func() {
+		Handle = defaultHandle
+		assert.NoError(t, SetTablePrefix(DefaultTablePrefix))
+		migration.SetTablePrefix(DefaultTablePrefix)
+	})
+	require.NoError(t, SetTablePrefix("tenant1_"))
+	migration.SetTablePrefix("tenant1_")
+	assert.Equal(t, "tenant1_fs_events", (&FsEvent{}).TableName())
+	assert.Equal(t, "tenant1_alerts", (&Alert{}).TableName())
+	// gorm caches the table names, a new handle is required
+	var err error
+	Handle, err = gorm.Open(defaultHandle.Dialector, &gorm.Config{SkipDefaultTransaction: true})
+	require.NoError(t, err)
+	require.NoError(t, migration.MigrateDatabase(Handle))
+	assert.True(t, Handle.Migrator().HasTable("tenant1_migrations"))
+	assert.True(t, Handle.Migrator().HasTable("tenant1_fs_events"))
+	assert.True(t, Handle.Migrator().HasIndex("tenant1_fs_events", "idx_tenant1_fs_events_timestamp"))
+	assert.True(t, Handle.Migrator().HasIndex("tenant1_fs_events", "idx_tenant1_fs_events_country_code"))
+	assert.False(t, Handle.Migrator().HasIndex("tenant1_fs_events", "idx_fs_events_timestamp"))
+
+	ev := &FsEvent{
+		Timestamp: time.Now().UnixNano(),
+		Action:    "upload",
+		Username:  "username",
+		Protocol:  "SFTP",
+	}
+	sess, cancel := GetDefaultSession()
+	defer cancel()
+
+	require.NoError(t, ev.Create(sess))
+	var count int64
+	require.NoError(t, sess.Table("tenant1_fs_events").Where("id = ?", ev.ID).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+	require.NoError(t, sess.Table("eventstore_fs_events").Where("id = ?", ev.ID).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+
+	require.NoError(t, migration.ResetDatabase(Handle))
+	assert.False(t, Handle.Migrator().HasTable("tenant1_fs_events"))
+	assert.False(t, Handle.Migrator().HasTable("tenant1_migrations"))
+	assert.True(t, Handle.Migrator().HasTable("eventstore_fs_events"))
+}
+
+func TestCleanupWithoutEventTime(t *testing.T) {
+	sess, cancel := GetDefaultSession()
+	defer cancel()
+
+	now := time.Now()
+	var ids []string
+	for _, ts := range []time.Time{now.Add(-2 * time.Hour), now} {
+		fsEvent := &FsEvent{Timestamp: ts.UnixNano(), Action: "upload", Username: "username"}
+		require.NoError(t, fsEvent.Create(sess))
+		providerEvent := &ProviderEvent{Timestamp: ts.UnixNano(), Action: "add", ObjectType: "user", ObjectName: "user1"}
+		require.NoError(t, providerEvent.Create(sess))
+		logEvent := &LogEvent{Timestamp: ts.UnixNano(), Event: 1}
+		require.NoError(t, logEvent.Create(sess))
+		ids = append(ids, fsEvent.ID, providerEvent.ID, logEvent.ID)
+	}
+	// simulate events saved before the event_time column was added
+	for _, model := range []any{&FsEvent{}, &ProviderEvent{}, &LogEvent{}} {
+		err := sess.Model(model).Where("id IN ?", ids).Update("event_time", nil).Error
+		require.NoError(t, err)
+	}
+	Cleanup(now.Add(-1 * time.Hour))
+
+	var count int64
+	require.NoError(t, sess.Model(&FsEvent{}).Where("id IN ?", ids).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+	require.NoError(t, sess.Model(&ProviderEvent{}).Where("id IN ?", ids).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+	require.NoError(t, sess.Model(&LogEvent{}).Where("id IN ?", ids).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	Cleanup(now.Add(1 * time.Hour))
+}
+
+func TestQueriesWithoutEventTime(t *testing.T) {
+	sess, cancel := GetDefaultSession()
+	defer cancel()
+
+	now := time.Now()
+	var ids []string
+	for idx, ts := range []time.Time{now.Add(-2 * time.Hour), now.Add(-30 * time.Minute), now.Add(-20 * time.Minute)} {
+		fsEvent := &FsEvent{Timestamp: ts.UnixNano(), Action: "upload", Username: "user_no_event_time",
+			FileSize: int64(idx + 1), Status: 1, SessionID: "session_no_event_time"}
+		require.NoError(t, fsEvent.Create(sess))
+		ids = append(ids, fsEvent.ID)
+	}
+	// simulate the events saved before the event_time column was added,
+	// except the last one
+	err := sess.Model(&FsEvent{}).Where("id IN ?", ids[:2]).Update("event_time", nil).Error
+	require.NoError(t, err)
+
+	uploaded, err := GetUserUploadedBytes("user_no_event_time", now.Add(-time.Hour), now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), uploaded)
+	stats, err := GetUserActionStats("user_no_event_time", now.Add(-3*time.Hour), now)
+	require.NoError(t, err)
+	assert.Equal(t, []ActionStats{{Action: "upload", Count: 3, Bytes: 6}}, stats)
+	sessions, err := GetTopSessions(now.Add(-time.Hour), now, SessionOrderBytes, 10)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "session_no_event_time", sessions[0].SessionID)
+	assert.Equal(t, int64(5), sessions[0].BytesUploaded)
+	var replayed []string
+	err = ReplayFsEvents(now.Add(-3*time.Hour), now.Add(-25*time.Minute), func(ev *FsEvent) error {
+		replayed = append(replayed, ev.ID)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ids[:2], replayed)
+
+	Cleanup(now.Add(1 * time.Hour))
+}
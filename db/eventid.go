@@ -0,0 +1,80 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+)
+
+// idHasher computes the event IDs from the event content, this way an event
+// resent by SFTPGo, for example because the response to a successful insert
+// was lost, gets the same ID and it is not saved twice
+type idHasher struct {
+	h   hash.Hash
+	buf [8]byte
+}
+
+func newIDHasher() *idHasher {
+	return &idHasher{
+		h: sha256.New(),
+	}
+}
+
+func (h *idHasher) int(v int64) *idHasher {
+	binary.BigEndian.PutUint64(h.buf[:], uint64(v))
+	h.h.Write(h.buf[:])
+	return h
+}
+
+// bytes writes the value prefixed by its length, so adjacent fields cannot
+// produce the same input
+func (h *idHasher) bytes(v []byte) *idHasher {
+	h.int(int64(len(v)))
+	h.h.Write(v)
+	return h
+}
+
+func (h *idHasher) string(v string) *idHasher {
+	h.int(int64(len(v)))
+	h.h.Write([]byte(v))
+	return h
+}
+
+// sum returns the first 128 bits of the hash, hex encoded
+func (h *idHasher) sum() string {
+	return hex.EncodeToString(h.h.Sum(nil)[:16])
+}
+
+func (ev *FsEvent) computeID() string {
+	return newIDHasher().int(ev.Timestamp).string(ev.Action).string(ev.Username).string(ev.FsPath).
+		string(ev.FsTargetPath).string(ev.VirtualPath).string(ev.VirtualTargetPath).string(ev.SSHCmd).
+		int(ev.FileSize).int(ev.Elapsed).int(int64(ev.Status)).string(ev.Protocol).string(ev.IP).
+		string(ev.SessionID).int(int64(ev.FsProvider)).string(ev.Bucket).string(ev.Endpoint).
+		int(int64(ev.OpenFlags)).string(ev.Role).string(ev.InstanceID).sum()
+}
+
+func (ev *ProviderEvent) computeID() string {
+	return newIDHasher().int(ev.Timestamp).string(ev.Action).string(ev.Username).string(ev.IP).
+		string(ev.ObjectType).string(ev.ObjectName).bytes(ev.ObjectData).string(ev.Role).
+		string(ev.InstanceID).sum()
+}
+
+func (ev *LogEvent) computeID() string {
+	return newIDHasher().int(ev.Timestamp).int(int64(ev.Event)).string(ev.Protocol).string(ev.Username).
+		string(ev.IP).string(ev.Message).string(ev.Role).string(ev.InstanceID).sum()
+}
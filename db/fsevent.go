@@ -19,49 +19,87 @@ import (
 
 	"github.com/rs/xid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
 )
 
 // FsEvent defines a filesystem event
 type FsEvent struct {
-	ID                string `json:"id" gorm:"primaryKey"`
-	Timestamp         int64  `json:"timestamp"`
-	Action            string `json:"action"`
-	Username          string `json:"username"`
-	FsPath            string `json:"fs_path"`
-	FsTargetPath      string `json:"fs_target_path,omitempty"`
-	VirtualPath       string `json:"virtual_path"`
-	VirtualTargetPath string `json:"virtual_target_path,omitempty"`
-	SSHCmd            string `json:"ssh_cmd,omitempty"`
-	FileSize          int64  `json:"file_size,omitempty"`
-	Elapsed           int64  `json:"elapsed,omitempty"`
-	Status            int    `json:"status"`
-	Protocol          string `json:"protocol"`
-	IP                string `json:"ip,omitempty"`
-	SessionID         string `json:"session_id"`
-	FsProvider        int    `json:"fs_provider"`
-	Bucket            string `json:"bucket,omitempty"`
-	Endpoint          string `json:"endpoint,omitempty"`
-	OpenFlags         int    `json:"open_flags,omitempty"`
-	Role              string `json:"role,omitempty"`
-	InstanceID        string `json:"instance_id,omitempty"`
+	ID                string `json:"id" gorm:"primaryKey" parquet:"id"`
+	Timestamp         int64  `json:"timestamp" parquet:"timestamp,timestamp(nanosecond)"`
+	Action            string `json:"action" parquet:"action"`
+	Username          string `json:"username" parquet:"username"`
+	FsPath            string `json:"fs_path" parquet:"fs_path"`
+	FsTargetPath      string `json:"fs_target_path,omitempty" parquet:"fs_target_path"`
+	VirtualPath       string `json:"virtual_path" parquet:"virtual_path"`
+	VirtualTargetPath string `json:"virtual_target_path,omitempty" parquet:"virtual_target_path"`
+	SSHCmd            string `json:"ssh_cmd,omitempty" parquet:"ssh_cmd"`
+	FileSize          int64  `json:"file_size,omitempty" parquet:"file_size"`
+	Elapsed           int64  `json:"elapsed,omitempty" parquet:"elapsed"`
+	Status            int    `json:"status" parquet:"status"`
+	Protocol          string `json:"protocol" parquet:"protocol"`
+	IP                string `json:"ip,omitempty" parquet:"ip"`
+	// CountryCode, City and ASN are set if the GeoIP enrichment is enabled
+	CountryCode string `json:"country_code,omitempty" parquet:"country_code"`
+	City        string `json:"city,omitempty" parquet:"city"`
+	ASN         uint32 `json:"asn,omitempty" gorm:"column:asn" parquet:"asn"`
+	SessionID   string `json:"session_id" parquet:"session_id"`
+	FsProvider  int    `json:"fs_provider" parquet:"fs_provider"`
+	Bucket      string `json:"bucket,omitempty" parquet:"bucket"`
+	Endpoint    string `json:"endpoint,omitempty" parquet:"endpoint"`
+	OpenFlags   int    `json:"open_flags,omitempty" parquet:"open_flags"`
+	Role        string `json:"role,omitempty" parquet:"role"`
+	InstanceID  string `json:"instance_id,omitempty" parquet:"instance_id"`
+	// EventTime is the native representation of Timestamp, truncated to
+	// microseconds
+	EventTime time.Time `json:"-" parquet:"-"`
 }
 
 // TableName defines the database table name
 func (ev *FsEvent) TableName() string {
-	return "eventstore_fs_events"
+	return tableName("fs_events")
 }
 
 // BeforeCreate implements gorm hook
 func (ev *FsEvent) BeforeCreate(_ *gorm.DB) error {
-	ev.ID = xid.New().String()
+	if ev.ID == "" {
+		ev.ID = xid.New().String()
+	}
+	if ev.EventTime.IsZero() {
+		ev.EventTime = getEventTime(ev.Timestamp)
+	}
 	return nil
 }
 
-// Create persists the object
+// Create persists the object, an event with the same ID is ignored
 func (ev *FsEvent) Create(tx *gorm.DB) error {
-	return tx.Create(ev).Error
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(ev).Error
+}
+
+// GetFsEventsSince returns the successful filesystem events with the specified
+// actions newer than or equal to from, the oldest first
+func GetFsEventsSince(actions []string, from time.Time) ([]FsEvent, error) {
+	sess, cancel := getSessionWithTimeout(5 * time.Minute)
+	defer cancel()
+
+	var result []FsEvent
+	err := WhereEventTime(sess.Where("action IN ? AND status = ?", actions, 1), from, time.Time{}).
+		Order("timestamp ASC, id ASC").Find(&result).Error
+	return result, err
+}
+
+// GetUserUploadedBytes returns the bytes successfully uploaded by the specified
+// user within the specified time range, to is excluded
+func GetUserUploadedBytes(username string, from, to time.Time) (int64, error) {
+	sess, cancel := getSessionWithTimeout(time.Minute)
+	defer cancel()
+
+	var result int64
+	sess = sess.Model(&FsEvent{}).Where("username = ? AND action = ? AND status = ?", username, "upload", 1)
+	err := WhereEventTime(sess, from, to).
+		Select("COALESCE(SUM(file_size), 0)").Scan(&result).Error
+	return result, err
 }
 
 func cleanupFsEvents(timestamp time.Time) error {
@@ -69,7 +107,7 @@ func cleanupFsEvents(timestamp time.Time) error {
 	sess, cancel := getSessionWithTimeout(20 * time.Minute)
 	defer cancel()
 
-	sess = sess.Where("timestamp < ?", timestamp.UnixNano()).Delete(&FsEvent{})
+	sess = WhereEventTime(sess, time.Time{}, timestamp).Delete(&FsEvent{})
 	err := sess.Error
 	if err == nil {
 		logger.AppLogger.Debug("fs events deleted", "num", sess.RowsAffected)
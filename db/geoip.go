@@ -0,0 +1,161 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+// UnknownCountryCode is the country code, "unknown region" in CLDR, saved for
+// the IP addresses not found in the GeoIP databases, so the backfill does not
+// process them again
+const UnknownCountryCode = "ZZ"
+
+// GeoLocation defines the geolocation of an IP address, empty fields are not
+// known
+type GeoLocation struct {
+	CountryCode string
+	City        string
+	ASN         uint32
+}
+
+// IsEmpty returns true if no field is set
+func (l *GeoLocation) IsEmpty() bool {
+	return l.CountryCode == "" && l.City == "" && l.ASN == 0
+}
+
+// GeoIPResolver resolves the geolocation of the IP addresses
+type GeoIPResolver interface {
+	Lookup(ip string) (GeoLocation, error)
+}
+
+func (n *Notifier) lookupIP(ip string) GeoLocation {
+	if n.GeoIP == nil || ip == "" {
+		return GeoLocation{}
+	}
+	return resolveIP(n.GeoIP, ip)
+}
+
+func resolveIP(resolver GeoIPResolver, ip string) GeoLocation {
+	location, err := resolver.Lookup(ip)
+	if err != nil {
+		logger.AppLogger.Debug("unable to resolve IP geolocation", "ip", ip, "error", err)
+	}
+	if location.CountryCode == "" {
+		location.CountryCode = UnknownCountryCode
+	}
+	return location
+}
+
+type geoIPRow struct {
+	ID string
+	IP string
+}
+
+// BackfillGeoIP sets the geolocation for the stored events with an IP address
+// and without a country code, within the specified time range if not zero.
+// The events with an IP address not found are marked using UnknownCountryCode.
+// The events are processed in batches and each batch is updated in a
+// transaction. It returns the number of updated events
+func BackfillGeoIP(resolver GeoIPResolver, from, to time.Time, batchSize int) (int64, error) {
+	var total int64
+	for _, table := range []string{(&FsEvent{}).TableName(), (&ProviderEvent{}).TableName(), (&LogEvent{}).TableName()} {
+		updated, err := backfillGeoIPTable(resolver, table, from, to, batchSize)
+		total += updated
+		if err != nil {
+			return total, err
+		}
+		logger.AppLogger.Debug("geoip backfill completed", "table", table, "updated", updated)
+	}
+	return total, nil
+}
+
+func backfillGeoIPTable(resolver GeoIPResolver, table string, from, to time.Time, batchSize int) (int64, error) {
+	var updated int64
+	var lastID string
+	for {
+		rows, err := getGeoIPRows(table, from, to, lastID, batchSize)
+		if err != nil {
+			return updated, err
+		}
+		if len(rows) == 0 {
+			return updated, nil
+		}
+		lastID = rows[len(rows)-1].ID
+		locations := make(map[string]GeoLocation)
+		for _, row := range rows {
+			if _, ok := locations[row.IP]; ok {
+				continue
+			}
+			locations[row.IP] = resolveIP(resolver, row.IP)
+		}
+		sess, cancel := getSessionWithTimeout(5 * time.Minute)
+		err = sess.Transaction(func(tx *gorm.DB) error {
+			for _, row := range rows {
+				location := locations[row.IP]
+				err := tx.Table(table).Where("id = ?", row.ID).Updates(map[string]any{
+					"country_code": location.CountryCode,
+					"city":         location.City,
+					"asn":          location.ASN,
+				}).Error
+				if err != nil {
+					return err
+				}
+				updated++
+			}
+			return nil
+		})
+		cancel()
+		if err != nil {
+			return updated, err
+		}
+		logger.AppLogger.Debug("geoip backfill batch processed", "table", table, "rows", len(rows),
+			"updated", updated)
+	}
+}
+
+func getGeoIPRows(table string, from, to time.Time, lastID string, limit int) ([]geoIPRow, error) {
+	sess, cancel := getSessionWithTimeout(5 * time.Minute)
+	defer cancel()
+
+	sess = sess.Table(table).Select("id, ip").
+		Where("id > ? AND ip IS NOT NULL AND ip <> '' AND (country_code IS NULL OR country_code = '')", lastID)
+	sess = WhereEventTime(sess, from, to)
+	var result []geoIPRow
+	err := sess.Order("id ASC").Limit(limit).Scan(&result).Error
+	return result, err
+}
+
+func (ev *FsEvent) setGeoLocation(location GeoLocation) {
+	ev.CountryCode = location.CountryCode
+	ev.City = location.City
+	ev.ASN = location.ASN
+}
+
+func (ev *ProviderEvent) setGeoLocation(location GeoLocation) {
+	ev.CountryCode = location.CountryCode
+	ev.City = location.City
+	ev.ASN = location.ASN
+}
+
+func (ev *LogEvent) setGeoLocation(location GeoLocation) {
+	ev.CountryCode = location.CountryCode
+	ev.City = location.City
+	ev.ASN = location.ASN
+}
@@ -0,0 +1,152 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sftpgo/sdk/plugin/notifier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testGeoIPResolver struct {
+	locations map[string]GeoLocation
+	lookups   int
+}
+
+func (r *testGeoIPResolver) Lookup(ip string) (GeoLocation, error) {
+	r.lookups++
+	location, ok := r.locations[ip]
+	if !ok {
+		return location, errors.New("not found")
+	}
+	return location, nil
+}
+
+func TestGeoIPEnrichment(t *testing.T) {
+	resolver := &testGeoIPResolver{
+		locations: map[string]GeoLocation{
+			"81.2.69.160": {CountryCode: "GB", City: "London", ASN: 20712},
+		},
+	}
+	now := time.Now()
+	n := Notifier{GeoIP: resolver}
+	fsEvent := &notifier.FsEvent{Timestamp: now.UnixNano(), Action: "upload", Username: "user1",
+		IP: "81.2.69.160", Status: 1}
+	require.NoError(t, n.NotifyFsEvent(fsEvent))
+	require.NoError(t, n.NotifyProviderEvent(&notifier.ProviderEvent{Timestamp: now.UnixNano(), Action: "add",
+		ObjectType: "user", ObjectName: "user1", IP: "81.2.69.160"}))
+	require.NoError(t, n.NotifyLogEvent(&notifier.LogEvent{Timestamp: now.UnixNano(), Event: 1,
+		IP: "10.0.0.1"}))
+	// events without IP are not resolved
+	require.NoError(t, n.NotifyLogEvent(&notifier.LogEvent{Timestamp: now.UnixNano(), Event: 3}))
+	assert.Equal(t, 3, resolver.lookups)
+
+	var fsEvents []FsEvent
+	require.NoError(t, ReplayFsEvents(now.Add(-time.Minute), now.Add(time.Minute), func(ev *FsEvent) error {
+		fsEvents = append(fsEvents, *ev)
+		return nil
+	}))
+	require.Len(t, fsEvents, 1)
+	assert.Equal(t, "GB", fsEvents[0].CountryCode)
+	assert.Equal(t, "London", fsEvents[0].City)
+	assert.Equal(t, uint32(20712), fsEvents[0].ASN)
+	// the geolocation is not part of the ID
+	withoutGeoIP := Notifier{}
+	require.NoError(t, withoutGeoIP.NotifyFsEvent(fsEvent))
+	fsEvents = nil
+	require.NoError(t, ReplayFsEvents(now.Add(-time.Minute), now.Add(time.Minute), func(ev *FsEvent) error {
+		fsEvents = append(fsEvents, *ev)
+		return nil
+	}))
+	assert.Len(t, fsEvents, 1)
+
+	var providerEvents []ProviderEvent
+	require.NoError(t, ReplayProviderEvents(now.Add(-time.Minute), now.Add(time.Minute), func(ev *ProviderEvent) error {
+		providerEvents = append(providerEvents, *ev)
+		return nil
+	}))
+	require.Len(t, providerEvents, 1)
+	assert.Equal(t, "GB", providerEvents[0].CountryCode)
+	var logEvents []LogEvent
+	require.NoError(t, ReplayLogEvents(now.Add(-time.Minute), now.Add(time.Minute), func(ev *LogEvent) error {
+		logEvents = append(logEvents, *ev)
+		return nil
+	}))
+	require.Len(t, logEvents, 2)
+	for _, ev := range logEvents {
+		if ev.IP == "" {
+			assert.Empty(t, ev.CountryCode)
+		} else {
+			// the IP is not found
+			assert.Equal(t, UnknownCountryCode, ev.CountryCode)
+		}
+	}
+
+	Cleanup(time.Now().Add(1 * time.Hour))
+}
+
+func TestBackfillGeoIP(t *testing.T) {
+	now := time.Now()
+	n := Notifier{}
+	for idx, ip := range []string{"81.2.69.160", "10.0.0.1", "81.2.69.160", "", "10.0.0.2"} {
+		require.NoError(t, n.NotifyLogEvent(&notifier.LogEvent{Timestamp: now.Add(-time.Duration(idx) * time.Hour).UnixNano(),
+			Event: 1, IP: ip}))
+	}
+	require.NoError(t, n.NotifyFsEvent(&notifier.FsEvent{Timestamp: now.UnixNano(), Action: "upload",
+		IP: "10.0.0.2", Status: 1}))
+
+	resolver := &testGeoIPResolver{
+		locations: map[string]GeoLocation{
+			"81.2.69.160": {CountryCode: "GB", City: "London", ASN: 20712},
+			"10.0.0.2":    {CountryCode: "IT"},
+		},
+	}
+	// the 10.0.0.2 log event is outside the time range
+	updated, err := BackfillGeoIP(resolver, now.Add(-210*time.Minute), now.Add(time.Minute), 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), updated)
+	assert.Equal(t, 4, resolver.lookups)
+
+	var logEvents []LogEvent
+	require.NoError(t, ReplayLogEvents(now.Add(-5*time.Hour), now.Add(time.Minute), func(ev *LogEvent) error {
+		logEvents = append(logEvents, *ev)
+		return nil
+	}))
+	require.Len(t, logEvents, 5)
+	for _, ev := range logEvents {
+		if ev.IP == "81.2.69.160" {
+			assert.Equal(t, "GB", ev.CountryCode)
+			assert.Equal(t, "London", ev.City)
+			assert.Equal(t, uint32(20712), ev.ASN)
+		} else if ev.IP == "10.0.0.1" {
+			assert.Equal(t, UnknownCountryCode, ev.CountryCode)
+			assert.Empty(t, ev.City)
+		} else {
+			assert.Empty(t, ev.CountryCode)
+		}
+	}
+	// the processed events are skipped, including the ones with an IP not found
+	resolver.lookups = 0
+	updated, err = BackfillGeoIP(resolver, time.Time{}, time.Time{}, 100)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), updated)
+	assert.Equal(t, 1, resolver.lookups)
+
+	Cleanup(time.Now().Add(1 * time.Hour))
+}
@@ -0,0 +1,94 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+// Instance defines a plugin instance registered in the event store
+type Instance struct {
+	InstanceID string `json:"instance_id" gorm:"primaryKey"`
+	// Version is the plugin version
+	Version  string `json:"version"`
+	Hostname string `json:"hostname"`
+	// Driver is the database driver used by the instance
+	Driver string `json:"driver"`
+	// StartedAt and LastSeen are unix timestamps in nanoseconds
+	StartedAt int64 `json:"started_at"`
+	LastSeen  int64 `json:"last_seen"`
+}
+
+// TableName defines the database table name
+func (i *Instance) TableName() string {
+	return tableName("instances")
+}
+
+// Register persists the instance, replacing any existing registration with
+// the same instance ID
+func (i *Instance) Register() error {
+	sess, cancel := GetDefaultSession()
+	defer cancel()
+
+	if i.LastSeen == 0 {
+		i.LastSeen = i.StartedAt
+	}
+	return sess.Clauses(clause.OnConflict{UpdateAll: true}).Create(i).Error
+}
+
+// Heartbeat updates the last seen time for the instance, the instance is
+// registered again if its registration was removed
+func (i *Instance) Heartbeat() error {
+	sess, cancel := GetDefaultSession()
+	defer cancel()
+
+	i.LastSeen = time.Now().UnixNano()
+	res := sess.Model(&Instance{}).Where("instance_id = ?", i.InstanceID).Update("last_seen", i.LastSeen)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return i.Register()
+	}
+	return nil
+}
+
+// GetInstances returns the registered instances ordered by instance ID
+func GetInstances() ([]Instance, error) {
+	sess, cancel := GetDefaultSession()
+	defer cancel()
+
+	var result []Instance
+	err := sess.Order("instance_id ASC").Find(&result).Error
+	return result, err
+}
+
+// cleanupInstances removes the instances not seen since the specified time
+func cleanupInstances(lastSeen time.Time) error {
+	logger.AppLogger.Debug("removing instances", "last seen", lastSeen)
+	sess, cancel := GetDefaultSession()
+	defer cancel()
+
+	sess = sess.Where("last_seen < ?", lastSeen.UnixNano()).Delete(&Instance{})
+	err := sess.Error
+	if err == nil {
+		logger.AppLogger.Debug("instances deleted", "num", sess.RowsAffected)
+	}
+	return err
+}
@@ -0,0 +1,77 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstances(t *testing.T) {
+	startedAt := time.Now().Add(-time.Hour).UnixNano()
+	instance1 := &Instance{
+		InstanceID: "sftpgo1",
+		Version:    "1.0.0",
+		Hostname:   "host1",
+		Driver:     "postgres",
+		StartedAt:  startedAt,
+	}
+	require.NoError(t, instance1.Register())
+	instance2 := &Instance{
+		InstanceID: "sftpgo2",
+		Version:    "1.0.0",
+		Hostname:   "host2",
+		Driver:     "postgres",
+		StartedAt:  startedAt,
+	}
+	require.NoError(t, instance2.Register())
+
+	instances, err := GetInstances()
+	require.NoError(t, err)
+	require.Len(t, instances, 2)
+	assert.Equal(t, *instance1, instances[0])
+	assert.Equal(t, startedAt, instances[0].LastSeen)
+	// a restart replaces the registration
+	instance1.Version = "1.0.1"
+	instance1.StartedAt = time.Now().UnixNano()
+	instance1.LastSeen = 0
+	require.NoError(t, instance1.Register())
+	require.NoError(t, instance1.Heartbeat())
+	instances, err = GetInstances()
+	require.NoError(t, err)
+	require.Len(t, instances, 2)
+	assert.Equal(t, *instance1, instances[0])
+	assert.Greater(t, instances[0].LastSeen, startedAt)
+
+	Cleanup(time.Now().Add(-time.Minute))
+	instances, err = GetInstances()
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	assert.Equal(t, *instance1, instances[0])
+	// the heartbeat registers the removed instance again
+	require.NoError(t, instance2.Heartbeat())
+	instances, err = GetInstances()
+	require.NoError(t, err)
+	require.Len(t, instances, 2)
+	assert.Equal(t, *instance2, instances[1])
+
+	Cleanup(time.Now().Add(time.Minute))
+	instances, err = GetInstances()
+	require.NoError(t, err)
+	assert.Len(t, instances, 0)
+}
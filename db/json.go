@@ -0,0 +1,74 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// jsonColumn is a JSON document stored in a native JSON column. It is sent to
+// the database as a string, MySQL refuses to convert binary strings to JSON
+type jsonColumn []byte
+
+// Value implements driver.Valuer
+func (j jsonColumn) Value() (driver.Value, error) {
+	if j == nil {
+		return nil, nil
+	}
+	return string(j), nil
+}
+
+// Scan implements sql.Scanner
+func (j *jsonColumn) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*j = nil
+	case string:
+		*j = jsonColumn(v)
+	case []byte:
+		*j = bytes.Clone(v)
+	default:
+		return fmt.Errorf("unsupported type %T for a JSON column", src)
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, the document is not encoded again
+func (j jsonColumn) MarshalJSON() ([]byte, error) {
+	if j == nil {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (j *jsonColumn) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*j = nil
+		return nil
+	}
+	*j = bytes.Clone(data)
+	return nil
+}
+
+// isValidJSON returns true if data can be stored in a native JSON column.
+// PostgreSQL requires valid UTF-8 and does not allow the NUL character
+func isValidJSON(data []byte) bool {
+	return len(data) > 0 && json.Valid(data) && utf8.Valid(data) && !bytes.Contains(data, []byte(`\u0000`))
+}
@@ -0,0 +1,91 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsValidJSON(t *testing.T) {
+	assert.True(t, isValidJSON([]byte(`{"username":"user1","status":1}`)))
+	assert.True(t, isValidJSON([]byte(`null`)))
+	assert.False(t, isValidJSON(nil))
+	assert.False(t, isValidJSON([]byte(`data`)))
+	assert.False(t, isValidJSON([]byte(`{"username":"user1"`)))
+	assert.False(t, isValidJSON([]byte("{\"username\":\"\xff\"}")))
+	assert.False(t, isValidJSON([]byte(`{"username":"\u0000"}`)))
+}
+
+func TestJSONColumn(t *testing.T) {
+	var j jsonColumn
+	v, err := j.Value()
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+	j = jsonColumn(`{}`)
+	v, err = j.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, `{}`, v)
+
+	require.NoError(t, j.Scan(nil))
+	assert.Nil(t, j)
+	require.NoError(t, j.Scan(`{"a":1}`))
+	assert.Equal(t, jsonColumn(`{"a":1}`), j)
+	src := []byte(`{"b":2}`)
+	require.NoError(t, j.Scan(src))
+	src[2] = 'c'
+	assert.Equal(t, jsonColumn(`{"b":2}`), j)
+	assert.Error(t, j.Scan(1))
+}
+
+func TestProviderEventObjectData(t *testing.T) {
+	sess, cancel := GetDefaultSession()
+	defer cancel()
+
+	objectData := []byte(`{"username": "user1", "status": 1, "permissions": {"/": ["*"]}}`)
+	for _, data := range [][]byte{objectData, []byte("invalid json"), nil} {
+		ev := &ProviderEvent{
+			Timestamp:  time.Now().UnixNano(),
+			Action:     "update",
+			Username:   "admin",
+			ObjectType: "user",
+			ObjectName: "user1",
+			ObjectData: data,
+		}
+		require.NoError(t, ev.Create(sess))
+
+		var eventRead ProviderEvent
+		require.NoError(t, sess.Where("id = ?", ev.ID).First(&eventRead).Error)
+		switch {
+		case data == nil:
+			assert.Nil(t, eventRead.ObjectData)
+			assert.Nil(t, eventRead.ObjectJSON)
+			assert.Nil(t, eventRead.ObjectDataRaw)
+		case isValidJSON(data):
+			// the database can normalize the JSON
+			assert.JSONEq(t, string(data), string(eventRead.ObjectData))
+			assert.Nil(t, eventRead.ObjectDataRaw)
+		default:
+			assert.Equal(t, data, eventRead.ObjectData)
+			assert.Equal(t, data, eventRead.ObjectDataRaw)
+			assert.Nil(t, eventRead.ObjectJSON)
+		}
+	}
+
+	Cleanup(time.Now().Add(1 * time.Hour))
+}
@@ -0,0 +1,159 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Supported JSON patch operations
+const (
+	PatchOpAdd     = "add"
+	PatchOpRemove  = "remove"
+	PatchOpReplace = "replace"
+)
+
+// PatchOperation defines an RFC 6902 JSON patch operation
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// DiffJSON returns the RFC 6902 JSON patch that transforms from into to.
+// Objects are compared key by key, arrays element by element if they have the
+// same length, otherwise they are replaced
+func DiffJSON(from, to []byte) ([]PatchOperation, error) {
+	fromValue, err := decodeJSON(from)
+	if err != nil {
+		return nil, err
+	}
+	toValue, err := decodeJSON(to)
+	if err != nil {
+		return nil, err
+	}
+	ops := []PatchOperation{}
+	ops, err = diffJSONValues(ops, "", fromValue, toValue)
+	return ops, err
+}
+
+func diffJSONValues(ops []PatchOperation, path string, from, to any) ([]PatchOperation, error) {
+	var err error
+	switch fromValue := from.(type) {
+	case map[string]any:
+		if toValue, ok := to.(map[string]any); ok {
+			keys := slices.Sorted(maps.Keys(fromValue))
+			for _, k := range keys {
+				if _, ok := toValue[k]; !ok {
+					ops = append(ops, PatchOperation{Op: PatchOpRemove, Path: path + "/" + escapeJSONPointer(k)})
+				}
+			}
+			for _, k := range slices.Sorted(maps.Keys(toValue)) {
+				childPath := path + "/" + escapeJSONPointer(k)
+				if v, ok := fromValue[k]; ok {
+					ops, err = diffJSONValues(ops, childPath, v, toValue[k])
+				} else {
+					ops, err = appendPatchOperation(ops, PatchOpAdd, childPath, toValue[k])
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+			return ops, nil
+		}
+	case []any:
+		if toValue, ok := to.([]any); ok && len(fromValue) == len(toValue) {
+			for idx := range fromValue {
+				ops, err = diffJSONValues(ops, path+"/"+strconv.Itoa(idx), fromValue[idx], toValue[idx])
+				if err != nil {
+					return nil, err
+				}
+			}
+			return ops, nil
+		}
+	}
+	if reflect.DeepEqual(from, to) {
+		return ops, nil
+	}
+	return appendPatchOperation(ops, PatchOpReplace, path, to)
+}
+
+func appendPatchOperation(ops []PatchOperation, op, path string, value any) ([]PatchOperation, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return append(ops, PatchOperation{Op: op, Path: path, Value: data}), nil
+}
+
+// GetJSONPointer returns the value referenced by an RFC 6901 JSON pointer, the
+// boolean is false if the value does not exist
+func GetJSONPointer(data []byte, pointer string) (json.RawMessage, bool) {
+	value, err := decodeJSON(data)
+	if err != nil {
+		return nil, false
+	}
+	if pointer != "" {
+		if !strings.HasPrefix(pointer, "/") {
+			return nil, false
+		}
+		for _, token := range strings.Split(pointer[1:], "/") {
+			token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+			switch v := value.(type) {
+			case map[string]any:
+				child, ok := v[token]
+				if !ok {
+					return nil, false
+				}
+				value = child
+			case []any:
+				idx, err := strconv.Atoi(token)
+				if err != nil || idx < 0 || idx >= len(v) {
+					return nil, false
+				}
+				value = v[idx]
+			default:
+				return nil, false
+			}
+		}
+	}
+	result, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	return result, true
+}
+
+func escapeJSONPointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// decodeJSON decodes data keeping the numbers as json.Number, this way they
+// are compared and encoded again without losing precision
+func decodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
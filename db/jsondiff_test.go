@@ -0,0 +1,133 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/sftpgo/sdk/plugin/notifier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffJSON(t *testing.T) {
+	from := []byte(`{"username":"user1","home_dir":"/srv/user1","status":1,"uid":12345678901234567890,
+"permissions":{"/":["*"],"/sub":["list"]},"filters":{"allowed_ip":["10.0.0.0/8"]},"a/b":1,"c~d":1,
+"public_keys":["key1","key2"]}`)
+	to := []byte(`{"username":"user1","home_dir":"/srv/user2","status":1,"uid":12345678901234567891,
+"permissions":{"/":["*"],"/sub":["list","download"]},"filters":{},"a/b":2,"description":null,
+"public_keys":["key1","key3"]}`)
+	ops, err := DiffJSON(from, to)
+	require.NoError(t, err)
+	// for each object the removed keys come first
+	expected := []PatchOperation{
+		{Op: PatchOpRemove, Path: "/c~0d"},
+		{Op: PatchOpReplace, Path: "/a~1b", Value: json.RawMessage(`2`)},
+		{Op: PatchOpAdd, Path: "/description", Value: json.RawMessage(`null`)},
+		{Op: PatchOpRemove, Path: "/filters/allowed_ip"},
+		{Op: PatchOpReplace, Path: "/home_dir", Value: json.RawMessage(`"/srv/user2"`)},
+		{Op: PatchOpReplace, Path: "/permissions/~1sub", Value: json.RawMessage(`["list","download"]`)},
+		{Op: PatchOpReplace, Path: "/public_keys/1", Value: json.RawMessage(`"key3"`)},
+		{Op: PatchOpReplace, Path: "/uid", Value: json.RawMessage(`12345678901234567891`)},
+	}
+	assert.Equal(t, expected, ops)
+
+	data, err := json.Marshal(ops[2])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"op":"add","path":"/description","value":null}`, string(data))
+	data, err = json.Marshal(ops[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"op":"remove","path":"/c~0d"}`, string(data))
+
+	ops, err = DiffJSON(from, from)
+	require.NoError(t, err)
+	assert.Empty(t, ops)
+	assert.NotNil(t, ops)
+	ops, err = DiffJSON([]byte(`{"a":1}`), []byte(`[1]`))
+	require.NoError(t, err)
+	assert.Equal(t, []PatchOperation{{Op: PatchOpReplace, Path: "", Value: json.RawMessage(`[1]`)}}, ops)
+	_, err = DiffJSON([]byte(`{`), to)
+	assert.Error(t, err)
+	_, err = DiffJSON(from, []byte(`invalid`))
+	assert.Error(t, err)
+}
+
+func TestGetJSONPointer(t *testing.T) {
+	data := []byte(`{"permissions":{"/sub":["list","download"]},"a~b":1}`)
+	v, ok := GetJSONPointer(data, "/permissions/~1sub/1")
+	assert.True(t, ok)
+	assert.Equal(t, `"download"`, string(v))
+	v, ok = GetJSONPointer(data, "/a~0b")
+	assert.True(t, ok)
+	assert.Equal(t, `1`, string(v))
+	v, ok = GetJSONPointer(data, "")
+	assert.True(t, ok)
+	assert.JSONEq(t, string(data), string(v))
+	for _, pointer := range []string{"/missing", "/permissions/~1sub/2", "/permissions/~1sub/a", "/a~0b/c", "a"} {
+		_, ok = GetJSONPointer(data, pointer)
+		assert.False(t, ok, pointer)
+	}
+	_, ok = GetJSONPointer([]byte("invalid"), "")
+	assert.False(t, ok)
+}
+
+func TestProviderObjectDiff(t *testing.T) {
+	n := Notifier{
+		InstanceID: "sftpgo1",
+	}
+	ts := time.Now().Add(-time.Minute).UnixNano()
+	versions := []struct {
+		action string
+		data   string
+	}{
+		{"add", `{"username":"user1","home_dir":"/srv/user1"}`},
+		{"update", `invalid json`},
+		{"update", `{"username":"user1","home_dir":"/srv/user2"}`},
+		{"delete", `{"username":"user1","home_dir":"/srv/user2"}`},
+	}
+	for idx, v := range versions {
+		err := n.NotifyProviderEvent(&notifier.ProviderEvent{
+			Timestamp:  ts + int64(idx),
+			Action:     v.action,
+			Username:   "admin",
+			ObjectType: "user",
+			ObjectName: "user1",
+			ObjectData: []byte(v.data),
+		})
+		require.NoError(t, err)
+	}
+	// another object must not affect the diff
+	err := n.NotifyProviderEvent(&notifier.ProviderEvent{
+		Timestamp:  ts + 1,
+		Action:     "add",
+		Username:   "admin",
+		ObjectType: "admin",
+		ObjectName: "user1",
+		ObjectData: []byte(`{"username":"user1"}`),
+	})
+	require.NoError(t, err)
+
+	events, err := GetProviderObjectHistory("user", "user1")
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	assert.Nil(t, events[0].ObjectDiff)
+	assert.Nil(t, events[1].ObjectDiff)
+	assert.JSONEq(t, `[{"op":"replace","path":"/home_dir","value":"/srv/user2"}]`, string(events[2].ObjectDiff))
+	assert.Nil(t, events[3].ObjectDiff)
+
+	Cleanup(time.Now().Add(1 * time.Hour))
+}
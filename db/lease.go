@@ -0,0 +1,97 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Lease defines a named lease, only the holder is allowed to run the jobs
+// gated by the lease until it expires
+type Lease struct {
+	Name   string `json:"name" gorm:"primaryKey"`
+	Holder string `json:"holder"`
+	// ExpiresAt is a unix timestamp in nanoseconds
+	ExpiresAt int64 `json:"expires_at"`
+}
+
+// TableName defines the database table name
+func (l *Lease) TableName() string {
+	return tableName("leases")
+}
+
+// AcquireLease acquires or renews the lease with the specified name for the
+// specified holder. It returns false if the lease is held, and not yet
+// expired, by another holder. The expiration is set and checked using the
+// database clock, so the instances clocks don't matter
+func AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	sess, cancel := GetDefaultSession()
+	defer cancel()
+
+	now, err := getDatabaseTime(sess)
+	if err != nil {
+		return false, fmt.Errorf("unable to get the database time: %w", err)
+	}
+	expiresAt := now.Add(ttl).UnixNano()
+	res := sess.Model(&Lease{}).Where("name = ? AND (holder = ? OR expires_at < ?)", name, holder, now.UnixNano()).
+		Updates(map[string]any{"holder": holder, "expires_at": expiresAt})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if res.RowsAffected > 0 {
+		return true, nil
+	}
+	// the lease does not exist yet or it is held by another holder
+	res = sess.Clauses(clause.OnConflict{DoNothing: true}).Create(&Lease{
+		Name:      name,
+		Holder:    holder,
+		ExpiresAt: expiresAt,
+	})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// getDatabaseTime returns the current time according to the database clock
+func getDatabaseTime(sess *gorm.DB) (time.Time, error) {
+	var query string
+	switch sess.Dialector.Name() {
+	case driverNamePostgreSQL:
+		query = "SELECT CAST(EXTRACT(EPOCH FROM clock_timestamp()) * 1000000 AS BIGINT)"
+	case driverNameMySQL:
+		query = "SELECT CAST(UNIX_TIMESTAMP(CURRENT_TIMESTAMP(6)) * 1000000 AS SIGNED)"
+	default:
+		return time.Time{}, fmt.Errorf("unsupported database dialect %q", sess.Dialector.Name())
+	}
+	var micros int64
+	if err := sess.Raw(query).Scan(&micros).Error; err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMicro(micros), nil
+}
+
+// ReleaseLease releases the lease with the specified name if held by the
+// specified holder
+func ReleaseLease(name, holder string) error {
+	sess, cancel := GetDefaultSession()
+	defer cancel()
+
+	return sess.Where("name = ? AND holder = ?", name, holder).Delete(&Lease{}).Error
+}
@@ -0,0 +1,71 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeases(t *testing.T) {
+	held, err := AcquireLease("job", "holder1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held)
+	held, err = AcquireLease("job", "holder2", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, held)
+	// renew
+	held, err = AcquireLease("job", "holder1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held)
+	// different leases are independent
+	held, err = AcquireLease("job2", "holder2", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held)
+	// an expired lease can be acquired by another holder
+	held, err = AcquireLease("job", "holder1", -time.Second)
+	require.NoError(t, err)
+	assert.True(t, held)
+	held, err = AcquireLease("job", "holder2", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held)
+	held, err = AcquireLease("job", "holder1", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, held)
+	// only the holder can release the lease
+	require.NoError(t, ReleaseLease("job", "holder1"))
+	held, err = AcquireLease("job", "holder1", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, held)
+	require.NoError(t, ReleaseLease("job", "holder2"))
+	held, err = AcquireLease("job", "holder1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held)
+
+	require.NoError(t, ReleaseLease("job", "holder1"))
+	require.NoError(t, ReleaseLease("job2", "holder2"))
+}
+
+func TestDatabaseTime(t *testing.T) {
+	sess, cancel := GetDefaultSession()
+	defer cancel()
+
+	now, err := getDatabaseTime(sess)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), now, 5*time.Second)
+}
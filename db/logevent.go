@@ -15,41 +15,84 @@
 package db
 
 import (
+	"strconv"
 	"time"
 
 	"github.com/rs/xid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
 )
 
+// log event types as defined in the SFTPGo SDK
+var logEventNames = map[int]string{
+	1: "login_failed",
+	2: "login_no_user",
+	3: "no_login_tried",
+	4: "not_negotiated",
+	5: "login_ok",
+}
+
+// GetLogEventName returns the name for the specified log event type
+func GetLogEventName(event int) string {
+	if name, ok := logEventNames[event]; ok {
+		return name
+	}
+	return "event_" + strconv.Itoa(event)
+}
+
 // LogEvent defines a log event
 type LogEvent struct {
-	ID         string `json:"id" gorm:"primaryKey"`
-	Timestamp  int64  `json:"timestamp"`
-	Event      int    `json:"event"`
-	Protocol   string `json:"protocol,omitempty"`
-	Username   string `json:"username,omitempty"`
-	IP         string `json:"ip,omitempty"`
-	Message    string `json:"message,omitempty"`
-	Role       string `json:"role,omitempty"`
-	InstanceID string `json:"instance_id,omitempty"`
+	ID        string `json:"id" gorm:"primaryKey" parquet:"id"`
+	Timestamp int64  `json:"timestamp" parquet:"timestamp,timestamp(nanosecond)"`
+	Event     int    `json:"event" parquet:"event"`
+	Protocol  string `json:"protocol,omitempty" parquet:"protocol"`
+	Username  string `json:"username,omitempty" parquet:"username"`
+	IP        string `json:"ip,omitempty" parquet:"ip"`
+	// CountryCode, City and ASN are set if the GeoIP enrichment is enabled
+	CountryCode string `json:"country_code,omitempty" parquet:"country_code"`
+	City        string `json:"city,omitempty" parquet:"city"`
+	ASN         uint32 `json:"asn,omitempty" gorm:"column:asn" parquet:"asn"`
+	Message     string `json:"message,omitempty" parquet:"message"`
+	Role        string `json:"role,omitempty" parquet:"role"`
+	InstanceID  string `json:"instance_id,omitempty" parquet:"instance_id"`
+	// EventTime is the native representation of Timestamp, truncated to
+	// microseconds
+	EventTime time.Time `json:"-" parquet:"-"`
 }
 
 // TableName defines the database table name
 func (ev *LogEvent) TableName() string {
-	return "eventstore_log_events"
+	return tableName("log_events")
 }
 
 // BeforeCreate implements gorm hook
 func (ev *LogEvent) BeforeCreate(_ *gorm.DB) (err error) {
-	ev.ID = xid.New().String()
+	if ev.ID == "" {
+		ev.ID = xid.New().String()
+	}
+	if ev.EventTime.IsZero() {
+		ev.EventTime = getEventTime(ev.Timestamp)
+	}
 	return
 }
 
-// Create persists the object
+// Create persists the object, an event with the same ID is ignored
 func (ev *LogEvent) Create(tx *gorm.DB) error {
-	return tx.Create(ev).Error
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(ev).Error
+}
+
+// GetLogEventsSince returns the log events of the specified types newer than
+// or equal to the specified time, ordered by time
+func GetLogEventsSince(events []int, from time.Time) ([]LogEvent, error) {
+	sess, cancel := getSessionWithTimeout(5 * time.Minute)
+	defer cancel()
+
+	var result []LogEvent
+	err := WhereEventTime(sess.Where("event IN ?", events), from, time.Time{}).
+		Order("timestamp ASC, id ASC").Find(&result).Error
+	return result, err
 }
 
 func cleanupLogEvents(timestamp time.Time) error {
@@ -57,7 +100,7 @@ func cleanupLogEvents(timestamp time.Time) error {
 	defer cancel()
 
 	logger.AppLogger.Debug("removing log events", "timestamp", timestamp)
-	sess = sess.Where("timestamp < ?", timestamp.UnixNano()).Delete(&LogEvent{})
+	sess = WhereEventTime(sess, time.Time{}, timestamp).Delete(&LogEvent{})
 	err := sess.Error
 	if err == nil {
 		logger.AppLogger.Debug("log events deleted", "num", sess.RowsAffected)
@@ -17,20 +17,34 @@ package migration
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/go-gormigrate/gormigrate/v2"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 )
 
 const (
-	fsEventsTableName = "eventstore_fs_events"
+	fsEventsTable       = "fs_events"
+	providerEventsTable = "provider_events"
+	logEventsTable      = "log_events"
+	defaultTablePrefix  = "eventstore_"
+	// the applied migrations are tracked in this table, prefixed if the
+	// table prefix is not the default one
+	migrationsTable = "migrations"
 )
 
 var (
 	migrations     []*gormigrate.Migration
 	options        *gormigrate.Options
 	defaultTimeout = 2 * time.Minute
+	tablePrefix    = defaultTablePrefix
+	schemaName     string
+	indexTagRegexp = regexp.MustCompile(`index:(idx_\w+)`)
 )
 
 func init() {
@@ -49,15 +63,102 @@ func registerMigrations() {
 		getV5Migration(),
 		getV6Migration(),
 		getV7Migration(),
+		getV8Migration(),
+		getV9Migration(),
+		getV10Migration(),
+		getV11Migration(),
+		getV12Migration(),
+		getV13Migration(),
+		getV14Migration(),
+		getV15Migration(),
+		getV16Migration(),
+		getV17Migration(),
+		getV18Migration(),
 	)
 }
 
+// SetTablePrefix sets the prefix for the table names, the prefix must be
+// already validated. The migrations for the default prefix are tracked in the
+// "migrations" table, for backward compatibility, the ones for other prefixes
+// in the "<prefix>migrations" table, so each prefix is migrated independently
+func SetTablePrefix(prefix string) {
+	tablePrefix = prefix
+	if prefix == defaultTablePrefix {
+		options.TableName = migrationsTable
+	} else {
+		options.TableName = prefix + migrationsTable
+	}
+}
+
+// SetSchema sets the PostgreSQL schema, the schema must be already validated
+// and set as search path for the database connections. It is created, if
+// missing, before applying the migrations
+func SetSchema(schema string) {
+	schemaName = schema
+}
+
+func tableName(name string) string {
+	return tablePrefix + name
+}
+
+// indexName returns the name to use for the specified index. The index names
+// are unique within a schema for PostgreSQL, so for a prefix other than the
+// default one, "idx_<table>_<columns>" becomes "idx_<prefix><table>_<columns>".
+// The indexes for the default prefix keep their names
+func indexName(name string) string {
+	if tablePrefix == defaultTablePrefix {
+		return name
+	}
+	return "idx_" + tableName(strings.TrimPrefix(name, "idx_"))
+}
+
+// withIndexNames returns the session and the model to use to create the
+// indexes defined in the struct tags of the specified model. For a prefix
+// other than the default one the model is copied to a new struct type with
+// the index names returned by indexName, the copy has no TableName method so
+// the table is set in the returned session
+func withIndexNames(tx *gorm.DB, model schema.Tabler) (*gorm.DB, any) {
+	if tablePrefix == defaultTablePrefix {
+		return tx, model
+	}
+	modelType := reflect.TypeOf(model).Elem()
+	fields := make([]reflect.StructField, 0, modelType.NumField())
+	for i := range modelType.NumField() {
+		field := modelType.Field(i)
+		field.Tag = reflect.StructTag(indexTagRegexp.ReplaceAllStringFunc(string(field.Tag), func(tag string) string {
+			return "index:" + indexName(strings.TrimPrefix(tag, "index:"))
+		}))
+		fields = append(fields, field)
+	}
+	return tx.Table(model.TableName()), reflect.New(reflect.StructOf(fields)).Interface()
+}
+
+func autoMigrate(tx *gorm.DB, models ...schema.Tabler) error {
+	for _, model := range models {
+		sess, value := withIndexNames(tx, model)
+		if err := sess.AutoMigrate(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createIndex(tx *gorm.DB, model schema.Tabler, name string) error {
+	sess, value := withIndexNames(tx, model)
+	return sess.Migrator().CreateIndex(value, indexName(name))
+}
+
 // MigrateDatabase migrates the database to the latest version
 func MigrateDatabase(db *gorm.DB) error {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
 	defer cancel()
 
 	db = db.WithContext(ctx)
+	if schemaName != "" && db.Dialector.Name() == "postgres" {
+		if err := db.Exec("CREATE SCHEMA IF NOT EXISTS ?", clause.Table{Name: schemaName}).Error; err != nil {
+			return fmt.Errorf("unable to create schema %q: %w", schemaName, err)
+		}
+	}
 	m := gormigrate.New(db, options, migrations)
 	return m.Migrate()
 }
@@ -17,6 +17,7 @@ package migration
 import (
 	"github.com/go-gormigrate/gormigrate/v2"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 const (
@@ -36,7 +37,7 @@ type providerEventV1 struct {
 }
 
 func (ev *providerEventV1) TableName() string {
-	return "eventstore_provider_events"
+	return tableName("provider_events")
 }
 
 type fsEventV1 struct {
@@ -57,15 +58,15 @@ type fsEventV1 struct {
 }
 
 func (ev *fsEventV1) TableName() string {
-	return fsEventsTableName
+	return tableName(fsEventsTable)
 }
 
 func v1Up(tx *gorm.DB) error {
-	modelsToMigrate := []interface{}{
+	modelsToMigrate := []schema.Tabler{
 		&providerEventV1{},
 		&fsEventV1{},
 	}
-	return tx.AutoMigrate(modelsToMigrate...)
+	return autoMigrate(tx, modelsToMigrate...)
 }
 
 func v1Down(tx *gorm.DB) error {
@@ -0,0 +1,153 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package migration
+
+import (
+	"fmt"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	mignationV10ID = "10"
+)
+
+type providerEventV10 struct {
+	ID            string `gorm:"primaryKey;size:36"`
+	ObjectDataRaw []byte
+}
+
+func (ev *providerEventV10) TableName() string {
+	return tableName(providerEventsTable)
+}
+
+// the invalid JSON payloads are moved to the object_data_raw column, the
+// others are converted to a native JSON column
+var (
+	v10UpPostgreSQL = []string{
+		`CREATE FUNCTION %[2]s(data BYTEA) RETURNS JSONB AS $$
+BEGIN
+	RETURN convert_from(data, 'UTF8')::JSONB;
+EXCEPTION WHEN OTHERS THEN
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE`,
+		`UPDATE %[1]s SET object_data_raw = object_data WHERE object_data IS NOT NULL
+AND %[2]s(object_data) IS NULL`,
+		`ALTER TABLE %[1]s ALTER COLUMN object_data TYPE JSONB
+USING %[2]s(object_data)`,
+		`DROP FUNCTION %[2]s(BYTEA)`,
+		`CREATE INDEX %[3]s ON %[1]s
+USING GIN (object_data jsonb_path_ops)`,
+		`CREATE INDEX %[4]s ON %[1]s
+((object_data->>'username'))`,
+	}
+	v10DownPostgreSQL = []string{
+		`DROP INDEX %[4]s`,
+		`DROP INDEX %[3]s`,
+		`ALTER TABLE %[1]s ALTER COLUMN object_data TYPE BYTEA
+USING convert_to(object_data::TEXT, 'UTF8')`,
+		`UPDATE %[1]s SET object_data = object_data_raw WHERE object_data_raw IS NOT NULL`,
+	}
+	// MySQL refuses to convert a binary column to JSON, a new column is added
+	// and then renamed. The generated columns are virtual, so only the
+	// indexes use storage
+	v10UpMySQL = []string{
+		`ALTER TABLE %[1]s ADD COLUMN object_data_json JSON`,
+		`UPDATE %[1]s SET object_data_raw = object_data WHERE object_data IS NOT NULL
+AND NOT JSON_VALID(CONVERT(object_data USING utf8mb4))`,
+		`UPDATE %[1]s SET object_data_json = CONVERT(object_data USING utf8mb4)
+WHERE object_data IS NOT NULL AND object_data_raw IS NULL`,
+		`ALTER TABLE %[1]s DROP COLUMN object_data`,
+		`ALTER TABLE %[1]s CHANGE object_data_json object_data JSON`,
+		`ALTER TABLE %[1]s
+ADD COLUMN object_username VARCHAR(255) AS (JSON_UNQUOTE(JSON_EXTRACT(object_data, '$.username'))) VIRTUAL,
+ADD COLUMN object_status INT AS (JSON_EXTRACT(object_data, '$.status')) VIRTUAL,
+ADD INDEX %[4]s (object_username),
+ADD INDEX %[5]s (object_status)`,
+	}
+	v10DownMySQL = []string{
+		`ALTER TABLE %[1]s DROP COLUMN object_username, DROP COLUMN object_status`,
+		`ALTER TABLE %[1]s ADD COLUMN object_data_blob LONGBLOB`,
+		`UPDATE %[1]s SET object_data_blob = COALESCE(object_data_raw, object_data)`,
+		`ALTER TABLE %[1]s DROP COLUMN object_data`,
+		`ALTER TABLE %[1]s CHANGE object_data_blob object_data LONGBLOB`,
+	}
+)
+
+func v10Up(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&providerEventV10{}); err != nil {
+		return err
+	}
+	switch tx.Dialector.Name() {
+	case "postgres":
+		return execStatements(tx, v10UpPostgreSQL, v10Names())
+	case "mysql":
+		return execStatements(tx, v10UpMySQL, v10Names())
+	default:
+		// other dialects keep storing the JSON payloads as binary data
+		return nil
+	}
+}
+
+func v10Down(tx *gorm.DB) error {
+	var err error
+	switch tx.Dialector.Name() {
+	case "postgres":
+		err = execStatements(tx, v10DownPostgreSQL, v10Names())
+	case "mysql":
+		err = execStatements(tx, v10DownMySQL, v10Names())
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Migrator().DropColumn(&providerEventV10{}, "ObjectDataRaw")
+}
+
+// v10Names returns the names to replace in the v10 statements: "%[1]s" is the
+// table, "%[2]s" the conversion function and the others are the indexes
+func v10Names() []any {
+	return []any{
+		tableName(providerEventsTable),
+		tableName("try_jsonb"),
+		indexName("idx_provider_events_object_data"),
+		indexName("idx_provider_events_object_username"),
+		indexName("idx_provider_events_object_status"),
+	}
+}
+
+// execStatements executes the specified statements replacing the
+// placeholders with the specified names
+func execStatements(tx *gorm.DB, statements []string, names []any) error {
+	for _, sql := range statements {
+		if err := tx.Exec(fmt.Sprintf(sql, names...)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getV10Migration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: mignationV10ID,
+		Migrate: func(tx *gorm.DB) error {
+			return v10Up(tx)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return v10Down(tx)
+		},
+	}
+}
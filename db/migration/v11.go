@@ -0,0 +1,73 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package migration
+
+import (
+	"fmt"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	mignationV11ID = "11"
+)
+
+type providerEventV11 struct {
+	ID         string `gorm:"primaryKey;size:36"`
+	Timestamp  int64  `gorm:"size:64;not null;index:idx_provider_events_object_history,priority:3"`
+	ObjectType string `gorm:"size:50;index:idx_provider_events_object_history,priority:1"`
+	ObjectName string `gorm:"size:255;index:idx_provider_events_object_history,priority:2"`
+	ObjectDiff []byte
+}
+
+func (ev *providerEventV11) TableName() string {
+	return tableName(providerEventsTable)
+}
+
+func v11Up(tx *gorm.DB) error {
+	var err error
+	switch tx.Dialector.Name() {
+	case "postgres":
+		err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN object_diff JSONB", tableName(providerEventsTable))).Error
+	case "mysql":
+		err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN object_diff JSON", tableName(providerEventsTable))).Error
+	default:
+		err = tx.Migrator().AddColumn(&providerEventV11{}, "ObjectDiff")
+	}
+	if err != nil {
+		return err
+	}
+	return createIndex(tx, &providerEventV11{}, "idx_provider_events_object_history")
+}
+
+func v11Down(tx *gorm.DB) error {
+	if err := tx.Migrator().DropIndex(&providerEventV11{}, indexName("idx_provider_events_object_history")); err != nil {
+		return err
+	}
+	return tx.Migrator().DropColumn(&providerEventV11{}, "ObjectDiff")
+}
+
+func getV11Migration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: mignationV11ID,
+		Migrate: func(tx *gorm.DB) error {
+			return v11Up(tx)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return v11Down(tx)
+		},
+	}
+}
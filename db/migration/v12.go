@@ -0,0 +1,53 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package migration
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	mignationV12ID = "12"
+)
+
+type fsEventV12 struct {
+	ID        string `gorm:"primaryKey;size:36"`
+	SessionID string `gorm:"size:512;index:idx_fs_events_session_id"`
+}
+
+func (ev *fsEventV12) TableName() string {
+	return tableName(fsEventsTable)
+}
+
+func v12Up(tx *gorm.DB) error {
+	return createIndex(tx, &fsEventV12{}, "idx_fs_events_session_id")
+}
+
+func v12Down(tx *gorm.DB) error {
+	return tx.Migrator().DropIndex(&fsEventV12{}, indexName("idx_fs_events_session_id"))
+}
+
+func getV12Migration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: mignationV12ID,
+		Migrate: func(tx *gorm.DB) error {
+			return v12Up(tx)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return v12Down(tx)
+		},
+	}
+}
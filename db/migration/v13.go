@@ -0,0 +1,87 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package migration
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	mignationV13ID = "13"
+)
+
+type fsRollupHourlyV13 struct {
+	Bucket     time.Time `gorm:"primaryKey;autoIncrement:false"`
+	InstanceID string    `gorm:"primaryKey;size:60"`
+	Username   string    `gorm:"primaryKey;size:255;index:idx_fs_rollups_hourly_username"`
+	Protocol   string    `gorm:"primaryKey;size:30"`
+	Action     string    `gorm:"primaryKey;size:60"`
+	Events     int64     `gorm:"not null"`
+	Bytes      int64     `gorm:"not null"`
+	Failures   int64     `gorm:"not null"`
+	Elapsed    int64     `gorm:"not null"`
+}
+
+func (r *fsRollupHourlyV13) TableName() string {
+	return tableName("fs_rollups_hourly")
+}
+
+type fsRollupDailyV13 struct {
+	Bucket     time.Time `gorm:"primaryKey;autoIncrement:false"`
+	InstanceID string    `gorm:"primaryKey;size:60"`
+	Username   string    `gorm:"primaryKey;size:255;index:idx_fs_rollups_daily_username"`
+	Protocol   string    `gorm:"primaryKey;size:30"`
+	Action     string    `gorm:"primaryKey;size:60"`
+	Events     int64     `gorm:"not null"`
+	Bytes      int64     `gorm:"not null"`
+	Failures   int64     `gorm:"not null"`
+	Elapsed    int64     `gorm:"not null"`
+}
+
+func (r *fsRollupDailyV13) TableName() string {
+	return tableName("fs_rollups_daily")
+}
+
+func v13Up(tx *gorm.DB) error {
+	modelsToMigrate := []schema.Tabler{
+		&fsRollupHourlyV13{},
+		&fsRollupDailyV13{},
+	}
+	return autoMigrate(tx, modelsToMigrate...)
+}
+
+func v13Down(tx *gorm.DB) error {
+	modelsToMigrate := []any{
+		&fsRollupHourlyV13{},
+		&fsRollupDailyV13{},
+	}
+	return tx.Migrator().DropTable(modelsToMigrate...)
+}
+
+func getV13Migration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: mignationV13ID,
+		Migrate: func(tx *gorm.DB) error {
+			return v13Up(tx)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return v13Down(tx)
+		},
+	}
+}
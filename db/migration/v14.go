@@ -0,0 +1,65 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package migration
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	mignationV14ID = "14"
+)
+
+type alertV14 struct {
+	ID         string `gorm:"primaryKey;size:36"`
+	Timestamp  int64  `gorm:"size:64;not null;index:idx_alerts_timestamp"`
+	Rule       string `gorm:"size:100;not null;index:idx_alerts_rule"`
+	Subject    string `gorm:"size:255;not null;index:idx_alerts_subject"`
+	Events     int    `gorm:"size:32"`
+	Message    string
+	InstanceID string `gorm:"size:60;index:idx_alerts_instance_id"`
+}
+
+func (a *alertV14) TableName() string {
+	return tableName("alerts")
+}
+
+func v14Up(tx *gorm.DB) error {
+	modelsToMigrate := []schema.Tabler{
+		&alertV14{},
+	}
+	return autoMigrate(tx, modelsToMigrate...)
+}
+
+func v14Down(tx *gorm.DB) error {
+	modelsToMigrate := []any{
+		&alertV14{},
+	}
+	return tx.Migrator().DropTable(modelsToMigrate...)
+}
+
+func getV14Migration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: mignationV14ID,
+		Migrate: func(tx *gorm.DB) error {
+			return v14Up(tx)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return v14Down(tx)
+		},
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package migration
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	mignationV15ID = "15"
+)
+
+type alertV15 struct {
+	ID       string `gorm:"primaryKey;size:36"`
+	Severity string `gorm:"size:20;not null;default:'';index:idx_alerts_severity"`
+	EventIDs string
+}
+
+func (a *alertV15) TableName() string {
+	return tableName("alerts")
+}
+
+func v15Up(tx *gorm.DB) error {
+	if err := tx.Migrator().AddColumn(&alertV15{}, "Severity"); err != nil {
+		return err
+	}
+	if err := tx.Migrator().AddColumn(&alertV15{}, "EventIDs"); err != nil {
+		return err
+	}
+	return createIndex(tx, &alertV15{}, "idx_alerts_severity")
+}
+
+func v15Down(tx *gorm.DB) error {
+	if err := tx.Migrator().DropIndex(&alertV15{}, indexName("idx_alerts_severity")); err != nil {
+		return err
+	}
+	if err := tx.Migrator().DropColumn(&alertV15{}, "EventIDs"); err != nil {
+		return err
+	}
+	return tx.Migrator().DropColumn(&alertV15{}, "Severity")
+}
+
+func getV15Migration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: mignationV15ID,
+		Migrate: func(tx *gorm.DB) error {
+			return v15Up(tx)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return v15Down(tx)
+		},
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package migration
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	mignationV16ID = "16"
+)
+
+type fsEventV16 struct {
+	ID          string `gorm:"primaryKey;size:36"`
+	CountryCode string `gorm:"size:2;index:idx_fs_events_country_code"`
+	City        string `gorm:"size:255"`
+	ASN         uint32 `gorm:"column:asn;type:bigint;index:idx_fs_events_asn"`
+}
+
+func (ev *fsEventV16) TableName() string {
+	return tableName(fsEventsTable)
+}
+
+type providerEventV16 struct {
+	ID          string `gorm:"primaryKey;size:36"`
+	CountryCode string `gorm:"size:2;index:idx_provider_events_country_code"`
+	City        string `gorm:"size:255"`
+	ASN         uint32 `gorm:"column:asn;type:bigint;index:idx_provider_events_asn"`
+}
+
+func (ev *providerEventV16) TableName() string {
+	return tableName(providerEventsTable)
+}
+
+type logEventV16 struct {
+	ID          string `gorm:"primaryKey;size:36"`
+	CountryCode string `gorm:"size:2;index:idx_log_events_country_code"`
+	City        string `gorm:"size:255"`
+	ASN         uint32 `gorm:"column:asn;type:bigint;index:idx_log_events_asn"`
+}
+
+func (ev *logEventV16) TableName() string {
+	return tableName(logEventsTable)
+}
+
+var v16Models = []struct {
+	model   schema.Tabler
+	indexes []string
+}{
+	{&fsEventV16{}, []string{"idx_fs_events_country_code", "idx_fs_events_asn"}},
+	{&providerEventV16{}, []string{"idx_provider_events_country_code", "idx_provider_events_asn"}},
+	{&logEventV16{}, []string{"idx_log_events_country_code", "idx_log_events_asn"}},
+}
+
+func v16Up(tx *gorm.DB) error {
+	for _, m := range v16Models {
+		for _, field := range []string{"CountryCode", "City", "ASN"} {
+			if err := tx.Migrator().AddColumn(m.model, field); err != nil {
+				return err
+			}
+		}
+		for _, index := range m.indexes {
+			if err := createIndex(tx, m.model, index); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func v16Down(tx *gorm.DB) error {
+	for _, m := range v16Models {
+		for _, index := range m.indexes {
+			if err := tx.Migrator().DropIndex(m.model, indexName(index)); err != nil {
+				return err
+			}
+		}
+		for _, field := range []string{"ASN", "City", "CountryCode"} {
+			if err := tx.Migrator().DropColumn(m.model, field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func getV16Migration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: mignationV16ID,
+		Migrate: func(tx *gorm.DB) error {
+			return v16Up(tx)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return v16Down(tx)
+		},
+	}
+}
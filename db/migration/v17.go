@@ -0,0 +1,64 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package migration
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	mignationV17ID = "17"
+)
+
+type instanceV17 struct {
+	InstanceID string `gorm:"primaryKey;size:60"`
+	Version    string `gorm:"size:100;not null"`
+	Hostname   string `gorm:"size:255;not null"`
+	Driver     string `gorm:"size:30;not null"`
+	StartedAt  int64  `gorm:"size:64;not null"`
+	LastSeen   int64  `gorm:"size:64;not null;index:idx_instances_last_seen"`
+}
+
+func (i *instanceV17) TableName() string {
+	return tableName("instances")
+}
+
+func v17Up(tx *gorm.DB) error {
+	modelsToMigrate := []schema.Tabler{
+		&instanceV17{},
+	}
+	return autoMigrate(tx, modelsToMigrate...)
+}
+
+func v17Down(tx *gorm.DB) error {
+	modelsToMigrate := []any{
+		&instanceV17{},
+	}
+	return tx.Migrator().DropTable(modelsToMigrate...)
+}
+
+func getV17Migration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: mignationV17ID,
+		Migrate: func(tx *gorm.DB) error {
+			return v17Up(tx)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return v17Down(tx)
+		},
+	}
+}
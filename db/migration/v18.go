@@ -0,0 +1,61 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package migration
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	mignationV18ID = "18"
+)
+
+type leaseV18 struct {
+	Name      string `gorm:"primaryKey;size:100"`
+	Holder    string `gorm:"size:255;not null"`
+	ExpiresAt int64  `gorm:"size:64;not null"`
+}
+
+func (l *leaseV18) TableName() string {
+	return tableName("leases")
+}
+
+func v18Up(tx *gorm.DB) error {
+	modelsToMigrate := []schema.Tabler{
+		&leaseV18{},
+	}
+	return autoMigrate(tx, modelsToMigrate...)
+}
+
+func v18Down(tx *gorm.DB) error {
+	modelsToMigrate := []any{
+		&leaseV18{},
+	}
+	return tx.Migrator().DropTable(modelsToMigrate...)
+}
+
+func getV18Migration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: mignationV18ID,
+		Migrate: func(tx *gorm.DB) error {
+			return v18Up(tx)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return v18Down(tx)
+		},
+	}
+}
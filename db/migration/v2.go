@@ -17,6 +17,7 @@ package migration
 import (
 	"github.com/go-gormigrate/gormigrate/v2"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 const (
@@ -42,14 +43,14 @@ type fsEventV2 struct {
 }
 
 func (ev *fsEventV2) TableName() string {
-	return fsEventsTableName
+	return tableName(fsEventsTable)
 }
 
 func v2Up(tx *gorm.DB) error {
-	modelsToMigrate := []interface{}{
+	modelsToMigrate := []schema.Tabler{
 		&fsEventV2{},
 	}
-	return tx.AutoMigrate(modelsToMigrate...)
+	return autoMigrate(tx, modelsToMigrate...)
 }
 
 func v2Down(tx *gorm.DB) error {
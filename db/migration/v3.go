@@ -17,6 +17,7 @@ package migration
 import (
 	"github.com/go-gormigrate/gormigrate/v2"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 const (
@@ -46,11 +47,11 @@ type fsEventV3 struct {
 }
 
 func (ev *fsEventV3) TableName() string {
-	return fsEventsTableName
+	return tableName(fsEventsTable)
 }
 
 func v3Up(tx *gorm.DB) error {
-	modelsToMigrate := []interface{}{
+	modelsToMigrate := []schema.Tabler{
 		&fsEventV3{},
 	}
 	for _, columnName := range []string{"FsPath", "FsTargetPath", "VirtualPath", "VirtualTargetPath"} {
@@ -58,7 +59,7 @@ func v3Up(tx *gorm.DB) error {
 			return err
 		}
 	}
-	return tx.AutoMigrate(modelsToMigrate...)
+	return autoMigrate(tx, modelsToMigrate...)
 }
 
 func v3Down(tx *gorm.DB) error {
@@ -17,6 +17,7 @@ package migration
 import (
 	"github.com/go-gormigrate/gormigrate/v2"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 const (
@@ -47,7 +48,7 @@ type fsEventV4 struct {
 }
 
 func (ev *fsEventV4) TableName() string {
-	return fsEventsTableName
+	return tableName(fsEventsTable)
 }
 
 type providerEventV4 struct {
@@ -64,15 +65,15 @@ type providerEventV4 struct {
 }
 
 func (ev *providerEventV4) TableName() string {
-	return "eventstore_provider_events"
+	return tableName("provider_events")
 }
 
 func v4Up(tx *gorm.DB) error {
-	modelsToMigrate := []interface{}{
+	modelsToMigrate := []schema.Tabler{
 		&fsEventV4{},
 		&providerEventV4{},
 	}
-	return tx.AutoMigrate(modelsToMigrate...)
+	return autoMigrate(tx, modelsToMigrate...)
 }
 
 func v4Down(tx *gorm.DB) error {
@@ -17,6 +17,7 @@ package migration
 import (
 	"github.com/go-gormigrate/gormigrate/v2"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 const (
@@ -48,14 +49,14 @@ type fsEventV5 struct {
 }
 
 func (ev *fsEventV5) TableName() string {
-	return fsEventsTableName
+	return tableName(fsEventsTable)
 }
 
 func v5Up(tx *gorm.DB) error {
-	modelsToMigrate := []interface{}{
+	modelsToMigrate := []schema.Tabler{
 		&fsEventV5{},
 	}
-	return tx.AutoMigrate(modelsToMigrate...)
+	return autoMigrate(tx, modelsToMigrate...)
 }
 
 func v5Down(tx *gorm.DB) error {
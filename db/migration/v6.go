@@ -17,6 +17,7 @@ package migration
 import (
 	"github.com/go-gormigrate/gormigrate/v2"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 const (
@@ -36,14 +37,14 @@ type logEventV1 struct {
 }
 
 func (ev *logEventV1) TableName() string {
-	return "eventstore_log_events"
+	return tableName("log_events")
 }
 
 func v6Up(tx *gorm.DB) error {
-	modelsToMigrate := []interface{}{
+	modelsToMigrate := []schema.Tabler{
 		&logEventV1{},
 	}
-	return tx.AutoMigrate(modelsToMigrate...)
+	return autoMigrate(tx, modelsToMigrate...)
 }
 
 func v6Down(tx *gorm.DB) error {
@@ -17,6 +17,7 @@ package migration
 import (
 	"github.com/go-gormigrate/gormigrate/v2"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 const (
@@ -48,14 +49,14 @@ type fsEventV7 struct {
 }
 
 func (ev *fsEventV7) TableName() string {
-	return fsEventsTableName
+	return tableName(fsEventsTable)
 }
 
 func v7Up(tx *gorm.DB) error {
-	modelsToMigrate := []any{
+	modelsToMigrate := []schema.Tabler{
 		&fsEventV7{},
 	}
-	return tx.AutoMigrate(modelsToMigrate...)
+	return autoMigrate(tx, modelsToMigrate...)
 }
 
 func v7Down(_ *gorm.DB) error {
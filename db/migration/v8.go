@@ -0,0 +1,66 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package migration
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	mignationV8ID = "8"
+)
+
+type webhookDeadLetterV8 struct {
+	ID        string `gorm:"primaryKey;size:36"`
+	Timestamp int64  `gorm:"size:64;not null;index:idx_webhook_dead_letters_timestamp"`
+	URL       string `gorm:"size:512;not null"`
+	EventType string `gorm:"size:20;not null"`
+	EventID   string `gorm:"size:36;not null;index:idx_webhook_dead_letters_event_id"`
+	Payload   []byte
+	Attempts  int `gorm:"size:32"`
+	LastError string
+}
+
+func (d *webhookDeadLetterV8) TableName() string {
+	return tableName("webhook_dead_letters")
+}
+
+func v8Up(tx *gorm.DB) error {
+	modelsToMigrate := []schema.Tabler{
+		&webhookDeadLetterV8{},
+	}
+	return autoMigrate(tx, modelsToMigrate...)
+}
+
+func v8Down(tx *gorm.DB) error {
+	modelsToMigrate := []any{
+		&webhookDeadLetterV8{},
+	}
+	return tx.Migrator().DropTable(modelsToMigrate...)
+}
+
+func getV8Migration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: mignationV8ID,
+		Migrate: func(tx *gorm.DB) error {
+			return v8Up(tx)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return v8Down(tx)
+		},
+	}
+}
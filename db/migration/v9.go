@@ -0,0 +1,137 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package migration
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	mignationV9ID = "9"
+	// number of rows updated in each backfill transaction
+	backfillChunkSize = 10000
+)
+
+type fsEventV9 struct {
+	ID        string     `gorm:"primaryKey;size:36"`
+	EventTime *time.Time `gorm:"precision:6;index:idx_fs_events_event_time"`
+}
+
+func (ev *fsEventV9) TableName() string {
+	return tableName(fsEventsTable)
+}
+
+type providerEventV9 struct {
+	ID        string     `gorm:"primaryKey;size:36"`
+	EventTime *time.Time `gorm:"precision:6;index:idx_provider_events_event_time"`
+}
+
+func (ev *providerEventV9) TableName() string {
+	return tableName(providerEventsTable)
+}
+
+type logEventV9 struct {
+	ID        string     `gorm:"primaryKey;size:36"`
+	EventTime *time.Time `gorm:"precision:6;index:idx_log_events_event_time"`
+}
+
+func (ev *logEventV9) TableName() string {
+	return tableName(logEventsTable)
+}
+
+func v9Up(tx *gorm.DB) error {
+	modelsToMigrate := []schema.Tabler{
+		&fsEventV9{},
+		&providerEventV9{},
+		&logEventV9{},
+	}
+	return autoMigrate(tx, modelsToMigrate...)
+}
+
+func v9Down(tx *gorm.DB) error {
+	modelsToMigrate := []any{
+		&fsEventV9{},
+		&providerEventV9{},
+		&logEventV9{},
+	}
+	for _, model := range modelsToMigrate {
+		if err := tx.Migrator().DropColumn(model, "EventTime"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getV9Migration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: mignationV9ID,
+		Migrate: func(tx *gorm.DB) error {
+			return v9Up(tx)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return v9Down(tx)
+		},
+	}
+}
+
+// BackfillEventTime sets the event_time column, added in migration 9, for the
+// events saved before. The rows are updated in chunks, each one in its own
+// transaction, so the tables are not locked for a long time and an
+// interrupted backfill can be resumed
+func BackfillEventTime(db *gorm.DB) error {
+	var eventTime string
+	switch db.Dialector.Name() {
+	case "postgres":
+		eventTime = "TIMESTAMPTZ 'epoch' + (timestamp / 1000) * INTERVAL '1 microsecond'"
+	case "mysql":
+		eventTime = "TIMESTAMPADD(MICROSECOND, timestamp DIV 1000, '1970-01-01 00:00:00')"
+	default:
+		return fmt.Errorf("unsupported database dialect %q", db.Dialector.Name())
+	}
+	for _, table := range []string{tableName(fsEventsTable), tableName(providerEventsTable), tableName(logEventsTable)} {
+		for {
+			updated, err := backfillChunk(db, table, eventTime)
+			if err != nil {
+				return fmt.Errorf("unable to backfill event time for table %q: %w", table, err)
+			}
+			if updated < backfillChunkSize {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func backfillChunk(db *gorm.DB, table, eventTime string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	var sql string
+	if db.Dialector.Name() == "mysql" {
+		sql = fmt.Sprintf("UPDATE %s SET event_time = %s WHERE event_time IS NULL LIMIT %d", table, eventTime,
+			backfillChunkSize)
+	} else {
+		sql = fmt.Sprintf("UPDATE %s SET event_time = %s WHERE id IN "+
+			"(SELECT id FROM %s WHERE event_time IS NULL LIMIT %d)", table, eventTime, table, backfillChunkSize)
+	}
+	result := db.WithContext(ctx).Exec(sql)
+	return result.RowsAffected, result.Error
+}
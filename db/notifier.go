@@ -22,6 +22,40 @@ import (
 
 type Notifier struct {
 	InstanceID string
+	// Sinks defines the destinations for the events, if empty the events
+	// are saved in the database
+	Sinks []SinkConfig
+	// GeoIP resolves the client IPs, if nil the events are not enriched
+	GeoIP GeoIPResolver
+}
+
+var defaultSinks = []SinkConfig{
+	{Sink: &DatabaseSink{}, Required: true},
+}
+
+// writeToSinks writes an event to the matching sinks. Required sinks are
+// written first and the first error is returned without writing to the other
+// sinks, so a retried event is not duplicated in the best effort sinks
+func (n *Notifier) writeToSinks(match func(*Route) bool, write func(Sink) error) error {
+	sinks := n.Sinks
+	if len(sinks) == 0 {
+		sinks = defaultSinks
+	}
+	for _, required := range []bool{true, false} {
+		for _, s := range sinks {
+			if s.Required != required || !match(s.Route) {
+				continue
+			}
+			if err := write(s.Sink); err != nil {
+				logger.AppLogger.Warn("unable to write event to sink", "sink", s.Sink.Name(), "required", s.Required,
+					"error", err)
+				if s.Required {
+					return err
+				}
+			}
+		}
+	}
+	return nil
 }
 
 func (n *Notifier) NotifyFsEvent(event *notifier.FsEvent) error {
@@ -47,16 +81,15 @@ func (n *Notifier) NotifyFsEvent(event *notifier.FsEvent) error {
 		Role:              event.Role,
 		InstanceID:        n.InstanceID,
 	}
-	sess, cancel := GetDefaultSession()
-	defer cancel()
-
-	err := ev.Create(sess)
-	if err != nil {
-		logger.AppLogger.Warn("unable to save fs event", "action", event.Action, "username",
-			event.Username, "virtual path", event.VirtualPath, "error", err)
-		return err
-	}
-	return nil
+	// the geolocation is not part of the ID, it could change for a resent
+	// event if the GeoIP database is updated
+	ev.ID = ev.computeID()
+	ev.setGeoLocation(n.lookupIP(ev.IP))
+	return n.writeToSinks(func(r *Route) bool {
+		return r.matchFsEvent(ev)
+	}, func(s Sink) error {
+		return s.WriteFsEvent(ev)
+	})
 }
 
 func (n *Notifier) NotifyProviderEvent(event *notifier.ProviderEvent) error {
@@ -71,15 +104,13 @@ func (n *Notifier) NotifyProviderEvent(event *notifier.ProviderEvent) error {
 		Role:       event.Role,
 		InstanceID: n.InstanceID,
 	}
-	sess, cancel := GetDefaultSession()
-	defer cancel()
-
-	err := ev.Create(sess)
-	if err != nil {
-		logger.AppLogger.Warn("unable to save provider event", "action", event.Action, "error", err)
-		return err
-	}
-	return nil
+	ev.ID = ev.computeID()
+	ev.setGeoLocation(n.lookupIP(ev.IP))
+	return n.writeToSinks(func(r *Route) bool {
+		return r.matchProviderEvent(ev)
+	}, func(s Sink) error {
+		return s.WriteProviderEvent(ev)
+	})
 }
 
 func (n *Notifier) NotifyLogEvent(event *notifier.LogEvent) error {
@@ -93,13 +124,11 @@ func (n *Notifier) NotifyLogEvent(event *notifier.LogEvent) error {
 		Role:       event.Role,
 		InstanceID: n.InstanceID,
 	}
-	sess, cancel := GetDefaultSession()
-	defer cancel()
-
-	err := ev.Create(sess)
-	if err != nil {
-		logger.AppLogger.Warn("unable to save log event", "event", event.Event, "error", err)
-		return err
-	}
-	return nil
+	ev.ID = ev.computeID()
+	ev.setGeoLocation(n.lookupIP(ev.IP))
+	return n.writeToSinks(func(r *Route) bool {
+		return r.matchLogEvent(ev)
+	}, func(s Sink) error {
+		return s.WriteLogEvent(ev)
+	})
 }
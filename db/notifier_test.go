@@ -17,6 +17,7 @@ package db
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"testing"
 	"time"
 
@@ -88,6 +89,8 @@ func TestNotifyEvents(t *testing.T) {
 	assert.Equal(t, fsEvent.Endpoint, event.Endpoint)
 	assert.Equal(t, fsEvent.OpenFlags, event.OpenFlags)
 	assert.Equal(t, fsEvent.Role, event.Role)
+	assert.True(t, time.Unix(0, fsEvent.Timestamp).Truncate(time.Microsecond).Equal(event.EventTime),
+		"unexpected event time %v", event.EventTime)
 
 	providerEvent := &notifier.ProviderEvent{
 		Timestamp:  time.Now().UnixNano(),
@@ -118,6 +121,7 @@ func TestNotifyEvents(t *testing.T) {
 	assert.Equal(t, providerEvent.ObjectName, providerEv.ObjectName)
 	assert.Equal(t, providerEvent.Role, providerEv.Role)
 	assert.Equal(t, providerEvent.ObjectData, providerEv.ObjectData)
+	assert.True(t, time.Unix(0, providerEvent.Timestamp).Truncate(time.Microsecond).Equal(providerEv.EventTime))
 
 	logEvent := &notifier.LogEvent{
 		Timestamp: time.Now().UnixNano(),
@@ -144,6 +148,7 @@ func TestNotifyEvents(t *testing.T) {
 	assert.Equal(t, logEvent.IP, logEv.IP)
 	assert.Equal(t, logEvent.Message, logEv.Message)
 	assert.Equal(t, logEvent.Role, logEv.Role)
+	assert.True(t, time.Unix(0, logEvent.Timestamp).Truncate(time.Microsecond).Equal(logEv.EventTime))
 
 	// test cleanup
 	Cleanup(time.Now().Add(-24 * time.Hour))
@@ -169,3 +174,231 @@ func TestNotifyEvents(t *testing.T) {
 	assert.NoError(t, result.Error)
 	assert.Equal(t, int64(0), result.RowsAffected)
 }
+
+type testSink struct {
+	err      error
+	fsEvents []*FsEvent
+}
+
+func (s *testSink) Name() string {
+	return "test"
+}
+
+func (s *testSink) WriteFsEvent(ev *FsEvent) error {
+	s.fsEvents = append(s.fsEvents, ev)
+	return s.err
+}
+
+func (s *testSink) WriteProviderEvent(_ *ProviderEvent) error {
+	return s.err
+}
+
+func (s *testSink) WriteLogEvent(_ *LogEvent) error {
+	return s.err
+}
+
+func TestNotifySinks(t *testing.T) {
+	required := &testSink{}
+	optional := &testSink{err: errors.New("sink error")}
+	n := Notifier{
+		InstanceID: "sftpgo1",
+		Sinks: []SinkConfig{
+			{Sink: required, Required: true},
+			{Sink: optional},
+		},
+	}
+	fsEvent := &notifier.FsEvent{
+		Timestamp: time.Now().UnixNano(),
+		Action:    "upload",
+		Username:  "username",
+		Protocol:  "SFTP",
+	}
+	err := n.NotifyFsEvent(fsEvent)
+	assert.NoError(t, err)
+	require.Len(t, required.fsEvents, 1)
+	assert.NotEmpty(t, required.fsEvents[0].ID)
+	assert.Equal(t, n.InstanceID, required.fsEvents[0].InstanceID)
+	assert.Len(t, optional.fsEvents, 1)
+
+	required.err = errors.New("required sink error")
+	err = n.NotifyFsEvent(fsEvent)
+	assert.ErrorIs(t, err, required.err)
+	// the event will be retried, it must not be written to the best effort sinks
+	assert.Len(t, optional.fsEvents, 1)
+	err = n.NotifyProviderEvent(&notifier.ProviderEvent{
+		Timestamp: time.Now().UnixNano(),
+		Action:    "add",
+		Username:  "admin",
+	})
+	assert.ErrorIs(t, err, required.err)
+	err = n.NotifyLogEvent(&notifier.LogEvent{
+		Timestamp: time.Now().UnixNano(),
+		Event:     1,
+	})
+	assert.ErrorIs(t, err, required.err)
+
+	Cleanup(time.Now().Add(1 * time.Hour))
+}
+
+func TestNotifyRoutes(t *testing.T) {
+	failedUploads := &testSink{}
+	providerOnly := &testSink{}
+	n := Notifier{
+		InstanceID: "sftpgo1",
+		Sinks: []SinkConfig{
+			{Sink: &DatabaseSink{}, Required: true},
+			{Sink: failedUploads, Route: &Route{EventTypes: []string{EventTypeFs}, Actions: []string{"upload"},
+				Statuses: []int{2, 3}}},
+			{Sink: providerOnly, Route: &Route{EventTypes: []string{EventTypeProvider}}},
+		},
+	}
+	fsEvent := &notifier.FsEvent{
+		Timestamp: time.Now().UnixNano(),
+		Action:    "upload",
+		Username:  "username",
+		Status:    1,
+	}
+	err := n.NotifyFsEvent(fsEvent)
+	assert.NoError(t, err)
+	assert.Len(t, failedUploads.fsEvents, 0)
+	fsEvent.Status = 2
+	err = n.NotifyFsEvent(fsEvent)
+	assert.NoError(t, err)
+	assert.Len(t, failedUploads.fsEvents, 1)
+	fsEvent.Action = "download"
+	err = n.NotifyFsEvent(fsEvent)
+	assert.NoError(t, err)
+	assert.Len(t, failedUploads.fsEvents, 1)
+	assert.Len(t, providerOnly.fsEvents, 0)
+
+	sess, cancel := GetDefaultSession()
+	defer cancel()
+
+	var count int64
+	err = sess.Model(&FsEvent{}).Count(&count).Error
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	// the same ID is used for all the sinks
+	var fsEventRead FsEvent
+	err = sess.Where("id = ?", failedUploads.fsEvents[0].ID).First(&fsEventRead).Error
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fsEventRead.Status)
+
+	Cleanup(time.Now().Add(1 * time.Hour))
+}
+
+func TestRouteMatch(t *testing.T) {
+	var r *Route
+	assert.True(t, r.matchFsEvent(&FsEvent{}))
+	assert.True(t, r.matchProviderEvent(&ProviderEvent{}))
+	assert.True(t, r.matchLogEvent(&LogEvent{}))
+
+	r = &Route{}
+	assert.True(t, r.matchFsEvent(&FsEvent{}))
+	assert.True(t, r.matchProviderEvent(&ProviderEvent{}))
+	assert.True(t, r.matchLogEvent(&LogEvent{}))
+
+	r = &Route{
+		Actions:     []string{"add", "upload"},
+		ObjectTypes: []string{"user"},
+		LogEvents:   []int{1, 2},
+	}
+	assert.True(t, r.matchFsEvent(&FsEvent{Action: "upload", Status: 3}))
+	assert.False(t, r.matchFsEvent(&FsEvent{Action: "download"}))
+	assert.True(t, r.matchProviderEvent(&ProviderEvent{Action: "add", ObjectType: "user"}))
+	assert.False(t, r.matchProviderEvent(&ProviderEvent{Action: "add", ObjectType: "admin"}))
+	assert.False(t, r.matchProviderEvent(&ProviderEvent{Action: "update", ObjectType: "user"}))
+	assert.True(t, r.matchLogEvent(&LogEvent{Event: 2}))
+	assert.False(t, r.matchLogEvent(&LogEvent{Event: 5}))
+
+	r = &Route{EventTypes: []string{EventTypeLog}}
+	assert.False(t, r.matchFsEvent(&FsEvent{}))
+	assert.False(t, r.matchProviderEvent(&ProviderEvent{}))
+	assert.True(t, r.matchLogEvent(&LogEvent{}))
+}
+
+func TestNotifyIdempotent(t *testing.T) {
+	n := Notifier{
+		InstanceID: "sftpgo1",
+	}
+	fsEvent := &notifier.FsEvent{
+		Timestamp:   time.Now().UnixNano(),
+		Action:      "upload",
+		Username:    "username",
+		VirtualPath: "/file.txt",
+		SessionID:   "session",
+		Status:      1,
+	}
+	providerEvent := &notifier.ProviderEvent{
+		Timestamp:  time.Now().UnixNano(),
+		Action:     "add",
+		Username:   "admin",
+		ObjectType: "user",
+		ObjectName: "username",
+		ObjectData: []byte("data"),
+	}
+	logEvent := &notifier.LogEvent{
+		Timestamp: time.Now().UnixNano(),
+		Event:     1,
+		Username:  "username",
+	}
+	// SFTPGo resends the same event if the first notification fails
+	for range 2 {
+		assert.NoError(t, n.NotifyFsEvent(fsEvent))
+		assert.NoError(t, n.NotifyProviderEvent(providerEvent))
+		assert.NoError(t, n.NotifyLogEvent(logEvent))
+	}
+
+	sess, cancel := GetDefaultSession()
+	defer cancel()
+
+	var count int64
+	require.NoError(t, sess.Model(&FsEvent{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+	require.NoError(t, sess.Model(&ProviderEvent{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+	require.NoError(t, sess.Model(&LogEvent{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	// a different event is saved
+	fsEvent.Timestamp++
+	assert.NoError(t, n.NotifyFsEvent(fsEvent))
+	require.NoError(t, sess.Model(&FsEvent{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+
+	Cleanup(time.Now().Add(1 * time.Hour))
+}
+
+func TestComputeID(t *testing.T) {
+	fsEvent := &FsEvent{
+		Timestamp:   time.Now().UnixNano(),
+		Action:      "rename",
+		Username:    "username",
+		VirtualPath: "/a",
+		SessionID:   "session",
+		InstanceID:  "sftpgo1",
+	}
+	id := fsEvent.computeID()
+	assert.Len(t, id, 32)
+	assert.Equal(t, id, fsEvent.computeID())
+	// the fields are length prefixed, moving a character between adjacent
+	// fields must change the ID
+	fsEvent.VirtualPath = "/"
+	fsEvent.VirtualTargetPath = "a"
+	assert.NotEqual(t, id, fsEvent.computeID())
+	fsEvent.VirtualPath = "/a"
+	fsEvent.VirtualTargetPath = ""
+	fsEvent.InstanceID = "sftpgo2"
+	assert.NotEqual(t, id, fsEvent.computeID())
+
+	providerEvent := &ProviderEvent{Timestamp: 1, Action: "add", ObjectData: []byte("data")}
+	id = providerEvent.computeID()
+	providerEvent.ObjectData = []byte("data1")
+	assert.NotEqual(t, id, providerEvent.computeID())
+
+	logEvent := &LogEvent{Timestamp: 1, Event: 1}
+	id = logEvent.computeID()
+	logEvent.Event = 2
+	assert.NotEqual(t, id, logEvent.computeID())
+	assert.NotEqual(t, logEvent.computeID(), (&LogEvent{Timestamp: 2, Event: 1}).computeID())
+}
@@ -0,0 +1,252 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+const (
+	// maximum number of rows inserted using a single COPY
+	pgxMaxBatchSize = 500
+	// number of concurrent inserts for each table
+	pgxWorkers = 2
+)
+
+var (
+	fsEventColumns = []string{"id", "timestamp", "action", "username", "fs_path", "fs_target_path", "virtual_path",
+		"virtual_target_path", "ssh_cmd", "file_size", "elapsed", "status", "protocol", "ip", "session_id",
+		"fs_provider", "bucket", "endpoint", "open_flags", "role", "instance_id", "event_time", "country_code", "city",
+		"asn"}
+	providerEventColumns = []string{"id", "timestamp", "action", "username", "ip", "object_type", "object_name",
+		"object_data", "role", "instance_id", "event_time", "object_data_raw", "object_diff", "country_code", "city", "asn"}
+	logEventColumns = []string{"id", "timestamp", "event", "protocol", "username", "ip", "message", "role",
+		"instance_id", "event_time", "country_code", "city", "asn"}
+
+	pgxWriterOnce     sync.Once
+	pgxWriterInstance *pgxWriter
+)
+
+// getPgxWriter returns the pgx writer if the database driver is PostgreSQL,
+// nil otherwise
+func getPgxWriter() *pgxWriter {
+	if driverName != driverNamePostgreSQL {
+		return nil
+	}
+	pgxWriterOnce.Do(func() {
+		pgxWriterInstance = newPgxWriter()
+	})
+	return pgxWriterInstance
+}
+
+// pgxWriter inserts the events in PostgreSQL using pgx directly, without the
+// gorm reflection overhead. The events received while an insert is in
+// progress are grouped and inserted using COPY, a single event is inserted
+// using a cached prepared statement
+type pgxWriter struct {
+	fsEvents       *pgxTable
+	providerEvents *pgxTable
+	logEvents      *pgxTable
+}
+
+func newPgxWriter() *pgxWriter {
+	return &pgxWriter{
+		fsEvents:       newPgxTable((&FsEvent{}).TableName(), fsEventColumns),
+		providerEvents: newPgxTable((&ProviderEvent{}).TableName(), providerEventColumns),
+		logEvents:      newPgxTable((&LogEvent{}).TableName(), logEventColumns),
+	}
+}
+
+func (w *pgxWriter) insertFsEvent(ev *FsEvent) error {
+	if err := ev.BeforeCreate(nil); err != nil {
+		return err
+	}
+	return w.fsEvents.insert([]any{ev.ID, ev.Timestamp, ev.Action, ev.Username, ev.FsPath, ev.FsTargetPath,
+		ev.VirtualPath, ev.VirtualTargetPath, ev.SSHCmd, ev.FileSize, ev.Elapsed, ev.Status, ev.Protocol, ev.IP,
+		ev.SessionID, ev.FsProvider, ev.Bucket, ev.Endpoint, ev.OpenFlags, ev.Role, ev.InstanceID, ev.EventTime,
+		ev.CountryCode, ev.City, ev.ASN})
+}
+
+func (w *pgxWriter) insertProviderEvent(ev *ProviderEvent) error {
+	if err := ev.BeforeCreate(nil); err != nil {
+		return err
+	}
+	return w.providerEvents.insert([]any{ev.ID, ev.Timestamp, ev.Action, ev.Username, ev.IP, ev.ObjectType,
+		ev.ObjectName, jsonColumnValue(ev.ObjectJSON), ev.Role, ev.InstanceID, ev.EventTime, ev.ObjectDataRaw,
+		jsonColumnValue(ev.ObjectDiff), ev.CountryCode, ev.City, ev.ASN})
+}
+
+func (w *pgxWriter) insertLogEvent(ev *LogEvent) error {
+	if err := ev.BeforeCreate(nil); err != nil {
+		return err
+	}
+	return w.logEvents.insert([]any{ev.ID, ev.Timestamp, ev.Event, ev.Protocol, ev.Username, ev.IP, ev.Message,
+		ev.Role, ev.InstanceID, ev.EventTime, ev.CountryCode, ev.City, ev.ASN})
+}
+
+// jsonColumnValue returns the value to use for a JSONB column, NULL if the
+// document is nil
+func jsonColumnValue(j jsonColumn) any {
+	if j == nil {
+		return nil
+	}
+	return string(j)
+}
+
+type pgxInsert struct {
+	row  []any
+	done chan error
+}
+
+type pgxTable struct {
+	name          string
+	columns       []string
+	statementName string
+	insertSQL     string
+	queue         chan *pgxInsert
+}
+
+func newPgxTable(name string, columns []string) *pgxTable {
+	placeholders := make([]string, 0, len(columns))
+	for idx := range columns {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", idx+1))
+	}
+	t := &pgxTable{
+		name:          name,
+		columns:       columns,
+		statementName: name + "_insert",
+		insertSQL: fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (id) DO NOTHING",
+			pgx.Identifier{name}.Sanitize(), strings.Join(columns, ","), strings.Join(placeholders, ",")),
+		queue: make(chan *pgxInsert, pgxMaxBatchSize*pgxWorkers),
+	}
+	for range pgxWorkers {
+		go t.worker()
+	}
+	return t
+}
+
+// insert queues a row and waits for the insert result
+func (t *pgxTable) insert(row []any) error {
+	req := &pgxInsert{
+		row:  row,
+		done: make(chan error, 1),
+	}
+	t.queue <- req
+	return <-req.done
+}
+
+func (t *pgxTable) worker() {
+	batch := make([]*pgxInsert, 0, pgxMaxBatchSize)
+	for req := range t.queue {
+		batch = append(batch[:0], req)
+	drain:
+		for len(batch) < pgxMaxBatchSize {
+			select {
+			case req := <-t.queue:
+				batch = append(batch, req)
+			default:
+				break drain
+			}
+		}
+		t.flush(batch)
+	}
+}
+
+func (t *pgxTable) flush(batch []*pgxInsert) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultQueryTimeout)
+	defer cancel()
+
+	err := withPgxConn(ctx, func(conn *pgx.Conn) error {
+		if len(batch) == 1 {
+			batch[0].done <- t.exec(ctx, conn, batch[0].row)
+			return nil
+		}
+		rows := make([][]any, 0, len(batch))
+		for _, req := range batch {
+			rows = append(rows, req.row)
+		}
+		_, err := conn.CopyFrom(ctx, pgx.Identifier{t.name}, t.columns, pgx.CopyFromRows(rows))
+		if err != nil {
+			logger.AppLogger.Debug("unable to copy events, inserting them one by one", "table", t.name,
+				"rows", len(rows), "error", err)
+			// COPY does not support ON CONFLICT and a single invalid or
+			// duplicate row fails the whole COPY, insert the rows one by one
+			// so duplicates are ignored and only invalid rows are reported
+			// as failed. Each insert has its own timeout, the COPY one could
+			// be almost expired
+			for _, req := range batch {
+				req.done <- t.execWithTimeout(conn, req.row)
+			}
+			return nil
+		}
+		for _, req := range batch {
+			req.done <- nil
+		}
+		return nil
+	})
+	if err != nil {
+		for _, req := range batch {
+			select {
+			case req.done <- err:
+			default:
+			}
+		}
+	}
+}
+
+func (t *pgxTable) execWithTimeout(conn *pgx.Conn, row []any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultQueryTimeout)
+	defer cancel()
+
+	return t.exec(ctx, conn, row)
+}
+
+func (t *pgxTable) exec(ctx context.Context, conn *pgx.Conn, row []any) error {
+	// the prepared statements are cached by pgx for each connection
+	if _, err := conn.Prepare(ctx, t.statementName, t.insertSQL); err != nil {
+		return err
+	}
+	_, err := conn.Exec(ctx, t.statementName, row...)
+	return err
+}
+
+// withPgxConn executes fn using a pgx connection from the database pool
+func withPgxConn(ctx context.Context, fn func(conn *pgx.Conn) error) error {
+	sqlDB, err := Handle.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection %T", driverConn)
+		}
+		return fn(c.Conn())
+	})
+}
@@ -0,0 +1,179 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestFsEvent() *FsEvent {
+	return &FsEvent{
+		Timestamp:   time.Now().UnixNano(),
+		Action:      "upload",
+		Username:    "username",
+		FsPath:      "/tmp/file.txt",
+		VirtualPath: "/file.txt",
+		FileSize:    123,
+		Elapsed:     456,
+		Status:      1,
+		Protocol:    "SFTP",
+		SessionID:   xid.New().String(),
+		IP:          "::1",
+		FsProvider:  1,
+		OpenFlags:   512,
+		Role:        "role1",
+		InstanceID:  "sftpgo1",
+		CountryCode: "IT",
+		City:        "Rome",
+		ASN:         12345,
+	}
+}
+
+func TestPgxWriter(t *testing.T) {
+	w := getPgxWriter()
+	if w == nil {
+		t.Skip("the pgx writer is only used for PostgreSQL")
+	}
+
+	fsEvent := getTestFsEvent()
+	require.NoError(t, w.insertFsEvent(fsEvent))
+	assert.NotEmpty(t, fsEvent.ID)
+	providerEvent := &ProviderEvent{
+		Timestamp:  time.Now().UnixNano(),
+		Action:     "add",
+		Username:   "admin",
+		IP:         "127.0.0.1",
+		ObjectType: "user",
+		ObjectName: "user1",
+		ObjectData: []byte(`{"username":"user1"}`),
+		Role:       "role1",
+		InstanceID: "sftpgo1",
+	}
+	require.NoError(t, w.insertProviderEvent(providerEvent))
+	assert.NotEmpty(t, providerEvent.ID)
+	logEvent := &LogEvent{
+		Timestamp:  time.Now().UnixNano(),
+		Event:      1,
+		Protocol:   "SSH",
+		Username:   "user1",
+		IP:         "127.0.0.1",
+		Message:    "login failed",
+		InstanceID: "sftpgo1",
+	}
+	require.NoError(t, w.insertLogEvent(logEvent))
+	assert.NotEmpty(t, logEvent.ID)
+
+	sess, cancel := GetDefaultSession()
+	defer cancel()
+
+	var fsEventRead FsEvent
+	require.NoError(t, sess.Where("id = ?", fsEvent.ID).First(&fsEventRead).Error)
+	assert.True(t, fsEvent.EventTime.Equal(fsEventRead.EventTime))
+	fsEventRead.EventTime = fsEvent.EventTime
+	assert.Equal(t, *fsEvent, fsEventRead)
+	var providerEventRead ProviderEvent
+	require.NoError(t, sess.Where("id = ?", providerEvent.ID).First(&providerEventRead).Error)
+	assert.True(t, providerEvent.EventTime.Equal(providerEventRead.EventTime))
+	providerEventRead.EventTime = providerEvent.EventTime
+	// JSONB normalizes the object data
+	assert.JSONEq(t, string(providerEvent.ObjectData), string(providerEventRead.ObjectData))
+	providerEventRead.ObjectData = providerEvent.ObjectData
+	providerEventRead.ObjectJSON = providerEvent.ObjectJSON
+	assert.Equal(t, *providerEvent, providerEventRead)
+	var logEventRead LogEvent
+	require.NoError(t, sess.Where("id = ?", logEvent.ID).First(&logEventRead).Error)
+	assert.True(t, logEvent.EventTime.Equal(logEventRead.EventTime))
+	logEventRead.EventTime = logEvent.EventTime
+	assert.Equal(t, *logEvent, logEventRead)
+
+	// concurrent inserts are grouped, a duplicate row must be ignored
+	// without affecting the other rows
+	numEvents := 200
+	errs := make([]error, numEvents)
+	var wg sync.WaitGroup
+	for idx := range numEvents {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ev := getTestFsEvent()
+			if idx == numEvents/2 {
+				ev.ID = fsEvent.ID
+			}
+			errs[idx] = w.insertFsEvent(ev)
+		}()
+	}
+	wg.Wait()
+	for idx, err := range errs {
+		assert.NoError(t, err, "event %d", idx)
+	}
+	var count int64
+	require.NoError(t, sess.Model(&FsEvent{}).Count(&count).Error)
+	assert.Equal(t, int64(numEvents), count)
+
+	Cleanup(time.Now().Add(1 * time.Hour))
+}
+
+func benchmarkFsEvents(b *testing.B, parallel bool, insert func(ev *FsEvent) error) {
+	b.Cleanup(func() {
+		Cleanup(time.Now().Add(1 * time.Hour))
+	})
+	b.ReportAllocs()
+	b.ResetTimer()
+	if !parallel {
+		for range b.N {
+			if err := insert(getTestFsEvent()); err != nil {
+				b.Fatal(err)
+			}
+		}
+		return
+	}
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := insert(getTestFsEvent()); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkFsEventInsert(b *testing.B) {
+	w := getPgxWriter()
+	if w == nil {
+		b.Skip("the pgx writer is only used for PostgreSQL")
+	}
+	gormInsert := func(ev *FsEvent) error {
+		sess, cancel := GetDefaultSession()
+		defer cancel()
+
+		return ev.Create(sess)
+	}
+	for _, parallel := range []bool{false, true} {
+		b.Run(fmt.Sprintf("gorm/parallel=%t", parallel), func(b *testing.B) {
+			benchmarkFsEvents(b, parallel, gormInsert)
+		})
+		b.Run(fmt.Sprintf("pgx/parallel=%t", parallel), func(b *testing.B) {
+			benchmarkFsEvents(b, parallel, w.insertFsEvent)
+		})
+	}
+}
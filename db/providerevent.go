@@ -15,42 +15,146 @@
 package db
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/rs/xid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
 )
 
 // ProviderEvent defines a provider event
 type ProviderEvent struct {
-	ID         string `json:"id" gorm:"primaryKey"`
-	Timestamp  int64  `json:"timestamp"`
-	Action     string `json:"action"`
-	Username   string `json:"username"`
-	IP         string `json:"ip,omitempty"`
-	ObjectType string `json:"object_type"`
-	ObjectName string `json:"object_name"`
-	ObjectData []byte `json:"object_data"`
-	Role       string `json:"role,omitempty"`
-	InstanceID string `json:"instance_id,omitempty"`
+	ID        string `json:"id" gorm:"primaryKey" parquet:"id"`
+	Timestamp int64  `json:"timestamp" parquet:"timestamp,timestamp(nanosecond)"`
+	Action    string `json:"action" parquet:"action"`
+	Username  string `json:"username" parquet:"username"`
+	IP        string `json:"ip,omitempty" parquet:"ip"`
+	// CountryCode, City and ASN are set if the GeoIP enrichment is enabled
+	CountryCode string `json:"country_code,omitempty" parquet:"country_code"`
+	City        string `json:"city,omitempty" parquet:"city"`
+	ASN         uint32 `json:"asn,omitempty" gorm:"column:asn" parquet:"asn"`
+	ObjectType  string `json:"object_type" parquet:"object_type"`
+	ObjectName  string `json:"object_name" parquet:"object_name"`
+	ObjectData  []byte `json:"object_data" gorm:"-" parquet:"object_data"`
+	Role        string `json:"role,omitempty" parquet:"role"`
+	InstanceID  string `json:"instance_id,omitempty" parquet:"instance_id"`
+	// EventTime is the native representation of Timestamp, truncated to
+	// microseconds
+	EventTime time.Time `json:"-" parquet:"-"`
+	// ObjectJSON stores ObjectData if it is valid JSON, in a native JSON
+	// column. The database normalizes it, for example the whitespaces are
+	// removed, so the bytes read could differ from the saved ones
+	ObjectJSON jsonColumn `json:"-" gorm:"column:object_data" parquet:"-"`
+	// ObjectDataRaw stores ObjectData if it is not valid JSON
+	ObjectDataRaw []byte `json:"-" parquet:"-"`
+	// ObjectDiff is the RFC 6902 JSON patch from the previous version of the
+	// same object, it is empty for the first known version, for deleted
+	// objects and if ObjectData is not valid JSON
+	ObjectDiff jsonColumn `json:"object_diff,omitempty" parquet:"object_diff"`
 }
 
 // TableName defines the database table name
 func (ev *ProviderEvent) TableName() string {
-	return "eventstore_provider_events"
+	return tableName("provider_events")
 }
 
 // BeforeCreate implements gorm hook
 func (ev *ProviderEvent) BeforeCreate(_ *gorm.DB) (err error) {
-	ev.ID = xid.New().String()
+	if ev.ID == "" {
+		ev.ID = xid.New().String()
+	}
+	if ev.EventTime.IsZero() {
+		ev.EventTime = getEventTime(ev.Timestamp)
+	}
+	ev.ObjectJSON = nil
+	ev.ObjectDataRaw = nil
+	if isValidJSON(ev.ObjectData) {
+		ev.ObjectJSON = jsonColumn(ev.ObjectData)
+	} else if len(ev.ObjectData) > 0 {
+		ev.ObjectDataRaw = ev.ObjectData
+	}
+	return
+}
+
+// AfterFind implements gorm hook
+func (ev *ProviderEvent) AfterFind(_ *gorm.DB) (err error) {
+	if ev.ObjectJSON != nil {
+		ev.ObjectData = []byte(ev.ObjectJSON)
+	} else {
+		ev.ObjectData = ev.ObjectDataRaw
+	}
 	return
 }
 
-// Create persists the object
+// Create persists the object, an event with the same ID is ignored
 func (ev *ProviderEvent) Create(tx *gorm.DB) error {
-	return tx.Create(ev).Error
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(ev).Error
+}
+
+// setObjectDiff computes the diff against the previous version of the same
+// object saved as valid JSON
+func (ev *ProviderEvent) setObjectDiff(tx *gorm.DB) error {
+	ev.ObjectDiff = nil
+	if ev.Action == "delete" || !isValidJSON(ev.ObjectData) {
+		return nil
+	}
+	var previous ProviderEvent
+	// the previous version is found using the timestamp column, like the
+	// events order, it has nanoseconds precision
+	err := tx.Select("object_data").
+		Where("object_type = ? AND object_name = ? AND timestamp < ? AND object_data IS NOT NULL",
+			ev.ObjectType, ev.ObjectName, ev.Timestamp).
+		Order("timestamp DESC").Limit(1).Find(&previous).Error
+	if err != nil || previous.ObjectJSON == nil {
+		return err
+	}
+	ops, err := DiffJSON(previous.ObjectJSON, ev.ObjectData)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(ops)
+	if err != nil {
+		return err
+	}
+	ev.ObjectDiff = data
+	return nil
+}
+
+// GetProviderObjectHistory returns the events for the specified provider
+// object ordered by time
+func GetProviderObjectHistory(objectType, objectName string) ([]ProviderEvent, error) {
+	sess, cancel := getSessionWithTimeout(5 * time.Minute)
+	defer cancel()
+
+	var result []ProviderEvent
+	err := sess.Where("object_type = ? AND object_name = ?", objectType, objectName).
+		Order("timestamp ASC, id ASC").Find(&result).Error
+	return result, err
+}
+
+// GetProviderObjectsAt returns, for each provider object, the last event saved
+// before or at the specified time, deleted objects are excluded. The objects
+// can be filtered by type and name, empty filters match all objects
+func GetProviderObjectsAt(at time.Time, objectTypes, objectNames []string) ([]ProviderEvent, error) {
+	sess, cancel := getSessionWithTimeout(5 * time.Minute)
+	defer cancel()
+
+	latest := sess.Model(&ProviderEvent{}).
+		Select("*, ROW_NUMBER() OVER (PARTITION BY object_type, object_name ORDER BY timestamp DESC, id DESC) AS rn")
+	latest = WhereEventTime(latest, time.Time{}, at.Add(time.Microsecond))
+	if len(objectTypes) > 0 {
+		latest = latest.Where("object_type IN ?", objectTypes)
+	}
+	if len(objectNames) > 0 {
+		latest = latest.Where("object_name IN ?", objectNames)
+	}
+	var result []ProviderEvent
+	err := sess.Table("(?) AS latest", latest).Where("rn = 1 AND action <> ?", "delete").
+		Order("object_type ASC, object_name ASC").Find(&result).Error
+	return result, err
 }
 
 func cleanupProviderEvents(timestamp time.Time) error {
@@ -58,7 +162,7 @@ func cleanupProviderEvents(timestamp time.Time) error {
 	defer cancel()
 
 	logger.AppLogger.Debug("removing provider events", "timestamp", timestamp)
-	sess = sess.Where("timestamp < ?", timestamp.UnixNano()).Delete(&ProviderEvent{})
+	sess = WhereEventTime(sess, time.Time{}, timestamp).Delete(&ProviderEvent{})
 	err := sess.Error
 	if err == nil {
 		logger.AppLogger.Debug("provider events deleted", "num", sess.RowsAffected)
@@ -0,0 +1,82 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sftpgo/sdk/plugin/notifier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderObjectsAt(t *testing.T) {
+	n := Notifier{
+		InstanceID: "sftpgo1",
+	}
+	ts := time.Now().Add(-time.Hour)
+	events := []struct {
+		offset     time.Duration
+		action     string
+		objectType string
+		objectName string
+		data       string
+	}{
+		{0, "add", "user", "user1", `{"username":"user1","home_dir":"/srv/user1"}`},
+		{time.Minute, "update", "user", "user1", `{"username":"user1","home_dir":"/srv/user2"}`},
+		{2 * time.Minute, "update", "user", "user1", `{"username":"user1","home_dir":"/srv/user3"}`},
+		{0, "add", "user", "user2", `{"username":"user2"}`},
+		{time.Minute, "delete", "user", "user2", `{"username":"user2"}`},
+		{0, "add", "admin", "user1", `{"username":"user1"}`},
+		{2 * time.Minute, "add", "folder", "folder1", `{"name":"folder1"}`},
+	}
+	for _, ev := range events {
+		err := n.NotifyProviderEvent(&notifier.ProviderEvent{
+			Timestamp:  ts.Add(ev.offset).UnixNano(),
+			Action:     ev.action,
+			Username:   "admin",
+			ObjectType: ev.objectType,
+			ObjectName: ev.objectName,
+			ObjectData: []byte(ev.data),
+		})
+		require.NoError(t, err)
+	}
+
+	result, err := GetProviderObjectsAt(ts.Add(-time.Second), nil, nil)
+	require.NoError(t, err)
+	assert.Len(t, result, 0)
+
+	result, err = GetProviderObjectsAt(ts, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, result, 3)
+	assert.Equal(t, "admin", result[0].ObjectType)
+	assert.Equal(t, "user2", result[2].ObjectName)
+
+	result, err = GetProviderObjectsAt(ts.Add(90*time.Second), []string{"user"}, nil)
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, "update", result[0].Action)
+	assert.JSONEq(t, `{"username":"user1","home_dir":"/srv/user2"}`, string(result[0].ObjectData))
+
+	result, err = GetProviderObjectsAt(time.Now(), nil, []string{"user1", "folder1"})
+	require.NoError(t, err)
+	require.Len(t, result, 3)
+	assert.Equal(t, "admin", result[0].ObjectType)
+	assert.Equal(t, "folder", result[1].ObjectType)
+	assert.JSONEq(t, `{"username":"user1","home_dir":"/srv/user3"}`, string(result[2].ObjectData))
+
+	Cleanup(time.Now().Add(1 * time.Hour))
+}
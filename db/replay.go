@@ -0,0 +1,80 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"time"
+)
+
+const replayPageSize = 1000
+
+// ReplayFsEvents calls fn for each filesystem event within the specified time
+// range, to is excluded, the oldest first
+func ReplayFsEvents(from, to time.Time, fn func(*FsEvent) error) error {
+	return replayEvents(from, to, func(ev *FsEvent) (int64, string) {
+		return ev.Timestamp, ev.ID
+	}, fn)
+}
+
+// ReplayProviderEvents calls fn for each provider event within the specified
+// time range, to is excluded, the oldest first
+func ReplayProviderEvents(from, to time.Time, fn func(*ProviderEvent) error) error {
+	return replayEvents(from, to, func(ev *ProviderEvent) (int64, string) {
+		return ev.Timestamp, ev.ID
+	}, fn)
+}
+
+// ReplayLogEvents calls fn for each log event within the specified time range,
+// to is excluded, the oldest first
+func ReplayLogEvents(from, to time.Time, fn func(*LogEvent) error) error {
+	return replayEvents(from, to, func(ev *LogEvent) (int64, string) {
+		return ev.Timestamp, ev.ID
+	}, fn)
+}
+
+// replayEvents reads the events in pages using the timestamp and the ID as
+// keyset, so the events with the same timestamp are not skipped
+func replayEvents[T any](from, to time.Time, key func(*T) (int64, string), fn func(*T) error) error {
+	var lastTimestamp int64
+	var lastID string
+	for {
+		page, err := getReplayPage[T](from, to, lastTimestamp, lastID)
+		if err != nil {
+			return err
+		}
+		for idx := range page {
+			if err := fn(&page[idx]); err != nil {
+				return err
+			}
+		}
+		if len(page) < replayPageSize {
+			return nil
+		}
+		lastTimestamp, lastID = key(&page[len(page)-1])
+	}
+}
+
+func getReplayPage[T any](from, to time.Time, lastTimestamp int64, lastID string) ([]T, error) {
+	sess, cancel := getSessionWithTimeout(5 * time.Minute)
+	defer cancel()
+
+	sess = WhereEventTime(sess, from, to)
+	if lastID != "" {
+		sess = sess.Where("(timestamp > ? OR (timestamp = ? AND id > ?))", lastTimestamp, lastTimestamp, lastID)
+	}
+	var result []T
+	err := sess.Order("timestamp ASC, id ASC").Limit(replayPageSize).Find(&result).Error
+	return result, err
+}
@@ -0,0 +1,66 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sftpgo/sdk/plugin/notifier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayEvents(t *testing.T) {
+	n := Notifier{}
+	now := time.Now()
+	for idx := range 3 {
+		ts := now.Add(-time.Duration(idx) * time.Hour).UnixNano()
+		require.NoError(t, n.NotifyFsEvent(&notifier.FsEvent{Timestamp: ts, Action: "upload", Username: "user1",
+			Status: 1}))
+		require.NoError(t, n.NotifyProviderEvent(&notifier.ProviderEvent{Timestamp: ts, Action: "add",
+			ObjectType: "user", ObjectName: "user1", ObjectData: []byte(`{"username":"user1"}`)}))
+		require.NoError(t, n.NotifyLogEvent(&notifier.LogEvent{Timestamp: ts, Event: 1, Username: "user1"}))
+	}
+
+	var timestamps []int64
+	err := ReplayFsEvents(now.Add(-90*time.Minute), now.Add(time.Minute), func(ev *FsEvent) error {
+		timestamps = append(timestamps, ev.Timestamp)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{now.Add(-time.Hour).UnixNano(), now.UnixNano()}, timestamps)
+
+	var providerEvents []ProviderEvent
+	err = ReplayProviderEvents(now.Add(-3*time.Hour), now, func(ev *ProviderEvent) error {
+		providerEvents = append(providerEvents, *ev)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, providerEvents, 2)
+	assert.JSONEq(t, `{"username":"user1"}`, string(providerEvents[0].ObjectData))
+
+	errReplay := errors.New("replay error")
+	var count int
+	err = ReplayLogEvents(now.Add(-3*time.Hour), now.Add(time.Minute), func(_ *LogEvent) error {
+		count++
+		return errReplay
+	})
+	assert.ErrorIs(t, err, errReplay)
+	assert.Equal(t, 1, count)
+
+	Cleanup(time.Now().Add(1 * time.Hour))
+}
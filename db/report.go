@@ -0,0 +1,122 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ActionStats defines the totals for a filesystem action
+type ActionStats struct {
+	Action   string `json:"action"`
+	Count    int64  `json:"count"`
+	Bytes    int64  `json:"bytes"`
+	Failures int64  `json:"failures"`
+}
+
+// PathStats defines the totals for a virtual path
+type PathStats struct {
+	Path  string `json:"path"`
+	Count int64  `json:"count"`
+	Bytes int64  `json:"bytes"`
+}
+
+// ValueCount defines the number of events for a value
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+func getUserFsEvents(sess *gorm.DB, username string, from, to time.Time) *gorm.DB {
+	return WhereEventTime(sess.Model(&FsEvent{}).Where("username = ?", username), from, to)
+}
+
+// GetUserActionStats returns the totals for each filesystem action done by the
+// specified user within the specified time range, to is excluded
+func GetUserActionStats(username string, from, to time.Time) ([]ActionStats, error) {
+	sess, cancel := getSessionWithTimeout(5 * time.Minute)
+	defer cancel()
+
+	var result []ActionStats
+	err := getUserFsEvents(sess, username, from, to).
+		Select("action, COUNT(*) AS count, SUM(file_size) AS bytes, SUM(CASE WHEN status <> 1 THEN 1 ELSE 0 END) AS failures").
+		Group("action").Order("count DESC, action ASC").Scan(&result).Error
+	return result, err
+}
+
+// GetUserPathStats returns the totals for each virtual path used by the
+// specified user within the specified time range, to is excluded
+func GetUserPathStats(username string, from, to time.Time) ([]PathStats, error) {
+	sess, cancel := getSessionWithTimeout(5 * time.Minute)
+	defer cancel()
+
+	var result []PathStats
+	err := getUserFsEvents(sess, username, from, to).
+		Select("virtual_path AS path, COUNT(*) AS count, SUM(file_size) AS bytes").
+		Group("virtual_path").Order("count DESC, virtual_path ASC").Scan(&result).Error
+	return result, err
+}
+
+// GetUserFsValueCounts returns the number of filesystem events for each value
+// of the specified field, "protocol" or "ip", for the specified user within
+// the specified time range, to is excluded
+func GetUserFsValueCounts(username, field string, from, to time.Time) ([]ValueCount, error) {
+	switch field {
+	case "protocol", "ip":
+	default:
+		return nil, fmt.Errorf("unsupported field %q", field)
+	}
+	sess, cancel := getSessionWithTimeout(5 * time.Minute)
+	defer cancel()
+
+	var result []ValueCount
+	err := getUserFsEvents(sess, username, from, to).
+		Select(field + " AS value, COUNT(*) AS count").
+		Group(field).Order("count DESC, " + field + " ASC").Scan(&result).Error
+	return result, err
+}
+
+// GetUserFailedFsEvents returns the most recent failed filesystem events for
+// the specified user within the specified time range, to is excluded
+func GetUserFailedFsEvents(username string, from, to time.Time, limit int) ([]FsEvent, error) {
+	sess, cancel := getSessionWithTimeout(5 * time.Minute)
+	defer cancel()
+
+	var result []FsEvent
+	err := getUserFsEvents(sess, username, from, to).Where("status <> 1").
+		Order("timestamp DESC, id DESC").Limit(limit).Find(&result).Error
+	return result, err
+}
+
+// GetUserLoginFailures returns the number of login failures, log events 1 and
+// 2, for the specified user within the specified time range, to is excluded,
+// and the most recent ones
+func GetUserLoginFailures(username string, from, to time.Time, limit int) (int64, []LogEvent, error) {
+	sess, cancel := getSessionWithTimeout(5 * time.Minute)
+	defer cancel()
+
+	var count int64
+	var result []LogEvent
+	query := WhereEventTime(sess.Model(&LogEvent{}).Where("username = ? AND event IN ?", username, []int{1, 2}),
+		from, to).Session(&gorm.Session{})
+	if err := query.Count(&count).Error; err != nil {
+		return 0, nil, err
+	}
+	err := query.Order("timestamp DESC, id DESC").Limit(limit).Find(&result).Error
+	return count, result, err
+}
@@ -0,0 +1,88 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sftpgo/sdk/plugin/notifier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserReport(t *testing.T) {
+	n := Notifier{
+		InstanceID: "sftpgo1",
+	}
+	now := time.Now()
+	ts := now.Add(-time.Hour).UnixNano()
+	fsEvents := []*notifier.FsEvent{
+		{Timestamp: ts, Action: "upload", Username: "user1", VirtualPath: "/dir/file1", FileSize: 100,
+			Status: 1, Protocol: "SFTP", IP: "127.0.0.1"},
+		{Timestamp: ts + 1, Action: "upload", Username: "user1", VirtualPath: "/dir/file1", FileSize: 200,
+			Status: 3, Protocol: "FTP", IP: "127.0.0.1"},
+		{Timestamp: ts + 2, Action: "delete", Username: "user1", VirtualPath: "/file2", FileSize: 50,
+			Status: 1, Protocol: "SFTP", IP: "127.0.0.2"},
+		{Timestamp: ts, Action: "upload", Username: "user2", VirtualPath: "/dir/file1", FileSize: 100,
+			Status: 1, Protocol: "SFTP", IP: "127.0.0.1"},
+		{Timestamp: now.Add(-48 * time.Hour).UnixNano(), Action: "upload", Username: "user1",
+			VirtualPath: "/dir/file1", FileSize: 100, Status: 2, Protocol: "SFTP", IP: "127.0.0.1"},
+	}
+	for _, ev := range fsEvents {
+		require.NoError(t, n.NotifyFsEvent(ev))
+	}
+	logEvents := []*notifier.LogEvent{
+		{Timestamp: ts, Event: 1, Protocol: "SSH", Username: "user1", IP: "127.0.0.1"},
+		{Timestamp: ts + 1, Event: 2, Protocol: "SSH", Username: "user1", IP: "127.0.0.1"},
+		{Timestamp: ts + 2, Event: 5, Protocol: "SSH", Username: "user1", IP: "127.0.0.1"},
+	}
+	for _, ev := range logEvents {
+		require.NoError(t, n.NotifyLogEvent(ev))
+	}
+	from := now.Add(-24 * time.Hour)
+
+	actions, err := GetUserActionStats("user1", from, now)
+	require.NoError(t, err)
+	assert.Equal(t, []ActionStats{
+		{Action: "upload", Count: 2, Bytes: 300, Failures: 1},
+		{Action: "delete", Count: 1, Bytes: 50},
+	}, actions)
+	paths, err := GetUserPathStats("user1", from, now)
+	require.NoError(t, err)
+	assert.Equal(t, []PathStats{
+		{Path: "/dir/file1", Count: 2, Bytes: 300},
+		{Path: "/file2", Count: 1, Bytes: 50},
+	}, paths)
+	protocols, err := GetUserFsValueCounts("user1", "protocol", from, now)
+	require.NoError(t, err)
+	assert.Equal(t, []ValueCount{{Value: "SFTP", Count: 2}, {Value: "FTP", Count: 1}}, protocols)
+	ips, err := GetUserFsValueCounts("user1", "ip", from, now)
+	require.NoError(t, err)
+	assert.Equal(t, []ValueCount{{Value: "127.0.0.1", Count: 2}, {Value: "127.0.0.2", Count: 1}}, ips)
+	_, err = GetUserFsValueCounts("user1", "username", from, now)
+	assert.Error(t, err)
+	failed, err := GetUserFailedFsEvents("user1", from, now, 10)
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	assert.Equal(t, 3, failed[0].Status)
+	count, logins, err := GetUserLoginFailures("user1", from, now, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	require.Len(t, logins, 1)
+	assert.Equal(t, 2, logins[0].Event)
+
+	Cleanup(time.Now().Add(1 * time.Hour))
+}
@@ -0,0 +1,154 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+const (
+	fsRollupsHourlyTable = "fs_rollups_hourly"
+	fsRollupsDailyTable  = "fs_rollups_daily"
+	// the hourly rollups are computed from the fs events, the daily ones
+	// from the hourly rollups
+	hourlyRollupTotals = `COUNT(*) AS events, SUM(file_size) AS bytes,
+SUM(CASE WHEN status <> 1 THEN 1 ELSE 0 END) AS failures, SUM(elapsed) AS elapsed`
+	dailyRollupTotals = `SUM(events) AS events, SUM(bytes) AS bytes, SUM(failures) AS failures,
+SUM(elapsed) AS elapsed`
+)
+
+// FsRollup defines the fs event totals for a time bucket, instance, username,
+// protocol and action
+type FsRollup struct {
+	// Bucket is the start of the hour or day, UTC
+	Bucket     time.Time `json:"bucket" gorm:"primaryKey"`
+	InstanceID string    `json:"instance_id" gorm:"primaryKey"`
+	Username   string    `json:"username" gorm:"primaryKey"`
+	Protocol   string    `json:"protocol" gorm:"primaryKey"`
+	Action     string    `json:"action" gorm:"primaryKey"`
+	Events     int64     `json:"events"`
+	Bytes      int64     `json:"bytes"`
+	// Failures is the number of events with a status other than OK
+	Failures int64 `json:"failures"`
+	// Elapsed is the total elapsed time, in milliseconds
+	Elapsed int64 `json:"elapsed"`
+}
+
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// RefreshRollups recomputes the hourly rollups for the hours overlapping the
+// specified time range, and the daily rollups for the affected days. The
+// rollups are computed from the stored events, so a refresh can be repeated
+// and includes the events received late
+func RefreshRollups(from, to time.Time) error {
+	start := from.UTC().Truncate(time.Hour)
+	for hour := start; hour.Before(to); hour = hour.Add(time.Hour) {
+		if err := refreshRollup(tableName(fsRollupsHourlyTable), hour, hour.Add(time.Hour),
+			(&FsEvent{}).TableName(), hourlyRollupTotals, WhereEventTime); err != nil {
+			return fmt.Errorf("unable to refresh hourly rollups for %s: %w", hour, err)
+		}
+	}
+	for day := truncateDay(start); day.Before(to); day = day.AddDate(0, 0, 1) {
+		if err := refreshRollup(tableName(fsRollupsDailyTable), day, day.AddDate(0, 0, 1),
+			tableName(fsRollupsHourlyTable), dailyRollupTotals, whereBucket); err != nil {
+			return fmt.Errorf("unable to refresh daily rollups for %s: %w", day, err)
+		}
+	}
+	return nil
+}
+
+func whereBucket(sess *gorm.DB, from, to time.Time) *gorm.DB {
+	return sess.Where("bucket >= ? AND bucket < ?", from.UTC(), to.UTC())
+}
+
+// refreshRollup replaces the rows for the specified bucket aggregating the
+// source table rows within the bucket, as selected by the where function
+func refreshRollup(table string, bucket, end time.Time, source, totals string,
+	where func(sess *gorm.DB, from, to time.Time) *gorm.DB,
+) error {
+	sess, cancel := getSessionWithTimeout(5 * time.Minute)
+	defer cancel()
+
+	var rollups []FsRollup
+	query := sess.Table(source).
+		Select("COALESCE(instance_id, '') AS instance_id, username, protocol, action, " + totals)
+	err := where(query, bucket, end).
+		Group("COALESCE(instance_id, ''), username, protocol, action").Scan(&rollups).Error
+	if err != nil {
+		return err
+	}
+	for idx := range rollups {
+		rollups[idx].Bucket = bucket
+	}
+	return sess.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(table).Where("bucket = ?", bucket).Delete(&FsRollup{}).Error; err != nil {
+			return err
+		}
+		if len(rollups) == 0 {
+			return nil
+		}
+		return tx.Table(table).CreateInBatches(rollups, 500).Error
+	})
+}
+
+// GetRollups returns the hourly or daily rollups, for the specified username
+// if not empty, within the specified time range, to is excluded
+func GetRollups(daily bool, username string, from, to time.Time) ([]FsRollup, error) {
+	sess, cancel := getSessionWithTimeout(5 * time.Minute)
+	defer cancel()
+
+	table := tableName(fsRollupsHourlyTable)
+	if daily {
+		table = tableName(fsRollupsDailyTable)
+	}
+	sess = sess.Table(table).Where("bucket >= ? AND bucket < ?", from.UTC(), to.UTC())
+	if username != "" {
+		sess = sess.Where("username = ?", username)
+	}
+	var result []FsRollup
+	err := sess.Order("bucket ASC, instance_id ASC, username ASC, protocol ASC, action ASC").Find(&result).Error
+	return result, err
+}
+
+// CleanupRollups removes the hourly and daily rollups older than the specified
+// times, a zero time means no rollup is removed
+func CleanupRollups(hourly, daily time.Time) {
+	tables := map[string]time.Time{
+		tableName(fsRollupsHourlyTable): hourly,
+		tableName(fsRollupsDailyTable):  daily,
+	}
+	for table, t := range tables {
+		if t.IsZero() {
+			continue
+		}
+		sess, cancel := getSessionWithTimeout(20 * time.Minute)
+		logger.AppLogger.Debug("removing rollups", "table", table, "timestamp", t)
+		sess = sess.Table(table).Where("bucket < ?", t.UTC()).Delete(&FsRollup{})
+		cancel()
+		if sess.Error != nil {
+			logger.AppLogger.Error("unable to delete rollups", "table", table, "error", sess.Error)
+			continue
+		}
+		logger.AppLogger.Debug("rollups deleted", "table", table, "num", sess.RowsAffected)
+	}
+}
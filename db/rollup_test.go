@@ -0,0 +1,95 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sftpgo/sdk/plugin/notifier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollups(t *testing.T) {
+	n := Notifier{
+		InstanceID: "sftpgo1",
+	}
+	hour := time.Now().UTC().Truncate(time.Hour).Add(-2 * time.Hour)
+	fsEvents := []*notifier.FsEvent{
+		{Timestamp: hour.UnixNano(), Action: "upload", Username: "user1", FileSize: 100, Elapsed: 10,
+			Status: 1, Protocol: "SFTP"},
+		{Timestamp: hour.Add(time.Minute).UnixNano(), Action: "upload", Username: "user1", FileSize: 200,
+			Elapsed: 20, Status: 3, Protocol: "SFTP"},
+		{Timestamp: hour.Add(time.Hour).UnixNano(), Action: "upload", Username: "user1", FileSize: 50,
+			Elapsed: 5, Status: 1, Protocol: "SFTP"},
+		{Timestamp: hour.Add(time.Hour).UnixNano(), Action: "download", Username: "user2", FileSize: 10,
+			Status: 1, Protocol: "FTP"},
+	}
+	for _, ev := range fsEvents {
+		require.NoError(t, n.NotifyFsEvent(ev))
+	}
+	now := time.Now()
+	require.NoError(t, RefreshRollups(hour, now))
+	// a refresh can be repeated
+	require.NoError(t, RefreshRollups(hour, now))
+
+	hourly, err := GetRollups(false, "user1", hour, now)
+	require.NoError(t, err)
+	require.Len(t, hourly, 2)
+	assert.True(t, hour.Equal(hourly[0].Bucket))
+	hourly[0].Bucket = time.Time{}
+	assert.Equal(t, FsRollup{InstanceID: "sftpgo1", Username: "user1", Protocol: "SFTP", Action: "upload",
+		Events: 2, Bytes: 300, Failures: 1, Elapsed: 30}, hourly[0])
+	assert.True(t, hour.Add(time.Hour).Equal(hourly[1].Bucket))
+	assert.Equal(t, int64(1), hourly[1].Events)
+
+	// late events are included in the next refresh
+	require.NoError(t, n.NotifyFsEvent(&notifier.FsEvent{Timestamp: hour.Add(2 * time.Minute).UnixNano(),
+		Action: "upload", Username: "user1", FileSize: 1, Status: 1, Protocol: "SFTP"}))
+	require.NoError(t, RefreshRollups(hour, hour.Add(time.Minute)))
+	hourly, err = GetRollups(false, "user1", hour, hour.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, hourly, 1)
+	assert.Equal(t, int64(3), hourly[0].Events)
+	assert.Equal(t, int64(301), hourly[0].Bytes)
+
+	// the events could be within two days
+	day := truncateDay(hour)
+	daily, err := GetRollups(true, "", day, day.AddDate(0, 0, 2))
+	require.NoError(t, err)
+	var events, bytes int64
+	for _, r := range daily {
+		assert.True(t, truncateDay(r.Bucket).Equal(r.Bucket))
+		events += r.Events
+		bytes += r.Bytes
+	}
+	assert.Equal(t, int64(5), events)
+	assert.Equal(t, int64(361), bytes)
+
+	CleanupRollups(time.Time{}, time.Time{})
+	hourly, err = GetRollups(false, "", hour, now)
+	require.NoError(t, err)
+	assert.Len(t, hourly, 3)
+	CleanupRollups(now.Add(time.Hour), now.AddDate(0, 0, 2))
+	hourly, err = GetRollups(false, "", hour, now)
+	require.NoError(t, err)
+	assert.Len(t, hourly, 0)
+	daily, err = GetRollups(true, "", day, day.AddDate(0, 0, 2))
+	require.NoError(t, err)
+	assert.Len(t, daily, 0)
+
+	Cleanup(time.Now().Add(1 * time.Hour))
+}
@@ -0,0 +1,115 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// Supported orders for the top sessions
+const (
+	SessionOrderBytes    = "bytes"
+	SessionOrderDuration = "duration"
+)
+
+// SessionSummary defines the totals for a session computed from the fs events
+type SessionSummary struct {
+	SessionID string `json:"session_id"`
+	Username  string `json:"username"`
+	IP        string `json:"ip,omitempty"`
+	Protocol  string `json:"protocol"`
+	// Start is the start of the first operation, as unix nanoseconds, the
+	// elapsed time is subtracted from the event timestamp
+	Start int64 `json:"start" gorm:"column:session_start"`
+	// End is the timestamp of the last event, as unix nanoseconds
+	End             int64 `json:"end" gorm:"column:session_end"`
+	BytesUploaded   int64 `json:"bytes_uploaded"`
+	BytesDownloaded int64 `json:"bytes_downloaded"`
+	// Files is the number of successful uploads and downloads
+	Files int64 `json:"files"`
+	// Failures is the number of events with a status other than OK
+	Failures int64 `json:"failures"`
+}
+
+// sessionSummarySelect computes the session totals, the elapsed time is in
+// milliseconds
+const sessionSummarySelect = `session_id, MIN(username) AS username, MIN(ip) AS ip, MIN(protocol) AS protocol,
+MIN(timestamp - elapsed * 1000000) AS session_start, MAX(timestamp) AS session_end,
+SUM(CASE WHEN action = 'upload' THEN file_size ELSE 0 END) AS bytes_uploaded,
+SUM(CASE WHEN action = 'download' THEN file_size ELSE 0 END) AS bytes_downloaded,
+SUM(CASE WHEN action IN ('upload', 'download') AND status = 1 THEN 1 ELSE 0 END) AS files,
+SUM(CASE WHEN status <> 1 THEN 1 ELSE 0 END) AS failures,
+SUM(CASE WHEN action IN ('upload', 'download') THEN file_size ELSE 0 END) AS total_bytes,
+MAX(timestamp) - MIN(timestamp - elapsed * 1000000) AS duration`
+
+// GetSessionFsEvents returns the fs events for the specified session ordered
+// by time
+func GetSessionFsEvents(sessionID string) ([]FsEvent, error) {
+	sess, cancel := getSessionWithTimeout(5 * time.Minute)
+	defer cancel()
+
+	var result []FsEvent
+	err := sess.Where("session_id = ?", sessionID).Order("timestamp ASC, id ASC").Find(&result).Error
+	return result, err
+}
+
+// GetSessionLogEvents returns the log events for the specified username, IP
+// and protocol within the specified time range, as unix nanoseconds, ordered
+// by time
+func GetSessionLogEvents(username, ip, protocol string, start, end int64) ([]LogEvent, error) {
+	sess, cancel := getSessionWithTimeout(5 * time.Minute)
+	defer cancel()
+
+	var result []LogEvent
+	sess = sess.Where("username = ? AND ip = ? AND protocol = ?", username, ip, protocol)
+	err := WhereEventTime(sess, time.Unix(0, start), time.Unix(0, end).Add(time.Microsecond)).
+		Order("timestamp ASC, id ASC").Find(&result).Error
+	return result, err
+}
+
+// GetTopSessions returns the sessions with at least an fs event within the
+// specified time range, to is excluded, ordered by transferred bytes or
+// duration. The totals only include the events within the time range
+func GetTopSessions(from, to time.Time, orderBy string, limit int) ([]SessionSummary, error) {
+	var order string
+	switch orderBy {
+	case SessionOrderBytes:
+		order = "total_bytes DESC, session_id ASC"
+	case SessionOrderDuration:
+		order = "duration DESC, session_id ASC"
+	default:
+		return nil, fmt.Errorf("unsupported order %q", orderBy)
+	}
+	sess, cancel := getSessionWithTimeout(5 * time.Minute)
+	defer cancel()
+
+	var result []SessionSummary
+	sess = sess.Model(&FsEvent{}).Select(sessionSummarySelect).Where("session_id <> ''")
+	err := WhereEventTime(sess, from, to).
+		Group("session_id").Order(order).Limit(limit).Scan(&result).Error
+	return result, err
+}
+
+// GetSessionSummary returns the totals for the specified session
+func GetSessionSummary(sessionID string) (SessionSummary, error) {
+	sess, cancel := getSessionWithTimeout(5 * time.Minute)
+	defer cancel()
+
+	var result SessionSummary
+	err := sess.Model(&FsEvent{}).Select(sessionSummarySelect).Where("session_id = ?", sessionID).
+		Group("session_id").Scan(&result).Error
+	return result, err
+}
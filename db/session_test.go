@@ -0,0 +1,98 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sftpgo/sdk/plugin/notifier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessions(t *testing.T) {
+	n := Notifier{
+		InstanceID: "sftpgo1",
+	}
+	now := time.Now()
+	ts := now.Add(-time.Hour).UnixNano()
+	fsEvents := []*notifier.FsEvent{
+		{Timestamp: ts, Action: "upload", Username: "user1", VirtualPath: "/file1", FileSize: 100,
+			Elapsed: 2000, Status: 1, Protocol: "SFTP", IP: "127.0.0.1", SessionID: "sid1"},
+		{Timestamp: ts + int64(time.Minute), Action: "download", Username: "user1", VirtualPath: "/file1",
+			FileSize: 50, Status: 1, Protocol: "SFTP", IP: "127.0.0.1", SessionID: "sid1"},
+		{Timestamp: ts + int64(2*time.Minute), Action: "upload", Username: "user1", VirtualPath: "/file2",
+			FileSize: 10, Status: 3, Protocol: "SFTP", IP: "127.0.0.1", SessionID: "sid1"},
+		{Timestamp: ts, Action: "upload", Username: "user2", VirtualPath: "/file1", FileSize: 1000,
+			Status: 1, Protocol: "FTP", IP: "127.0.0.2", SessionID: "sid2"},
+		{Timestamp: ts, Action: "mkdir", Username: "user2", VirtualPath: "/dir", Status: 1,
+			Protocol: "FTP", IP: "127.0.0.2"},
+	}
+	for _, ev := range fsEvents {
+		require.NoError(t, n.NotifyFsEvent(ev))
+	}
+	logEvents := []*notifier.LogEvent{
+		{Timestamp: ts - int64(time.Second), Event: 5, Protocol: "SFTP", Username: "user1", IP: "127.0.0.1"},
+		{Timestamp: ts - int64(time.Hour), Event: 1, Protocol: "SFTP", Username: "user1", IP: "127.0.0.1"},
+		{Timestamp: ts, Event: 5, Protocol: "FTP", Username: "user1", IP: "127.0.0.1"},
+	}
+	for _, ev := range logEvents {
+		require.NoError(t, n.NotifyLogEvent(ev))
+	}
+
+	summary, err := GetSessionSummary("sid1")
+	require.NoError(t, err)
+	assert.Equal(t, SessionSummary{
+		SessionID:       "sid1",
+		Username:        "user1",
+		IP:              "127.0.0.1",
+		Protocol:        "SFTP",
+		Start:           ts - int64(2*time.Second),
+		End:             ts + int64(2*time.Minute),
+		BytesUploaded:   110,
+		BytesDownloaded: 50,
+		Files:           2,
+		Failures:        1,
+	}, summary)
+	summary, err = GetSessionSummary("missing")
+	require.NoError(t, err)
+	assert.Empty(t, summary.SessionID)
+
+	events, err := GetSessionFsEvents("sid1")
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, "download", events[1].Action)
+	logs, err := GetSessionLogEvents("user1", "127.0.0.1", "SFTP", ts-int64(time.Minute), ts)
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, 5, logs[0].Event)
+
+	top, err := GetTopSessions(now.Add(-2*time.Hour), now, SessionOrderBytes, 10)
+	require.NoError(t, err)
+	require.Len(t, top, 2)
+	assert.Equal(t, "sid2", top[0].SessionID)
+	top, err = GetTopSessions(now.Add(-2*time.Hour), now, SessionOrderDuration, 1)
+	require.NoError(t, err)
+	require.Len(t, top, 1)
+	assert.Equal(t, "sid1", top[0].SessionID)
+	top, err = GetTopSessions(now.Add(-30*time.Minute), now, SessionOrderBytes, 10)
+	require.NoError(t, err)
+	assert.Len(t, top, 0)
+	_, err = GetTopSessions(now.Add(-2*time.Hour), now, "invalid", 10)
+	assert.Error(t, err)
+
+	Cleanup(time.Now().Add(1 * time.Hour))
+}
@@ -0,0 +1,168 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+// Supported event types
+const (
+	EventTypeFs       = "fs"
+	EventTypeProvider = "provider"
+	EventTypeLog      = "log"
+)
+
+// Sink defines a destination the events are written to
+type Sink interface {
+	// Name returns the sink name, it is used in logs and to configure the routes
+	Name() string
+	WriteFsEvent(ev *FsEvent) error
+	WriteProviderEvent(ev *ProviderEvent) error
+	WriteLogEvent(ev *LogEvent) error
+}
+
+// SinkConfig defines a sink, its failure policy and the events to write
+type SinkConfig struct {
+	Sink Sink
+	// Required defines whether a sink error fails the notify call, this way
+	// SFTPGo will retry the event. Errors from other sinks are only logged
+	Required bool
+	// Route defines the events written to the sink, nil means all events
+	Route *Route
+}
+
+// Route defines the events to write to a sink. Empty fields match all events,
+// fields that don't apply to an event type are ignored for that type
+type Route struct {
+	// EventTypes defines the event types: fs, provider, log
+	EventTypes []string `json:"event_types,omitempty"`
+	// Actions defines the filesystem and provider actions
+	Actions []string `json:"actions,omitempty"`
+	// Statuses defines the filesystem event statuses: 1 OK, 2 error,
+	// 3 quota exceeded
+	Statuses []int `json:"statuses,omitempty"`
+	// ObjectTypes defines the provider object types
+	ObjectTypes []string `json:"object_types,omitempty"`
+	// LogEvents defines the log event types
+	LogEvents []int `json:"log_events,omitempty"`
+}
+
+// Validate returns an error if the route is not valid
+func (r *Route) Validate() error {
+	for _, t := range r.EventTypes {
+		switch t {
+		case EventTypeFs, EventTypeProvider, EventTypeLog:
+		default:
+			return fmt.Errorf("unsupported event type %q", t)
+		}
+	}
+	return nil
+}
+
+func matches[T comparable](values []T, value T) bool {
+	return len(values) == 0 || slices.Contains(values, value)
+}
+
+func (r *Route) matchFsEvent(ev *FsEvent) bool {
+	if r == nil {
+		return true
+	}
+	return matches(r.EventTypes, EventTypeFs) && matches(r.Actions, ev.Action) && matches(r.Statuses, ev.Status)
+}
+
+func (r *Route) matchProviderEvent(ev *ProviderEvent) bool {
+	if r == nil {
+		return true
+	}
+	return matches(r.EventTypes, EventTypeProvider) && matches(r.Actions, ev.Action) &&
+		matches(r.ObjectTypes, ev.ObjectType)
+}
+
+func (r *Route) matchLogEvent(ev *LogEvent) bool {
+	if r == nil {
+		return true
+	}
+	return matches(r.EventTypes, EventTypeLog) && matches(r.LogEvents, ev.Event)
+}
+
+// DatabaseSink saves the events in the database. For PostgreSQL the events
+// are inserted using pgx directly, see pgxWriter
+type DatabaseSink struct{}
+
+// Name implements Sink
+func (s *DatabaseSink) Name() string {
+	return "database"
+}
+
+// WriteFsEvent implements Sink
+func (s *DatabaseSink) WriteFsEvent(ev *FsEvent) error {
+	var err error
+	if w := getPgxWriter(); w != nil {
+		err = w.insertFsEvent(ev)
+	} else {
+		sess, cancel := GetDefaultSession()
+		defer cancel()
+
+		err = ev.Create(sess)
+	}
+	if err != nil {
+		logger.AppLogger.Warn("unable to save fs event", "action", ev.Action, "username",
+			ev.Username, "virtual path", ev.VirtualPath, "error", err)
+	}
+	return err
+}
+
+// WriteProviderEvent implements Sink
+func (s *DatabaseSink) WriteProviderEvent(ev *ProviderEvent) error {
+	sess, cancel := GetDefaultSession()
+	defer cancel()
+
+	// the diff is not essential, the event is saved anyway
+	if err := ev.setObjectDiff(sess); err != nil {
+		logger.AppLogger.Warn("unable to compute provider object diff", "object type", ev.ObjectType,
+			"object name", ev.ObjectName, "error", err)
+	}
+	var err error
+	if w := getPgxWriter(); w != nil {
+		err = w.insertProviderEvent(ev)
+	} else {
+		err = ev.Create(sess)
+	}
+	if err != nil {
+		logger.AppLogger.Warn("unable to save provider event", "action", ev.Action, "error", err)
+	}
+	return err
+}
+
+// WriteLogEvent implements Sink
+func (s *DatabaseSink) WriteLogEvent(ev *LogEvent) error {
+	var err error
+	if w := getPgxWriter(); w != nil {
+		err = w.insertLogEvent(ev)
+	} else {
+		sess, cancel := GetDefaultSession()
+		defer cancel()
+
+		err = ev.Create(sess)
+	}
+	if err != nil {
+		logger.AppLogger.Warn("unable to save log event", "event", ev.Event, "error", err)
+	}
+	return err
+}
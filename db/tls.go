@@ -0,0 +1,272 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func handleCustomTLSConfig(config string) error {
+	if config == "" {
+		return nil
+	}
+	tlsConfig, err := GetTLSConfig(config)
+	if err != nil {
+		logger.AppLogger.Error("unable to parse custom tls config", "value", config, "error", err)
+		return err
+	}
+	if err := mysqldriver.RegisterTLSConfig("custom", tlsConfig); err != nil {
+		return fmt.Errorf("unable to register tls config: %v", err)
+	}
+	return nil
+}
+
+// setPgxTLSConfig replaces the TLS settings parsed from the dsn with the
+// custom ones, TLS is required for all the hosts except Unix sockets
+func setPgxTLSConfig(config *pgx.ConnConfig, customTLSConfig string) error {
+	if customTLSConfig == "" {
+		return nil
+	}
+	tlsConfig, err := GetTLSConfig(customTLSConfig)
+	if err != nil {
+		logger.AppLogger.Error("unable to parse custom tls config", "value", customTLSConfig, "error", err)
+		return err
+	}
+	config.TLSConfig = getHostTLSConfig(tlsConfig, config.Host)
+	// the dsn adds a plain text fallback for each host if sslmode is
+	// prefer or allow
+	hosts := map[string]bool{net.JoinHostPort(config.Host, fmt.Sprint(config.Port)): true}
+	fallbacks := make([]*pgconn.FallbackConfig, 0, len(config.Fallbacks))
+	for _, fallback := range config.Fallbacks {
+		hostPort := net.JoinHostPort(fallback.Host, fmt.Sprint(fallback.Port))
+		if hosts[hostPort] {
+			continue
+		}
+		hosts[hostPort] = true
+		fallback.TLSConfig = getHostTLSConfig(tlsConfig, fallback.Host)
+		fallbacks = append(fallbacks, fallback)
+	}
+	config.Fallbacks = fallbacks
+	return nil
+}
+
+func getHostTLSConfig(tlsConfig *tls.Config, host string) *tls.Config {
+	if strings.HasPrefix(host, "/") {
+		return nil
+	}
+	c := tlsConfig.Clone()
+	c.ServerName = host
+	return c
+}
+
+// GetTLSConfig returns a TLS configuration from a string using the URL query
+// syntax. The supported keys are root_cert, client_cert, client_key,
+// root_cert_env, client_cert_env, client_key_env, min_tls_version,
+// cipher_suites and tls_mode. The keys ending with _env set the name of an
+// environment variable with the PEM encoded content, the client certificate
+// and key files are reloaded when they change
+func GetTLSConfig(config string) (*tls.Config, error) {
+	values, err := url.ParseQuery(config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse tls config: %w", err)
+	}
+	tlsMode := values.Get("tls_mode")
+
+	tlsConfig := &tls.Config{}
+	rootCrt, err := getPEM(values, "root_cert")
+	if err != nil {
+		return nil, err
+	}
+	if rootCrt != nil {
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(rootCrt) {
+			return nil, fmt.Errorf("unable to parse root certificate")
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+	clientCert := values.Get("client_cert")
+	clientKey := values.Get("client_key")
+	if clientCert != "" && clientKey != "" {
+		reloader, err := newCertReloader(clientCert, clientKey)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.GetClientCertificate = reloader.getClientCertificate
+	} else {
+		certPEM, err := getEnvPEM(values.Get("client_cert_env"))
+		if err != nil {
+			return nil, err
+		}
+		keyPEM, err := getEnvPEM(values.Get("client_key_env"))
+		if err != nil {
+			return nil, err
+		}
+		if certPEM != nil && keyPEM != nil {
+			tlsCert, err := tls.X509KeyPair(certPEM, keyPEM)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse key pair from environment: %v", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{tlsCert}
+		}
+	}
+	if minVersion := values.Get("min_tls_version"); minVersion != "" {
+		version, ok := tlsVersions[minVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported min TLS version %q", minVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+	if cipherSuites := values.Get("cipher_suites"); cipherSuites != "" {
+		tlsConfig.CipherSuites, err = getCipherSuites(cipherSuites)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if tlsMode == "1" {
+		tlsConfig.InsecureSkipVerify = true
+	}
+	return tlsConfig, nil
+}
+
+// getPEM returns the PEM content for the specified key reading the file
+// or, if the key with the _env suffix is set, the environment variable
+func getPEM(values url.Values, key string) ([]byte, error) {
+	if name := values.Get(key); name != "" {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load %s %q: %v", key, name, err)
+		}
+		return data, nil
+	}
+	return getEnvPEM(values.Get(key + "_env"))
+}
+
+func getEnvPEM(name string) ([]byte, error) {
+	if name == "" {
+		return nil, nil
+	}
+	value := os.Getenv(name)
+	if value == "" {
+		return nil, fmt.Errorf("environment variable %q is not set", name)
+	}
+	return []byte(value), nil
+}
+
+// getCipherSuites returns the IDs for the specified comma separated cipher
+// suite names. The cipher suites only apply to TLS 1.2 and earlier
+func getCipherSuites(names string) ([]uint16, error) {
+	suites := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		suites[suite.Name] = suite.ID
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		suites[suite.Name] = suite.ID
+	}
+	var result []uint16
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		id, ok := suites[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported cipher suite %q", name)
+		}
+		result = append(result, id)
+	}
+	return result, nil
+}
+
+// certReloader loads a client certificate and reloads it, at the next TLS
+// handshake, if the certificate or key file changes. This way short-lived
+// certificates can be renewed without restarting
+type certReloader struct {
+	certPath    string
+	keyPath     string
+	mu          sync.Mutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+func newCertReloader(certPath, keyPath string) (*certReloader, error) {
+	r := &certReloader{
+		certPath: certPath,
+		keyPath:  keyPath,
+	}
+	if err := r.reloadIfChanged(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) getClientCertificate(_ *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.reloadIfChanged(); err != nil {
+		// the key pair may be replaced while we read it, the previous
+		// certificate is used and the reload is retried at the next
+		// handshake
+		logger.AppLogger.Warn("unable to reload client certificate, using the previous one", "error", err)
+	}
+	return r.cert, nil
+}
+
+func (r *certReloader) reloadIfChanged() error {
+	certInfo, err := os.Stat(r.certPath)
+	if err != nil {
+		return fmt.Errorf("unable to stat client certificate %q: %v", r.certPath, err)
+	}
+	keyInfo, err := os.Stat(r.keyPath)
+	if err != nil {
+		return fmt.Errorf("unable to stat client key %q: %v", r.keyPath, err)
+	}
+	if r.cert != nil && certInfo.ModTime().Equal(r.certModTime) && keyInfo.ModTime().Equal(r.keyModTime) {
+		return nil
+	}
+	tlsCert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return fmt.Errorf("unable to load key pair %q, %q: %v", r.certPath, r.keyPath, err)
+	}
+	if r.cert != nil {
+		logger.AppLogger.Info("client certificate reloaded", "cert", r.certPath, "key", r.keyPath)
+	}
+	r.cert = &tlsCert
+	r.certModTime = certInfo.ModTime()
+	r.keyModTime = keyInfo.ModTime()
+	return nil
+}
@@ -0,0 +1,145 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func generateTestCert(t *testing.T, commonName string) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestGetTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certPEM, keyPEM := generateTestCert(t, "client1")
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certPath, certPEM, 0600))
+	require.NoError(t, os.WriteFile(keyPath, keyPEM, 0600))
+
+	tlsConfig, err := GetTLSConfig("root_cert=" + certPath + "&client_cert=" + certPath + "&client_key=" + keyPath +
+		"&min_tls_version=1.2&cipher_suites=TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256" +
+		"&tls_mode=1")
+	require.NoError(t, err)
+	assert.NotNil(t, tlsConfig.RootCAs)
+	assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+		tlsConfig.CipherSuites)
+	assert.True(t, tlsConfig.InsecureSkipVerify)
+	cert, err := tlsConfig.GetClientCertificate(nil)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, "client1", leaf.Subject.CommonName)
+	// the client certificate is reloaded if it changes
+	certPEM, keyPEM = generateTestCert(t, "client2")
+	require.NoError(t, os.WriteFile(certPath, certPEM, 0600))
+	require.NoError(t, os.WriteFile(keyPath, keyPEM, 0600))
+	modTime := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certPath, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyPath, modTime, modTime))
+	cert, err = tlsConfig.GetClientCertificate(nil)
+	require.NoError(t, err)
+	leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, "client2", leaf.Subject.CommonName)
+	// an invalid key pair is ignored and the previous certificate is used
+	require.NoError(t, os.WriteFile(keyPath, []byte("invalid"), 0600))
+	modTime = modTime.Add(time.Minute)
+	require.NoError(t, os.Chtimes(keyPath, modTime, modTime))
+	cert, err = tlsConfig.GetClientCertificate(nil)
+	require.NoError(t, err)
+	leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, "client2", leaf.Subject.CommonName)
+
+	t.Setenv("TEST_ROOT_CERT", string(certPEM))
+	t.Setenv("TEST_CLIENT_CERT", string(certPEM))
+	t.Setenv("TEST_CLIENT_KEY", string(keyPEM))
+	tlsConfig, err = GetTLSConfig("root_cert_env=TEST_ROOT_CERT&client_cert_env=TEST_CLIENT_CERT" +
+		"&client_key_env=TEST_CLIENT_KEY&min_tls_version=1.3")
+	require.NoError(t, err)
+	assert.NotNil(t, tlsConfig.RootCAs)
+	assert.Len(t, tlsConfig.Certificates, 1)
+	assert.Nil(t, tlsConfig.GetClientCertificate)
+	assert.Equal(t, uint16(tls.VersionTLS13), tlsConfig.MinVersion)
+	assert.False(t, tlsConfig.InsecureSkipVerify)
+
+	for _, config := range []string{
+		"min_tls_version=1.4",
+		"cipher_suites=TLS_UNKNOWN",
+		"root_cert_env=TEST_MISSING_ENV",
+		"client_cert_env=TEST_CLIENT_CERT&client_key_env=TEST_ROOT_CERT",
+		"client_cert=" + certPath + "&client_key=" + keyPath,
+		"root_cert=" + filepath.Join(dir, "missing.pem"),
+		"%gh&%ij",
+	} {
+		_, err = GetTLSConfig(config)
+		assert.Error(t, err, config)
+	}
+}
+
+func TestPgxTLSConfig(t *testing.T) {
+	config, err := pgx.ParseConfig("host=host1,host2 port=5432,5433 user=sftpgo sslmode=prefer")
+	require.NoError(t, err)
+	require.NoError(t, setPgxTLSConfig(config, "min_tls_version=1.3"))
+	require.NotNil(t, config.TLSConfig)
+	assert.Equal(t, "host1", config.TLSConfig.ServerName)
+	assert.Equal(t, uint16(tls.VersionTLS13), config.TLSConfig.MinVersion)
+	// the plain text fallbacks are removed
+	require.Len(t, config.Fallbacks, 1)
+	assert.Equal(t, "host2", config.Fallbacks[0].Host)
+	require.NotNil(t, config.Fallbacks[0].TLSConfig)
+	assert.Equal(t, "host2", config.Fallbacks[0].TLSConfig.ServerName)
+
+	config, err = pgx.ParseConfig("host=/var/run/postgresql user=sftpgo sslmode=disable")
+	require.NoError(t, err)
+	require.NoError(t, setPgxTLSConfig(config, "min_tls_version=1.3"))
+	assert.Nil(t, config.TLSConfig)
+	assert.Error(t, setPgxTLSConfig(config, "min_tls_version=1.1.1"))
+}
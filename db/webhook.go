@@ -0,0 +1,109 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"time"
+
+	"github.com/rs/xid"
+	"gorm.io/gorm"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+// WebhookDeadLetter defines a webhook delivery that permanently failed
+type WebhookDeadLetter struct {
+	ID        string `json:"id" gorm:"primaryKey"`
+	Timestamp int64  `json:"timestamp"`
+	URL       string `json:"url"`
+	EventType string `json:"event_type"`
+	EventID   string `json:"event_id"`
+	Payload   []byte `json:"payload"`
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"last_error"`
+}
+
+// TableName defines the database table name
+func (d *WebhookDeadLetter) TableName() string {
+	return tableName("webhook_dead_letters")
+}
+
+// BeforeCreate implements gorm hook
+func (d *WebhookDeadLetter) BeforeCreate(_ *gorm.DB) error {
+	d.ID = xid.New().String()
+	return nil
+}
+
+// Create persists the object
+func (d *WebhookDeadLetter) Create(tx *gorm.DB) error {
+	return tx.Create(d).Error
+}
+
+// SaveWebhookDeadLetter persists the given dead letter using the default session
+func SaveWebhookDeadLetter(d *WebhookDeadLetter) error {
+	sess, cancel := GetDefaultSession()
+	defer cancel()
+
+	return d.Create(sess)
+}
+
+// GetWebhookDeadLetters returns at most limit dead letters with an ID greater
+// than the specified one, ordered by ID. If ids is not empty only the
+// dead letters with the specified IDs are returned
+func GetWebhookDeadLetters(after string, limit int, ids []string) ([]WebhookDeadLetter, error) {
+	sess, cancel := GetDefaultSession()
+	defer cancel()
+
+	var result []WebhookDeadLetter
+	sess = sess.Where("id > ?", after)
+	if len(ids) > 0 {
+		sess = sess.Where("id IN ?", ids)
+	}
+	err := sess.Order("id").Limit(limit).Find(&result).Error
+	return result, err
+}
+
+// UpdateWebhookDeadLetter updates the delivery status for the given dead letter
+func UpdateWebhookDeadLetter(d *WebhookDeadLetter) error {
+	sess, cancel := GetDefaultSession()
+	defer cancel()
+
+	return sess.Model(d).Updates(map[string]any{
+		"timestamp":  d.Timestamp,
+		"attempts":   d.Attempts,
+		"last_error": d.LastError,
+	}).Error
+}
+
+// DeleteWebhookDeadLetter deletes the dead letter with the given ID
+func DeleteWebhookDeadLetter(id string) error {
+	sess, cancel := GetDefaultSession()
+	defer cancel()
+
+	return sess.Where("id = ?", id).Delete(&WebhookDeadLetter{}).Error
+}
+
+func cleanupWebhookDeadLetters(timestamp time.Time) error {
+	logger.AppLogger.Debug("removing webhook dead letters", "timestamp", timestamp)
+	sess, cancel := getSessionWithTimeout(20 * time.Minute)
+	defer cancel()
+
+	sess = sess.Where("timestamp < ?", timestamp.UnixNano()).Delete(&WebhookDeadLetter{})
+	err := sess.Error
+	if err == nil {
+		logger.AppLogger.Debug("webhook dead letters deleted", "num", sess.RowsAffected)
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookDeadLetters(t *testing.T) {
+	var ids []string
+	for i := 0; i < 3; i++ {
+		d := &WebhookDeadLetter{
+			Timestamp: time.Now().UnixNano(),
+			URL:       "https://example.com/hook",
+			EventType: "fs",
+			EventID:   "event id",
+			Payload:   []byte(`{"id":"event id"}`),
+			Attempts:  3,
+			LastError: "unexpected status code 500",
+		}
+		require.NoError(t, SaveWebhookDeadLetter(d))
+		assert.NotEmpty(t, d.ID)
+		ids = append(ids, d.ID)
+	}
+
+	deadLetters, err := GetWebhookDeadLetters("", 2, nil)
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 2)
+	assert.Equal(t, ids[0], deadLetters[0].ID)
+	assert.Equal(t, ids[1], deadLetters[1].ID)
+	assert.Equal(t, []byte(`{"id":"event id"}`), deadLetters[0].Payload)
+	deadLetters, err = GetWebhookDeadLetters(ids[1], 2, nil)
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, ids[2], deadLetters[0].ID)
+	deadLetters, err = GetWebhookDeadLetters("", 10, []string{ids[1]})
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, ids[1], deadLetters[0].ID)
+
+	d := deadLetters[0]
+	d.Attempts = 5
+	d.LastError = "connection refused"
+	require.NoError(t, UpdateWebhookDeadLetter(&d))
+	deadLetters, err = GetWebhookDeadLetters("", 10, []string{ids[1]})
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, 5, deadLetters[0].Attempts)
+	assert.Equal(t, "connection refused", deadLetters[0].LastError)
+
+	require.NoError(t, DeleteWebhookDeadLetter(ids[0]))
+	deadLetters, err = GetWebhookDeadLetters("", 10, nil)
+	require.NoError(t, err)
+	assert.Len(t, deadLetters, 2)
+
+	Cleanup(time.Now().Add(1 * time.Hour))
+	deadLetters, err = GetWebhookDeadLetters("", 10, nil)
+	require.NoError(t, err)
+	assert.Len(t, deadLetters, 0)
+}
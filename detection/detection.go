@@ -0,0 +1,347 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package detection raises alerts when the events received within a sliding
+// window cross the configured thresholds, for example for brute force attacks
+// or mass deletions
+package detection
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+// Supported rules
+const (
+	RuleIPLoginFailures       = "ip_login_failures"
+	RuleUsernameLoginFailures = "username_login_failures"
+)
+
+// loginFailureEvents are the log events counted as login failures:
+// login_failed and login_no_user
+var loginFailureEvents = []int{1, 2}
+
+// AlertWriter defines a destination for the alerts in addition to the
+// database, for example a webhook sink
+type AlertWriter interface {
+	WriteAlert(alert *db.Alert) error
+}
+
+// Config defines the configuration for the detection engine
+type Config struct {
+	InstanceID string
+	// Window defines the sliding window for the thresholds
+	Window time.Duration
+	// IPThreshold defines the number of login failures from the same IP
+	// within the window that raise an alert, 0 means disabled
+	IPThreshold int
+	// UsernameThreshold defines the number of login failures for the same
+	// username within the window that raise an alert, 0 means disabled
+	UsernameThreshold int
+	// FsRules defines the rules for the filesystem events
+	FsRules      []FsRule
+	AlertWriters []AlertWriter
+}
+
+func (c *Config) validate() error {
+	if c.IPThreshold < 0 || c.UsernameThreshold < 0 {
+		return errors.New("the thresholds cannot be negative")
+	}
+	if c.IPThreshold == 0 && c.UsernameThreshold == 0 && len(c.FsRules) == 0 {
+		return errors.New("at least a threshold or a filesystem rule is required")
+	}
+	if (c.IPThreshold > 0 || c.UsernameThreshold > 0) && c.Window <= 0 {
+		return fmt.Errorf("invalid window %s", c.Window)
+	}
+	return nil
+}
+
+// Engine analyzes the log events and raises alerts for the login failures, and
+// the filesystem events and raises alerts for the matching filesystem rules.
+// It implements db.Sink, so it receives the events like the other sinks
+type Engine struct {
+	config        Config
+	mu            sync.Mutex
+	ipRule        *thresholdRule
+	userRule      *thresholdRule
+	fsRules       []*fsRule
+	saveAlert     func(*db.Alert) error
+	uploadedBytes func(string, time.Time, time.Time) (int64, error)
+}
+
+// NewEngine returns a new detection engine. The counters are restored from
+// the events and alerts stored within the windows, so they survive restarts
+func NewEngine(config Config) (*Engine, error) {
+	e, err := newEngine(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.restore(); err != nil {
+		return nil, fmt.Errorf("unable to restore the detection counters: %w", err)
+	}
+	return e, nil
+}
+
+func newEngine(config Config) (*Engine, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	e := &Engine{
+		config:        config,
+		saveAlert:     db.SaveAlert,
+		uploadedBytes: db.GetUserUploadedBytes,
+	}
+	if config.IPThreshold > 0 {
+		e.ipRule = newThresholdRule(RuleIPLoginFailures, "login failures from IP", config.Window,
+			config.IPThreshold)
+	}
+	if config.UsernameThreshold > 0 {
+		e.userRule = newThresholdRule(RuleUsernameLoginFailures, "login failures for user", config.Window,
+			config.UsernameThreshold)
+	}
+	names := []string{RuleIPLoginFailures, RuleUsernameLoginFailures}
+	for _, rule := range config.FsRules {
+		r, err := newFsRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(names, r.Name) {
+			return nil, fmt.Errorf("duplicate rule name %q", r.Name)
+		}
+		names = append(names, r.Name)
+		e.fsRules = append(e.fsRules, r)
+	}
+	return e, nil
+}
+
+func (e *Engine) rules() []*thresholdRule {
+	var rules []*thresholdRule
+	if e.ipRule != nil {
+		rules = append(rules, e.ipRule)
+	}
+	if e.userRule != nil {
+		rules = append(rules, e.userRule)
+	}
+	return rules
+}
+
+// windows returns the sliding windows for each rule name
+func (e *Engine) windows() map[string]*slidingWindows {
+	result := make(map[string]*slidingWindows)
+	for _, rule := range e.rules() {
+		result[rule.name] = &rule.slidingWindows
+	}
+	for _, rule := range e.fsRules {
+		result[rule.Name] = &rule.slidingWindows
+	}
+	return result
+}
+
+func (e *Engine) restore() error {
+	now := time.Now()
+	var logEvents []db.LogEvent
+	var fsEvents []db.FsEvent
+	var err error
+	if len(e.rules()) > 0 {
+		if logEvents, err = db.GetLogEventsSince(loginFailureEvents, now.Add(-e.config.Window)); err != nil {
+			return err
+		}
+	}
+	var fsWindow time.Duration
+	for _, rule := range e.fsRules {
+		fsWindow = max(fsWindow, rule.window)
+	}
+	if len(e.fsRules) > 0 {
+		if fsEvents, err = db.GetFsEventsSince(fsRuleEvents, now.Add(-fsWindow)); err != nil {
+			return err
+		}
+	}
+	windows := e.windows()
+	var names []string
+	for name := range windows {
+		names = append(names, name)
+	}
+	alerts, err := db.GetAlerts(db.AlertFilter{From: now.Add(-max(e.config.Window, fsWindow)), Rules: names})
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.restoreFs(fsEvents)
+	for idx := range logEvents {
+		e.observe(&logEvents[idx])
+	}
+	for _, alert := range alerts {
+		if s, ok := windows[alert.Rule]; ok {
+			w := s.getSubject(alert.Subject)
+			w.lastAlert = max(w.lastAlert, alert.Timestamp)
+		}
+	}
+	logger.AppLogger.Debug("detection counters restored", "log events", len(logEvents),
+		"fs events", len(fsEvents), "alerts", len(alerts))
+	return nil
+}
+
+// restoreFs records the specified filesystem events, the alerts are not raised
+// again. The upload baselines are not read, so the restore doesn't query the
+// database for each upload, they are read for the first events received
+func (e *Engine) restoreFs(events []db.FsEvent) {
+	for idx := range events {
+		for _, rule := range e.fsRules {
+			if rule.matches(&events[idx]) {
+				rule.observe(&events[idx], nil, e.config.InstanceID)
+			}
+		}
+	}
+}
+
+// observe records a login failure and returns the alerts to raise
+func (e *Engine) observe(ev *db.LogEvent) []*db.Alert {
+	var alerts []*db.Alert
+	for _, rule := range e.rules() {
+		subject := ev.IP
+		if rule == e.userRule {
+			subject = ev.Username
+		}
+		if subject == "" {
+			continue
+		}
+		if w, ok := rule.add(subject, ev.Timestamp, ev.ID); ok {
+			alerts = append(alerts, &db.Alert{
+				Timestamp: ev.Timestamp,
+				Rule:      rule.name,
+				Subject:   subject,
+				Events:    len(w.entries),
+				Message: fmt.Sprintf("%d %s %s within %s", len(w.entries), rule.description, subject,
+					e.config.Window),
+				InstanceID: e.config.InstanceID,
+				Severity:   db.SeverityHigh,
+				EventIDs:   w.eventIDs(),
+			})
+		}
+	}
+	return alerts
+}
+
+// observeFs records a filesystem event and returns the alerts to raise. The
+// upload baselines are read from the database without holding the lock
+func (e *Engine) observeFs(ev *db.FsEvent) ([]*db.Alert, error) {
+	var rules []*fsRule
+	e.mu.Lock()
+	for _, rule := range e.fsRules {
+		if rule.matches(ev) && rule.needsBaseline(ev) {
+			rules = append(rules, rule)
+		}
+	}
+	e.mu.Unlock()
+
+	var errs []error
+	baselines := make(map[*fsRule]int64)
+	for _, rule := range rules {
+		baseline, err := rule.getBaseline(ev, e.uploadedBytes)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		baselines[rule] = baseline
+	}
+
+	var alerts []*db.Alert
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, rule := range e.fsRules {
+		if !rule.matches(ev) {
+			continue
+		}
+		if alert := rule.observe(ev, baselines, e.config.InstanceID); alert != nil {
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts, errors.Join(errs...)
+}
+
+func (e *Engine) raiseAll(alerts []*db.Alert) error {
+	var errs []error
+	for _, alert := range alerts {
+		errs = append(errs, e.raise(alert))
+	}
+	return errors.Join(errs...)
+}
+
+func (e *Engine) raise(alert *db.Alert) error {
+	logger.AppLogger.Warn("alert raised", "rule", alert.Rule, "severity", alert.Severity, "subject", alert.Subject,
+		"events", alert.Events)
+	var errs []error
+	if err := e.saveAlert(alert); err != nil {
+		errs = append(errs, fmt.Errorf("unable to save alert: %w", err))
+	}
+	for _, w := range e.config.AlertWriters {
+		if err := w.WriteAlert(alert); err != nil {
+			errs = append(errs, fmt.Errorf("unable to write alert: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Name implements db.Sink
+func (e *Engine) Name() string {
+	return "detection"
+}
+
+// WriteFsEvent implements db.Sink
+func (e *Engine) WriteFsEvent(ev *db.FsEvent) error {
+	if len(e.fsRules) == 0 || !slices.Contains(fsRuleEvents, ev.Action) {
+		return nil
+	}
+	alerts, err := e.observeFs(ev)
+	return errors.Join(err, e.raiseAll(alerts))
+}
+
+// WriteProviderEvent implements db.Sink
+func (e *Engine) WriteProviderEvent(_ *db.ProviderEvent) error {
+	return nil
+}
+
+// WriteLogEvent implements db.Sink
+func (e *Engine) WriteLogEvent(ev *db.LogEvent) error {
+	if !slices.Contains(loginFailureEvents, ev.Event) {
+		return nil
+	}
+	e.mu.Lock()
+	alerts := e.observe(ev)
+	e.mu.Unlock()
+
+	return e.raiseAll(alerts)
+}
+
+// WriteAlerts writes the specified alerts as a table
+func WriteAlerts(w io.Writer, alerts []db.Alert) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tRULE\tSEVERITY\tSUBJECT\tEVENTS\tINSTANCE\tMESSAGE")
+	for _, a := range alerts {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", time.Unix(0, a.Timestamp).UTC().Format(time.RFC3339),
+			a.Rule, a.Severity, a.Subject, a.Events, a.InstanceID, a.Message)
+	}
+	return tw.Flush()
+}
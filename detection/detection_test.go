@@ -0,0 +1,164 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package detection
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+)
+
+type testAlertWriter struct {
+	alerts []*db.Alert
+	err    error
+}
+
+func (w *testAlertWriter) WriteAlert(alert *db.Alert) error {
+	w.alerts = append(w.alerts, alert)
+	return w.err
+}
+
+func newTestEngine(t *testing.T, config Config) (*Engine, *[]*db.Alert) {
+	var saved []*db.Alert
+	e, err := newEngine(config)
+	require.NoError(t, err)
+	e.saveAlert = func(alert *db.Alert) error {
+		saved = append(saved, alert)
+		return nil
+	}
+	return e, &saved
+}
+
+func TestValidation(t *testing.T) {
+	_, err := NewEngine(Config{Window: time.Minute})
+	assert.Error(t, err)
+	_, err = NewEngine(Config{Window: time.Minute, IPThreshold: -1, UsernameThreshold: 1})
+	assert.Error(t, err)
+	_, err = NewEngine(Config{IPThreshold: 1})
+	assert.Error(t, err)
+	_, err = NewEngine(Config{FsRules: []FsRule{{Name: RuleIPLoginFailures, Type: FsRuleMassDeletion,
+		Window: "1m", Threshold: 1}}})
+	assert.ErrorContains(t, err, "duplicate rule name")
+	_, err = NewEngine(Config{FsRules: []FsRule{{Name: "rule", Type: FsRuleMassDeletion, Window: "1m"}}})
+	assert.Error(t, err)
+}
+
+func TestThresholdRule(t *testing.T) {
+	r := newThresholdRule("rule", "events", time.Minute, 3)
+	ts := time.Now().UnixNano()
+	minute := int64(time.Minute)
+
+	_, ok := r.add("a", ts, "1")
+	assert.False(t, ok)
+	// the first event is outside the window
+	_, ok = r.add("a", ts+minute, "2")
+	assert.False(t, ok)
+	_, ok = r.add("a", ts+minute+1, "3")
+	assert.False(t, ok)
+	// a retried event is counted once
+	_, ok = r.add("a", ts+minute+1, "3")
+	assert.False(t, ok)
+	assert.Len(t, r.subjects["a"].entries, 2)
+	w, ok := r.add("a", ts+minute+2, "4")
+	assert.True(t, ok)
+	assert.Len(t, w.entries, 3)
+	assert.Equal(t, []string{"2", "3", "4"}, w.eventIDs())
+	// a single alert is raised within the window
+	_, ok = r.add("a", ts+minute+3, "")
+	assert.False(t, ok)
+	assert.Len(t, r.subjects["a"].entries, 3)
+	_, ok = r.add("b", ts+minute+3, "")
+	assert.False(t, ok)
+	// a new alert is raised after the window
+	_, ok = r.add("a", ts+2*minute+2, "")
+	assert.False(t, ok)
+	_, ok = r.add("a", ts+2*minute+3, "")
+	assert.False(t, ok)
+	_, ok = r.add("a", ts+2*minute+4, "")
+	assert.True(t, ok)
+	// the subjects without recent events are removed
+	r.add("c", ts+5*minute, "")
+	assert.Len(t, r.subjects, 1)
+	assert.Contains(t, r.subjects, "c")
+}
+
+func TestEngine(t *testing.T) {
+	writer := &testAlertWriter{}
+	e, saved := newTestEngine(t, Config{
+		InstanceID:        "sftpgo1",
+		Window:            10 * time.Minute,
+		IPThreshold:       3,
+		UsernameThreshold: 2,
+		AlertWriters:      []AlertWriter{writer},
+	})
+	assert.Equal(t, "detection", e.Name())
+	assert.NoError(t, e.WriteFsEvent(&db.FsEvent{}))
+	assert.NoError(t, e.WriteProviderEvent(&db.ProviderEvent{}))
+
+	ts := time.Now().UnixNano()
+	// successful logins are ignored
+	require.NoError(t, e.WriteLogEvent(&db.LogEvent{Timestamp: ts, Event: 5, Username: "user1", IP: "10.0.0.1"}))
+	require.NoError(t, e.WriteLogEvent(&db.LogEvent{Timestamp: ts, Event: 1, Username: "user1", IP: "10.0.0.1"}))
+	require.NoError(t, e.WriteLogEvent(&db.LogEvent{Timestamp: ts + 1, Event: 2, Username: "user2", IP: "10.0.0.1"}))
+	assert.Empty(t, *saved)
+	require.NoError(t, e.WriteLogEvent(&db.LogEvent{ID: "3", Timestamp: ts + 2, Event: 1, Username: "user1",
+		IP: "10.0.0.1"}))
+	require.Len(t, *saved, 2)
+	assert.Equal(t, &db.Alert{
+		Timestamp:  ts + 2,
+		Rule:       RuleIPLoginFailures,
+		Subject:    "10.0.0.1",
+		Events:     3,
+		Message:    "3 login failures from IP 10.0.0.1 within 10m0s",
+		InstanceID: "sftpgo1",
+		Severity:   db.SeverityHigh,
+		EventIDs:   []string{"3"},
+	}, (*saved)[0])
+	assert.Equal(t, RuleUsernameLoginFailures, (*saved)[1].Rule)
+	assert.Equal(t, "user1", (*saved)[1].Subject)
+	assert.Equal(t, "2 login failures for user user1 within 10m0s", (*saved)[1].Message)
+	assert.Equal(t, *saved, writer.alerts)
+
+	// events without IP or username are only counted for the other subject
+	writer.err = errors.New("write error")
+	require.NoError(t, e.WriteLogEvent(&db.LogEvent{Timestamp: ts + 3, Event: 1, Username: "user3"}))
+	assert.Error(t, e.WriteLogEvent(&db.LogEvent{Timestamp: ts + 4, Event: 1, Username: "user3"}))
+	require.Len(t, *saved, 3)
+	assert.Equal(t, "user3", (*saved)[2].Subject)
+}
+
+func TestWriteAlerts(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteAlerts(&buf, []db.Alert{
+		{
+			Timestamp:  time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC).UnixNano(),
+			Rule:       RuleIPLoginFailures,
+			Subject:    "10.0.0.1",
+			Events:     10,
+			Message:    "10 login failures from IP 10.0.0.1 within 10m0s",
+			InstanceID: "sftpgo1",
+			Severity:   db.SeverityHigh,
+		},
+	}))
+	assert.Equal(t, `TIME                  RULE               SEVERITY  SUBJECT   EVENTS  INSTANCE  MESSAGE
+2026-03-14T10:00:00Z  ip_login_failures  high      10.0.0.1  10      sftpgo1   10 login failures from IP 10.0.0.1 within 10m0s
+`, buf.String())
+}
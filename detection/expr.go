@@ -0,0 +1,196 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package detection
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/google/cel-go/cel"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+)
+
+// exprVariables defines the variables available in the rule expressions for
+// each event type, they use the same names as the JSON representation
+var exprVariables = map[string]map[string]*cel.Type{
+	db.EventTypeFs: {
+		"id":                  cel.StringType,
+		"timestamp":           cel.IntType,
+		"action":              cel.StringType,
+		"username":            cel.StringType,
+		"fs_path":             cel.StringType,
+		"fs_target_path":      cel.StringType,
+		"virtual_path":        cel.StringType,
+		"virtual_target_path": cel.StringType,
+		"ssh_cmd":             cel.StringType,
+		"file_size":           cel.IntType,
+		"elapsed":             cel.IntType,
+		"status":              cel.IntType,
+		"protocol":            cel.StringType,
+		"ip":                  cel.StringType,
+		"country_code":        cel.StringType,
+		"city":                cel.StringType,
+		"asn":                 cel.IntType,
+		"session_id":          cel.StringType,
+		"fs_provider":         cel.IntType,
+		"bucket":              cel.StringType,
+		"endpoint":            cel.StringType,
+		"open_flags":          cel.IntType,
+		"role":                cel.StringType,
+		"instance_id":         cel.StringType,
+	},
+	db.EventTypeProvider: {
+		"id":           cel.StringType,
+		"timestamp":    cel.IntType,
+		"action":       cel.StringType,
+		"username":     cel.StringType,
+		"ip":           cel.StringType,
+		"country_code": cel.StringType,
+		"city":         cel.StringType,
+		"asn":          cel.IntType,
+		"object_type":  cel.StringType,
+		"object_name":  cel.StringType,
+		// object_data is the parsed JSON object, null if it is not valid JSON
+		"object_data": cel.DynType,
+		"role":        cel.StringType,
+		"instance_id": cel.StringType,
+	},
+	db.EventTypeLog: {
+		"id":           cel.StringType,
+		"timestamp":    cel.IntType,
+		"event":        cel.IntType,
+		"event_name":   cel.StringType,
+		"protocol":     cel.StringType,
+		"username":     cel.StringType,
+		"ip":           cel.StringType,
+		"country_code": cel.StringType,
+		"city":         cel.StringType,
+		"asn":          cel.IntType,
+		"message":      cel.StringType,
+		"role":         cel.StringType,
+		"instance_id":  cel.StringType,
+	},
+}
+
+// compileExpr compiles a boolean expression for the specified event type
+func compileExpr(eventType, expr string) (cel.Program, error) {
+	variables, ok := exprVariables[eventType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported event type %q", eventType)
+	}
+	var opts []cel.EnvOption
+	for _, name := range slices.Sorted(maps.Keys(variables)) {
+		opts = append(opts, cel.Variable(name, variables[name]))
+	}
+	env, err := cel.NewEnv(opts...)
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(expr)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("the expression must return a bool, not %s", ast.OutputType())
+	}
+	return env.Program(ast)
+}
+
+// evalExpr returns true if the expression matches the specified variables
+func evalExpr(program cel.Program, vars map[string]any) (bool, error) {
+	out, _, err := program.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	result, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("unexpected expression result %v", out.Value())
+	}
+	return result, nil
+}
+
+func fsEventVars(ev *db.FsEvent) map[string]any {
+	return map[string]any{
+		"id":                  ev.ID,
+		"timestamp":           ev.Timestamp,
+		"action":              ev.Action,
+		"username":            ev.Username,
+		"fs_path":             ev.FsPath,
+		"fs_target_path":      ev.FsTargetPath,
+		"virtual_path":        ev.VirtualPath,
+		"virtual_target_path": ev.VirtualTargetPath,
+		"ssh_cmd":             ev.SSHCmd,
+		"file_size":           ev.FileSize,
+		"elapsed":             ev.Elapsed,
+		"status":              int64(ev.Status),
+		"protocol":            ev.Protocol,
+		"ip":                  ev.IP,
+		"country_code":        ev.CountryCode,
+		"city":                ev.City,
+		"asn":                 int64(ev.ASN),
+		"session_id":          ev.SessionID,
+		"fs_provider":         int64(ev.FsProvider),
+		"bucket":              ev.Bucket,
+		"endpoint":            ev.Endpoint,
+		"open_flags":          int64(ev.OpenFlags),
+		"role":                ev.Role,
+		"instance_id":         ev.InstanceID,
+	}
+}
+
+func providerEventVars(ev *db.ProviderEvent) map[string]any {
+	var objectData any
+	if len(ev.ObjectData) > 0 {
+		if err := json.Unmarshal(ev.ObjectData, &objectData); err != nil {
+			objectData = nil
+		}
+	}
+	return map[string]any{
+		"id":           ev.ID,
+		"timestamp":    ev.Timestamp,
+		"action":       ev.Action,
+		"username":     ev.Username,
+		"ip":           ev.IP,
+		"country_code": ev.CountryCode,
+		"city":         ev.City,
+		"asn":          int64(ev.ASN),
+		"object_type":  ev.ObjectType,
+		"object_name":  ev.ObjectName,
+		"object_data":  objectData,
+		"role":         ev.Role,
+		"instance_id":  ev.InstanceID,
+	}
+}
+
+func logEventVars(ev *db.LogEvent) map[string]any {
+	return map[string]any{
+		"id":           ev.ID,
+		"timestamp":    ev.Timestamp,
+		"event":        int64(ev.Event),
+		"event_name":   db.GetLogEventName(ev.Event),
+		"protocol":     ev.Protocol,
+		"username":     ev.Username,
+		"ip":           ev.IP,
+		"country_code": ev.CountryCode,
+		"city":         ev.City,
+		"asn":          int64(ev.ASN),
+		"message":      ev.Message,
+		"role":         ev.Role,
+		"instance_id":  ev.InstanceID,
+	}
+}
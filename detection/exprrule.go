@@ -0,0 +1,572 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package detection
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/cel-go/cel"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+// Supported outputs for the expression rules
+const (
+	// OutputAlerts saves the alerts in the alerts table
+	OutputAlerts = "alerts"
+	// OutputLog writes the alerts to the plugin log
+	OutputLog = "log"
+)
+
+var (
+	exprRuleOutputs = []string{OutputAlerts, OutputLog}
+	eventTypes      = []string{db.EventTypeFs, db.EventTypeProvider, db.EventTypeLog}
+)
+
+// ExprRule defines an alert rule as an expression over the event fields
+type ExprRule struct {
+	// Name identifies the rule in the alerts, it must be unique
+	Name string `json:"name"`
+	// EventType defines the events the rule applies to: fs, provider, log
+	EventType string `json:"event_type"`
+	// Condition is a CEL expression returning a bool, for example
+	// `action == "upload" && status != 1 && protocol == "FTP"`
+	Condition   string `json:"condition"`
+	Description string `json:"description,omitempty"`
+	Severity    string `json:"severity,omitempty"`
+	// GroupBy defines the event fields used to group the matching events,
+	// empty means a single group
+	GroupBy []string `json:"group_by,omitempty"`
+	// Window defines the aggregation window as Go duration, empty means no
+	// aggregation, each matching event raises an alert
+	Window string `json:"window,omitempty"`
+	// Threshold defines the number of matching events for the same group
+	// within the window that raise an alert. Default: 1
+	Threshold int `json:"threshold,omitempty"`
+	// Suppression defines the minimum interval between two alerts for the
+	// same group as Go duration. Default: the window
+	Suppression  string   `json:"suppression,omitempty"`
+	Outputs      []string `json:"outputs,omitempty"`
+	CallbackURLs []string `json:"callback_urls,omitempty"`
+}
+
+// LoadExprRules loads the expression rules from the specified JSON file
+func LoadExprRules(path string) ([]ExprRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []ExprRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("unable to parse the rules file %q: %w", path, err)
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no rule defined in %q", path)
+	}
+	var names []string
+	for _, rule := range rules {
+		if _, err := newExprRule(rule); err != nil {
+			return nil, err
+		}
+		if slices.Contains(names, rule.Name) {
+			return nil, fmt.Errorf("duplicate rule name %q", rule.Name)
+		}
+		names = append(names, rule.Name)
+	}
+	return rules, nil
+}
+
+// exprRule is the compiled ExprRule with its state
+type exprRule struct {
+	ExprRule
+	program     cel.Program
+	window      time.Duration
+	suppression time.Duration
+	callbacks   []AlertWriter
+	groups      map[string]*subjectWindow
+	lastSweep   int64
+	// matches and errors count the matching events and the evaluation errors
+	matches int
+	errors  int
+}
+
+func newExprRule(rule ExprRule) (*exprRule, error) {
+	if rule.Name == "" {
+		return nil, errors.New("the rule name is required")
+	}
+	if !slices.Contains(eventTypes, rule.EventType) {
+		return nil, fmt.Errorf("invalid event type %q for rule %q, supported types: %s", rule.EventType,
+			rule.Name, strings.Join(eventTypes, ", "))
+	}
+	program, err := compileExpr(rule.EventType, rule.Condition)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition for rule %q: %w", rule.Name, err)
+	}
+	if rule.Severity == "" {
+		rule.Severity = db.SeverityMedium
+	}
+	if !slices.Contains(severities, rule.Severity) {
+		return nil, fmt.Errorf("invalid severity %q for rule %q, supported severities: %s", rule.Severity,
+			rule.Name, strings.Join(severities, ", "))
+	}
+	for _, field := range rule.GroupBy {
+		if t, ok := exprVariables[rule.EventType][field]; !ok || t == cel.DynType {
+			return nil, fmt.Errorf("invalid group by field %q for rule %q", field, rule.Name)
+		}
+	}
+	r := &exprRule{
+		ExprRule: rule,
+		program:  program,
+		groups:   make(map[string]*subjectWindow),
+	}
+	if rule.Window != "" {
+		if r.window, err = time.ParseDuration(rule.Window); err != nil || r.window < 0 {
+			return nil, fmt.Errorf("invalid window %q for rule %q", rule.Window, rule.Name)
+		}
+	}
+	if r.Threshold == 0 {
+		r.Threshold = 1
+	}
+	if r.Threshold < 0 || (r.Threshold > 1 && r.window == 0) {
+		return nil, fmt.Errorf("rule %q: the threshold must be greater than 0, a window is required for thresholds greater than 1",
+			rule.Name)
+	}
+	r.suppression = r.window
+	if rule.Suppression != "" {
+		if r.suppression, err = time.ParseDuration(rule.Suppression); err != nil || r.suppression < 0 {
+			return nil, fmt.Errorf("invalid suppression %q for rule %q", rule.Suppression, rule.Name)
+		}
+	}
+	if len(r.Outputs) == 0 && len(r.CallbackURLs) == 0 {
+		r.Outputs = []string{OutputAlerts}
+	}
+	for _, output := range r.Outputs {
+		if !slices.Contains(exprRuleOutputs, output) {
+			return nil, fmt.Errorf("invalid output %q for rule %q, supported outputs: %s", output, rule.Name,
+				strings.Join(exprRuleOutputs, ", "))
+		}
+	}
+	for _, callback := range r.CallbackURLs {
+		u, err := url.Parse(callback)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid callback URL %q for rule %q", callback, rule.Name)
+		}
+	}
+	return r, nil
+}
+
+func (r *exprRule) groupKey(vars map[string]any) string {
+	var parts []string
+	for _, field := range r.GroupBy {
+		parts = append(parts, fmt.Sprintf("%s=%v", field, vars[field]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// add records a matching event for the group. It returns the group window and
+// true if the threshold is reached and no alert was raised for the group
+// within the suppression interval. Events already within the window, for
+// example retried notifications, are ignored
+func (r *exprRule) add(key string, entry windowEntry) (*subjectWindow, bool) {
+	r.sweep(entry.timestamp)
+	w, ok := r.groups[key]
+	if !ok {
+		w = &subjectWindow{}
+		r.groups[key] = w
+	}
+	if !w.add(entry, entry.timestamp-int64(r.window), r.Threshold) {
+		return w, false
+	}
+	if len(w.entries) < r.Threshold || w.lastAlert > entry.timestamp-int64(r.suppression) {
+		return w, false
+	}
+	w.lastAlert = entry.timestamp
+	return w, true
+}
+
+// sweep removes the groups without events within the window and alerts within
+// the suppression interval, it runs at most once per minute or retention
+func (r *exprRule) sweep(now int64) {
+	retention := int64(max(r.window, r.suppression))
+	if now-r.lastSweep < max(retention, int64(time.Minute)) {
+		return
+	}
+	r.lastSweep = now
+	for key, w := range r.groups {
+		if w.lastAlert <= now-int64(r.suppression) &&
+			(len(w.entries) == 0 || w.entries[len(w.entries)-1].timestamp <= now-int64(r.window)) {
+			delete(r.groups, key)
+		}
+	}
+}
+
+func (r *exprRule) newAlert(key string, w *subjectWindow, timestamp int64, instanceID string) *db.Alert {
+	message := r.Description
+	if message == "" {
+		message = "events matching rule " + r.Name
+	}
+	message = fmt.Sprintf("%s: %d events", message, len(w.entries))
+	if r.window > 0 {
+		message += " within " + r.window.String()
+	}
+	if key != "" {
+		message += " for " + key
+	}
+	return &db.Alert{
+		Timestamp:  timestamp,
+		Rule:       r.Name,
+		Subject:    key,
+		Events:     len(w.entries),
+		Message:    message,
+		InstanceID: instanceID,
+		Severity:   r.Severity,
+		EventIDs:   w.eventIDs(),
+	}
+}
+
+// RuleEngineConfig defines the configuration for the expression rules engine
+type RuleEngineConfig struct {
+	InstanceID string
+	Rules      []ExprRule
+	// Callbacks defines the writers for the callback URLs used in the rules
+	Callbacks map[string]AlertWriter
+}
+
+// RuleEngine evaluates the expression rules against the events. It implements
+// db.Sink, so it receives the events like the other sinks
+type RuleEngine struct {
+	config    RuleEngineConfig
+	mu        sync.Mutex
+	rules     []*exprRule
+	saveAlert func(*db.Alert) error
+}
+
+// NewRuleEngine returns a new rules engine. The windows are restored from the
+// events stored within the windows and the suppression intervals from the
+// stored alerts, so they survive restarts
+func NewRuleEngine(config RuleEngineConfig) (*RuleEngine, error) {
+	e, err := newRuleEngine(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.restore(); err != nil {
+		return nil, fmt.Errorf("unable to restore the rules state: %w", err)
+	}
+	return e, nil
+}
+
+func newRuleEngine(config RuleEngineConfig) (*RuleEngine, error) {
+	if len(config.Rules) == 0 {
+		return nil, errors.New("at least a rule is required")
+	}
+	e := &RuleEngine{
+		config:    config,
+		saveAlert: db.SaveAlert,
+	}
+	var names []string
+	for _, rule := range config.Rules {
+		r, err := newExprRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(names, r.Name) {
+			return nil, fmt.Errorf("duplicate rule name %q", r.Name)
+		}
+		names = append(names, r.Name)
+		for _, callback := range r.CallbackURLs {
+			w, ok := config.Callbacks[callback]
+			if !ok {
+				return nil, fmt.Errorf("no writer for callback URL %q", callback)
+			}
+			r.callbacks = append(r.callbacks, w)
+		}
+		e.rules = append(e.rules, r)
+	}
+	return e, nil
+}
+
+func (e *RuleEngine) restore() error {
+	now := time.Now()
+	var window, suppression time.Duration
+	var names []string
+	for _, rule := range e.rules {
+		window = max(window, rule.window)
+		suppression = max(suppression, rule.suppression)
+		names = append(names, rule.Name)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if window > 0 {
+		if err := e.replay(now.Add(-window), now, nil); err != nil {
+			return err
+		}
+	}
+	if suppression > 0 {
+		alerts, err := db.GetAlerts(db.AlertFilter{From: now.Add(-suppression), Rules: names})
+		if err != nil {
+			return err
+		}
+		for _, alert := range alerts {
+			for _, rule := range e.rules {
+				if rule.Name == alert.Rule {
+					w, ok := rule.groups[alert.Subject]
+					if !ok {
+						w = &subjectWindow{}
+						rule.groups[alert.Subject] = w
+					}
+					w.lastAlert = max(w.lastAlert, alert.Timestamp)
+				}
+			}
+		}
+	}
+	logger.AppLogger.Debug("rules state restored", "rules", len(e.rules))
+	return nil
+}
+
+// replay evaluates the stored events within the specified time range, fn is
+// called for each evaluated event with the alerts to raise
+func (e *RuleEngine) replay(from, to time.Time, fn func([]*db.Alert)) error {
+	observe := func(alerts []*db.Alert, _ error) error {
+		if fn != nil {
+			fn(alerts)
+		}
+		return nil
+	}
+	for _, eventType := range eventTypes {
+		if !slices.ContainsFunc(e.rules, func(r *exprRule) bool { return r.EventType == eventType }) {
+			continue
+		}
+		var err error
+		switch eventType {
+		case db.EventTypeFs:
+			err = db.ReplayFsEvents(from, to, func(ev *db.FsEvent) error {
+				return observe(e.observeFs(ev))
+			})
+		case db.EventTypeProvider:
+			err = db.ReplayProviderEvents(from, to, func(ev *db.ProviderEvent) error {
+				return observe(e.observeProvider(ev))
+			})
+		default:
+			err = db.ReplayLogEvents(from, to, func(ev *db.LogEvent) error {
+				return observe(e.observeLog(ev))
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evaluate evaluates the rules for the specified event type and returns the
+// alerts to raise, vars is called only if there are rules for the event type
+func (e *RuleEngine) evaluate(eventType, id string, timestamp int64, vars func() map[string]any) ([]*db.Alert, error) {
+	var alerts []*db.Alert
+	var errs []error
+	var values map[string]any
+	for _, rule := range e.rules {
+		if rule.EventType != eventType {
+			continue
+		}
+		if values == nil {
+			values = vars()
+		}
+		ok, err := evalExpr(rule.program, values)
+		if err != nil {
+			rule.errors++
+			errs = append(errs, fmt.Errorf("unable to evaluate rule %q for event %q: %w", rule.Name, id, err))
+			continue
+		}
+		if !ok {
+			continue
+		}
+		rule.matches++
+		key := rule.groupKey(values)
+		if w, ok := rule.add(key, windowEntry{timestamp: timestamp, id: id}); ok {
+			alerts = append(alerts, rule.newAlert(key, w, timestamp, e.config.InstanceID))
+		}
+	}
+	return alerts, errors.Join(errs...)
+}
+
+func (e *RuleEngine) observeFs(ev *db.FsEvent) ([]*db.Alert, error) {
+	return e.evaluate(db.EventTypeFs, ev.ID, ev.Timestamp, func() map[string]any {
+		return fsEventVars(ev)
+	})
+}
+
+func (e *RuleEngine) observeProvider(ev *db.ProviderEvent) ([]*db.Alert, error) {
+	return e.evaluate(db.EventTypeProvider, ev.ID, ev.Timestamp, func() map[string]any {
+		return providerEventVars(ev)
+	})
+}
+
+func (e *RuleEngine) observeLog(ev *db.LogEvent) ([]*db.Alert, error) {
+	return e.evaluate(db.EventTypeLog, ev.ID, ev.Timestamp, func() map[string]any {
+		return logEventVars(ev)
+	})
+}
+
+func (e *RuleEngine) getRule(name string) *exprRule {
+	for _, rule := range e.rules {
+		if rule.Name == name {
+			return rule
+		}
+	}
+	return nil
+}
+
+// raise sends the alerts to the outputs configured for their rules
+func (e *RuleEngine) raise(alerts []*db.Alert, err error) error {
+	errs := []error{err}
+	for _, alert := range alerts {
+		rule := e.getRule(alert.Rule)
+		for _, output := range rule.Outputs {
+			switch output {
+			case OutputAlerts:
+				if err := e.saveAlert(alert); err != nil {
+					errs = append(errs, fmt.Errorf("unable to save alert: %w", err))
+				}
+			case OutputLog:
+				logger.AppLogger.Warn("rule matched", "rule", alert.Rule, "severity", alert.Severity,
+					"subject", alert.Subject, "events", alert.Events, "message", alert.Message)
+			}
+		}
+		for _, w := range rule.callbacks {
+			if err := w.WriteAlert(alert); err != nil {
+				errs = append(errs, fmt.Errorf("unable to write alert: %w", err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Name implements db.Sink
+func (e *RuleEngine) Name() string {
+	return "rules"
+}
+
+// WriteFsEvent implements db.Sink
+func (e *RuleEngine) WriteFsEvent(ev *db.FsEvent) error {
+	e.mu.Lock()
+	alerts, err := e.observeFs(ev)
+	e.mu.Unlock()
+
+	return e.raise(alerts, err)
+}
+
+// WriteProviderEvent implements db.Sink
+func (e *RuleEngine) WriteProviderEvent(ev *db.ProviderEvent) error {
+	e.mu.Lock()
+	alerts, err := e.observeProvider(ev)
+	e.mu.Unlock()
+
+	return e.raise(alerts, err)
+}
+
+// WriteLogEvent implements db.Sink
+func (e *RuleEngine) WriteLogEvent(ev *db.LogEvent) error {
+	e.mu.Lock()
+	alerts, err := e.observeLog(ev)
+	e.mu.Unlock()
+
+	return e.raise(alerts, err)
+}
+
+// RuleTestStats defines the replay statistics for a rule
+type RuleTestStats struct {
+	Name      string
+	EventType string
+	Matches   int
+	Alerts    int
+	Errors    int
+}
+
+// RuleTestResult defines the result of replaying the stored events against
+// the expression rules
+type RuleTestResult struct {
+	Events int
+	Rules  []RuleTestStats
+	// Alerts contains the alerts that would be raised, the oldest first
+	Alerts []db.Alert
+}
+
+// TestExprRules replays the events stored within the specified time range, to
+// is excluded, against the specified rules. The alerts are not sent to the
+// rule outputs
+func TestExprRules(rules []ExprRule, from, to time.Time) (*RuleTestResult, error) {
+	e, err := newRuleEngine(RuleEngineConfig{Rules: rules})
+	if err != nil {
+		return nil, err
+	}
+	result := &RuleTestResult{}
+	alerts := make(map[string]int)
+	err = e.replay(from, to, func(raised []*db.Alert) {
+		result.Events++
+		for _, alert := range raised {
+			result.Alerts = append(result.Alerts, *alert)
+			alerts[alert.Rule]++
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range e.rules {
+		result.Rules = append(result.Rules, RuleTestStats{
+			Name:      rule.Name,
+			EventType: rule.EventType,
+			Matches:   rule.matches,
+			Alerts:    alerts[rule.Name],
+			Errors:    rule.errors,
+		})
+	}
+	// the events are replayed for each event type
+	slices.SortStableFunc(result.Alerts, func(a, b db.Alert) int {
+		return cmp.Compare(a.Timestamp, b.Timestamp)
+	})
+	return result, nil
+}
+
+// Write writes the test result as text, a summary for each rule followed by
+// the alerts
+func (r *RuleTestResult) Write(w io.Writer) error {
+	fmt.Fprintf(w, "Events replayed: %d\n\n", r.Events)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RULE\tEVENT TYPE\tMATCHES\tALERTS\tERRORS")
+	for _, rule := range r.Rules {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\n", rule.Name, rule.EventType, rule.Matches, rule.Alerts, rule.Errors)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(r.Alerts) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w)
+	return WriteAlerts(w, r.Alerts)
+}
@@ -0,0 +1,253 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package detection
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+)
+
+func newTestRuleEngine(t *testing.T, config RuleEngineConfig) (*RuleEngine, *[]*db.Alert) {
+	var saved []*db.Alert
+	e, err := newRuleEngine(config)
+	require.NoError(t, err)
+	e.saveAlert = func(alert *db.Alert) error {
+		saved = append(saved, alert)
+		return nil
+	}
+	return e, &saved
+}
+
+func TestLoadExprRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	_, err := LoadExprRules(path)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(`[{"name":"ftp_upload_failures","event_type":"fs",
+"condition":"action == \"upload\" && status != 1 && protocol == \"FTP\"","group_by":["username"],
+"window":"10m","threshold":5,"suppression":"1h","severity":"low","outputs":["alerts","log"]}]`), 0600))
+	rules, err := LoadExprRules(path)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, []string{"username"}, rules[0].GroupBy)
+
+	for _, data := range []string{
+		`[]`,
+		`{}`,
+		`[{"name":"a","event_type":"fs","condition":"true"},{"name":"a","event_type":"log","condition":"true"}]`,
+	} {
+		require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+		_, err = LoadExprRules(path)
+		assert.Error(t, err, data)
+	}
+}
+
+func TestExprRuleValidation(t *testing.T) {
+	r, err := newExprRule(ExprRule{Name: "rule", EventType: db.EventTypeLog, Condition: `event_name == "login_ok"`})
+	require.NoError(t, err)
+	assert.Equal(t, 1, r.Threshold)
+	assert.Equal(t, db.SeverityMedium, r.Severity)
+	assert.Equal(t, []string{OutputAlerts}, r.Outputs)
+	r, err = newExprRule(ExprRule{Name: "rule", EventType: db.EventTypeFs, Condition: "true", Window: "1m",
+		CallbackURLs: []string{"https://example.com/alerts"}})
+	require.NoError(t, err)
+	assert.Empty(t, r.Outputs)
+	assert.Equal(t, time.Minute, r.suppression)
+	for _, eventType := range eventTypes {
+		_, err = newExprRule(ExprRule{Name: "rule", EventType: eventType,
+			Condition: `country_code != "IT" && asn == 20712 && city == "London"`})
+		assert.NoError(t, err)
+	}
+
+	for _, rule := range []ExprRule{
+		{EventType: db.EventTypeFs, Condition: "true"},
+		{Name: "rule", EventType: "unknown", Condition: "true"},
+		{Name: "rule", EventType: db.EventTypeFs, Condition: "unknown == 1"},
+		{Name: "rule", EventType: db.EventTypeFs, Condition: "file_size"},
+		{Name: "rule", EventType: db.EventTypeFs, Condition: `action == "upload" &&`},
+		{Name: "rule", EventType: db.EventTypeLog, Condition: `status == 1`},
+		{Name: "rule", EventType: db.EventTypeFs, Condition: "true", Severity: "unknown"},
+		{Name: "rule", EventType: db.EventTypeFs, Condition: "true", GroupBy: []string{"unknown"}},
+		{Name: "rule", EventType: db.EventTypeProvider, Condition: "true", GroupBy: []string{"object_data"}},
+		{Name: "rule", EventType: db.EventTypeFs, Condition: "true", Window: "1"},
+		{Name: "rule", EventType: db.EventTypeFs, Condition: "true", Threshold: 2},
+		{Name: "rule", EventType: db.EventTypeFs, Condition: "true", Threshold: -1},
+		{Name: "rule", EventType: db.EventTypeFs, Condition: "true", Suppression: "-1m"},
+		{Name: "rule", EventType: db.EventTypeFs, Condition: "true", Outputs: []string{"unknown"}},
+		{Name: "rule", EventType: db.EventTypeFs, Condition: "true", CallbackURLs: []string{"ftp://example.com"}},
+	} {
+		_, err = newExprRule(rule)
+		assert.Error(t, err, rule)
+	}
+	_, err = newRuleEngine(RuleEngineConfig{})
+	assert.Error(t, err)
+	_, err = newRuleEngine(RuleEngineConfig{Rules: []ExprRule{{Name: "rule", EventType: db.EventTypeFs,
+		Condition: "true", CallbackURLs: []string{"https://example.com/alerts"}}}})
+	assert.ErrorContains(t, err, "no writer for callback URL")
+}
+
+func TestRuleEngine(t *testing.T) {
+	callback := &testAlertWriter{}
+	e, saved := newTestRuleEngine(t, RuleEngineConfig{
+		InstanceID: "sftpgo1",
+		Rules: []ExprRule{
+			{
+				Name:        "ftp_upload_failures",
+				EventType:   db.EventTypeFs,
+				Condition:   `action == "upload" && status != 1 && protocol == "FTP"`,
+				Description: "FTP upload failures",
+				GroupBy:     []string{"username", "ip"},
+				Window:      "10m",
+				Threshold:   3,
+				Severity:    db.SeverityHigh,
+				Outputs:     []string{OutputAlerts, OutputLog},
+			},
+			{
+				Name:         "admin_deleted",
+				EventType:    db.EventTypeProvider,
+				Condition:    `object_type == "admin" && action == "delete"`,
+				CallbackURLs: []string{"https://example.com/alerts"},
+			},
+			{
+				Name:      "permissions",
+				EventType: db.EventTypeProvider,
+				Condition: `object_type == "user" && "*" in object_data.permissions["/"]`,
+				GroupBy:   []string{"object_name"},
+			},
+			{
+				Name:        "logins_ok",
+				EventType:   db.EventTypeLog,
+				Condition:   `event_name == "login_ok" && username == "admin"`,
+				Suppression: "1h",
+			},
+		},
+		Callbacks: map[string]AlertWriter{"https://example.com/alerts": callback},
+	})
+	assert.Equal(t, "rules", e.Name())
+	ts := time.Now().UnixNano()
+
+	for idx, protocol := range []string{"FTP", "SFTP", "FTP"} {
+		require.NoError(t, e.WriteFsEvent(&db.FsEvent{ID: string(rune('a' + idx)), Timestamp: ts + int64(idx),
+			Action: "upload", Status: 2, Protocol: protocol, Username: "user1", IP: "10.0.0.1"}))
+	}
+	// a duplicated event is ignored
+	require.NoError(t, e.WriteFsEvent(&db.FsEvent{ID: "c", Timestamp: ts + 2, Action: "upload", Status: 2,
+		Protocol: "FTP", Username: "user1", IP: "10.0.0.1"}))
+	assert.Empty(t, *saved)
+	require.NoError(t, e.WriteFsEvent(&db.FsEvent{ID: "e", Timestamp: ts + 4, Action: "upload", Status: 2,
+		Protocol: "FTP", Username: "user1", IP: "10.0.0.1"}))
+	require.Len(t, *saved, 1)
+	assert.Equal(t, &db.Alert{
+		Timestamp:  ts + 4,
+		Rule:       "ftp_upload_failures",
+		Subject:    "username=user1,ip=10.0.0.1",
+		Events:     3,
+		Message:    "FTP upload failures: 3 events within 10m0s for username=user1,ip=10.0.0.1",
+		InstanceID: "sftpgo1",
+		Severity:   db.SeverityHigh,
+		EventIDs:   []string{"a", "c", "e"},
+	}, (*saved)[0])
+	// the alerts are suppressed within the window
+	require.NoError(t, e.WriteFsEvent(&db.FsEvent{ID: "f", Timestamp: ts + 5, Action: "upload", Status: 2,
+		Protocol: "FTP", Username: "user1", IP: "10.0.0.1"}))
+	assert.Len(t, *saved, 1)
+
+	// callbacks only
+	require.NoError(t, e.WriteProviderEvent(&db.ProviderEvent{ID: "g", Timestamp: ts, Action: "delete",
+		ObjectType: "admin", ObjectName: "admin1"}))
+	assert.Len(t, *saved, 1)
+	require.Len(t, callback.alerts, 1)
+	assert.Equal(t, "admin_deleted", callback.alerts[0].Rule)
+	assert.Equal(t, "events matching rule admin_deleted: 1 events", callback.alerts[0].Message)
+	assert.Equal(t, db.SeverityMedium, callback.alerts[0].Severity)
+	// no suppression, each event raises an alert
+	require.NoError(t, e.WriteProviderEvent(&db.ProviderEvent{ID: "h", Timestamp: ts + 1, Action: "delete",
+		ObjectType: "admin", ObjectName: "admin2"}))
+	assert.Len(t, callback.alerts, 2)
+
+	// evaluation errors are returned, the other rules are evaluated
+	err := e.WriteProviderEvent(&db.ProviderEvent{ID: "i", Timestamp: ts, Action: "update", ObjectType: "user",
+		ObjectName: "user1", ObjectData: []byte(`{"username":"user1"}`)})
+	assert.ErrorContains(t, err, "permissions")
+	require.NoError(t, e.WriteProviderEvent(&db.ProviderEvent{ID: "j", Timestamp: ts, Action: "update",
+		ObjectType: "user", ObjectName: "user1", ObjectData: []byte(`{"permissions":{"/":["list"]}}`)}))
+	assert.Len(t, *saved, 1)
+	require.NoError(t, e.WriteProviderEvent(&db.ProviderEvent{ID: "k", Timestamp: ts, Action: "update",
+		ObjectType: "user", ObjectName: "user1", ObjectData: []byte(`{"permissions":{"/":["*"]}}`)}))
+	require.Len(t, *saved, 2)
+	assert.Equal(t, "object_name=user1", (*saved)[1].Subject)
+
+	require.NoError(t, e.WriteLogEvent(&db.LogEvent{ID: "l", Timestamp: ts, Event: 5, Username: "admin"}))
+	require.NoError(t, e.WriteLogEvent(&db.LogEvent{ID: "m", Timestamp: ts + int64(time.Minute), Event: 5,
+		Username: "admin"}))
+	require.Len(t, *saved, 3)
+	assert.Equal(t, "logins_ok", (*saved)[2].Rule)
+	require.NoError(t, e.WriteLogEvent(&db.LogEvent{ID: "n", Timestamp: ts + int64(time.Hour), Event: 5,
+		Username: "admin"}))
+	assert.Len(t, *saved, 4)
+
+	matches := make(map[string]int)
+	for _, rule := range e.rules {
+		matches[rule.Name] = rule.matches
+	}
+	assert.Equal(t, map[string]int{"ftp_upload_failures": 5, "admin_deleted": 2, "permissions": 1,
+		"logins_ok": 3}, matches)
+	assert.Equal(t, 1, e.rules[2].errors)
+}
+
+func TestExprRuleSweep(t *testing.T) {
+	r, err := newExprRule(ExprRule{Name: "rule", EventType: db.EventTypeFs, Condition: "true", Window: "1m",
+		Threshold: 2, GroupBy: []string{"username"}})
+	require.NoError(t, err)
+	ts := time.Now().UnixNano()
+	r.add("a", windowEntry{timestamp: ts, id: "1"})
+	r.add("b", windowEntry{timestamp: ts, id: "2"})
+	assert.Len(t, r.groups, 2)
+	r.add("c", windowEntry{timestamp: ts + int64(2*time.Minute), id: "3"})
+	assert.Len(t, r.groups, 1)
+	assert.Contains(t, r.groups, "c")
+}
+
+func TestWriteRuleTestResult(t *testing.T) {
+	result := &RuleTestResult{
+		Events: 10,
+		Rules: []RuleTestStats{
+			{Name: "rule1", EventType: db.EventTypeFs, Matches: 3, Alerts: 1},
+			{Name: "rule2", EventType: db.EventTypeLog, Errors: 2},
+		},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, result.Write(&buf))
+	assert.Equal(t, `Events replayed: 10
+
+RULE   EVENT TYPE  MATCHES  ALERTS  ERRORS
+rule1  fs          3        1       0
+rule2  log         0        0       2
+`, buf.String())
+	result.Alerts = []db.Alert{{Timestamp: time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC).UnixNano(), Rule: "rule1",
+		Severity: db.SeverityLow, Events: 3, Message: "events matching rule rule1: 3 events"}}
+	buf.Reset()
+	require.NoError(t, result.Write(&buf))
+	assert.Contains(t, buf.String(), "\n\nTIME")
+	assert.Contains(t, buf.String(), "events matching rule rule1: 3 events")
+}
@@ -0,0 +1,263 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package detection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+// Supported filesystem rule types
+const (
+	// FsRuleMassDeletion counts the deleted files and directories
+	FsRuleMassDeletion = "mass_deletion"
+	// FsRuleSuspiciousRenames counts the renames that change the file
+	// extension
+	FsRuleSuspiciousRenames = "suspicious_renames"
+	// FsRuleUploadVolume compares the uploaded bytes with the user's baseline
+	FsRuleUploadVolume = "upload_volume"
+)
+
+const (
+	defaultBaseline = 7 * 24 * time.Hour
+	// baselineTTL defines how long the baseline for a user is cached
+	baselineTTL = int64(time.Hour)
+)
+
+var (
+	fsRuleTypes  = []string{FsRuleMassDeletion, FsRuleSuspiciousRenames, FsRuleUploadVolume}
+	fsRuleEvents = []string{"delete", "rmdir", "rename", "upload"}
+	severities   = []string{db.SeverityLow, db.SeverityMedium, db.SeverityHigh, db.SeverityCritical}
+)
+
+// FsRule defines a detection rule for the filesystem events. Only the
+// successful events are analyzed
+type FsRule struct {
+	// Name identifies the rule in the alerts, it must be unique
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Roles restricts the rule to the users with these roles, empty means
+	// all users. Use an empty string for the users without a role
+	Roles    []string `json:"roles,omitempty"`
+	Severity string   `json:"severity,omitempty"`
+	// Window defines the sliding window as Go duration, for example "5m"
+	Window string `json:"window"`
+	// Threshold defines the number of events for the same user within the
+	// window that raise an alert, for mass_deletion and suspicious_renames
+	Threshold int `json:"threshold,omitempty"`
+	// Extensions restricts suspicious_renames to the renames to these
+	// extensions, empty means any extension change
+	Extensions []string `json:"extensions,omitempty"`
+	// Multiplier, MinBytes and Baseline are used by upload_volume: an alert
+	// is raised if the bytes uploaded within the window are at least
+	// MinBytes and Multiplier times the average uploaded bytes per window
+	// within the Baseline period preceding the window
+	Multiplier float64 `json:"multiplier,omitempty"`
+	MinBytes   int64   `json:"min_bytes,omitempty"`
+	Baseline   string  `json:"baseline,omitempty"`
+}
+
+// ParseFsRules parses the filesystem rules from their JSON representation
+func ParseFsRules(data string) ([]FsRule, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var rules []FsRule
+	if err := json.Unmarshal([]byte(data), &rules); err != nil {
+		return nil, fmt.Errorf("unable to parse the filesystem rules: %w", err)
+	}
+	for idx := range rules {
+		if _, err := newFsRule(rules[idx]); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+// fsRule is the validated FsRule with its state
+type fsRule struct {
+	FsRule
+	window     time.Duration
+	baseline   time.Duration
+	extensions []string
+	slidingWindows
+}
+
+func newFsRule(rule FsRule) (*fsRule, error) {
+	if rule.Name == "" {
+		return nil, errors.New("the filesystem rule name is required")
+	}
+	if !slices.Contains(fsRuleTypes, rule.Type) {
+		return nil, fmt.Errorf("invalid type %q for rule %q, supported types: %s", rule.Type, rule.Name,
+			strings.Join(fsRuleTypes, ", "))
+	}
+	if rule.Severity == "" {
+		rule.Severity = db.SeverityHigh
+	}
+	if !slices.Contains(severities, rule.Severity) {
+		return nil, fmt.Errorf("invalid severity %q for rule %q, supported severities: %s", rule.Severity,
+			rule.Name, strings.Join(severities, ", "))
+	}
+	r := &fsRule{
+		FsRule:   rule,
+		baseline: defaultBaseline,
+	}
+	var err error
+	if r.window, err = time.ParseDuration(rule.Window); err != nil || r.window <= 0 {
+		return nil, fmt.Errorf("invalid window %q for rule %q", rule.Window, rule.Name)
+	}
+	switch rule.Type {
+	case FsRuleUploadVolume:
+		if rule.Multiplier <= 0 || rule.MinBytes < 0 {
+			return nil, fmt.Errorf("rule %q: the multiplier must be greater than 0 and min_bytes cannot be negative",
+				rule.Name)
+		}
+		if rule.Baseline != "" {
+			if r.baseline, err = time.ParseDuration(rule.Baseline); err != nil || r.baseline < r.window {
+				return nil, fmt.Errorf("invalid baseline %q for rule %q, it must be at least the window",
+					rule.Baseline, rule.Name)
+			}
+		}
+	default:
+		if rule.Threshold <= 0 {
+			return nil, fmt.Errorf("rule %q: the threshold must be greater than 0", rule.Name)
+		}
+	}
+	for _, ext := range rule.Extensions {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		r.extensions = append(r.extensions, ext)
+	}
+	r.slidingWindows = newSlidingWindows(r.window)
+	return r, nil
+}
+
+func (r *fsRule) matches(ev *db.FsEvent) bool {
+	if ev.Status != 1 || ev.Username == "" {
+		return false
+	}
+	if len(r.Roles) > 0 && !slices.Contains(r.Roles, ev.Role) {
+		return false
+	}
+	switch r.Type {
+	case FsRuleMassDeletion:
+		return ev.Action == "delete" || ev.Action == "rmdir"
+	case FsRuleSuspiciousRenames:
+		if ev.Action != "rename" {
+			return false
+		}
+		ext := strings.ToLower(path.Ext(ev.VirtualTargetPath))
+		if ext == "" || ext == strings.ToLower(path.Ext(ev.VirtualPath)) {
+			return false
+		}
+		return len(r.extensions) == 0 || slices.Contains(r.extensions, ext)
+	default:
+		return ev.Action == "upload" && ev.FileSize > 0
+	}
+}
+
+// needsBaseline returns true if the upload baseline for the user must be
+// read before observing the event. The baseline is only read if the event
+// can raise an alert and the cached baseline is expired
+func (r *fsRule) needsBaseline(ev *db.FsEvent) bool {
+	if r.Type != FsRuleUploadVolume {
+		return false
+	}
+	w, ok := r.subjects[ev.Username]
+	if !ok {
+		return ev.FileSize >= r.MinBytes
+	}
+	return w.baselineExpiration <= ev.Timestamp && r.canAlert(w, ev.Timestamp) &&
+		w.sumSince(ev.Timestamp-r.slidingWindows.window)+ev.FileSize >= r.MinBytes
+}
+
+// getBaseline returns the average bytes uploaded by the user per window
+// within the baseline period. The baseline period ends where the window
+// starts, so the uploads being analyzed don't raise the baseline
+func (r *fsRule) getBaseline(ev *db.FsEvent, uploadedBytes func(string, time.Time, time.Time) (int64, error),
+) (int64, error) {
+	end := time.Unix(0, ev.Timestamp).Add(-r.window)
+	bytes, err := uploadedBytes(ev.Username, end.Add(-r.baseline), end)
+	if err != nil {
+		return 0, fmt.Errorf("unable to get the upload baseline for user %q: %w", ev.Username, err)
+	}
+	return int64(float64(bytes) * float64(r.window) / float64(r.baseline)), nil
+}
+
+// observe records a matching event and returns the alert to raise, if any.
+// baselines contains the upload baselines read for the event, see
+// needsBaseline
+func (r *fsRule) observe(ev *db.FsEvent, baselines map[*fsRule]int64, instanceID string) *db.Alert {
+	if r.Type != FsRuleUploadVolume {
+		w, added := r.slidingWindows.add(ev.Username, windowEntry{timestamp: ev.Timestamp, id: ev.ID}, r.Threshold)
+		if !added || len(w.entries) < r.Threshold || !r.canAlert(w, ev.Timestamp) {
+			return nil
+		}
+		description := "deletes"
+		if r.Type == FsRuleSuspiciousRenames {
+			description = "renames to suspicious extensions"
+		}
+		return r.newAlert(ev, w, instanceID, fmt.Sprintf("%d %s by user %s within %s", len(w.entries),
+			description, ev.Username, r.window))
+	}
+	w, added := r.slidingWindows.add(ev.Username, windowEntry{timestamp: ev.Timestamp, id: ev.ID, value: ev.FileSize}, 0)
+	if !added {
+		return nil
+	}
+	if baseline, ok := baselines[r]; ok {
+		w.baseline = baseline
+		w.baselineExpiration = ev.Timestamp + baselineTTL
+		logger.AppLogger.Debug("upload baseline updated", "rule", r.Name, "username", ev.Username,
+			"baseline", w.baseline)
+	}
+	total := w.sum()
+	if total < r.MinBytes || !r.canAlert(w, ev.Timestamp) {
+		return nil
+	}
+	// the users without uploads within the baseline period, or whose baseline
+	// could not be read, are not analyzed
+	if w.baseline == 0 || w.baselineExpiration <= ev.Timestamp {
+		return nil
+	}
+	if float64(total) < r.Multiplier*float64(w.baseline) {
+		return nil
+	}
+	return r.newAlert(ev, w, instanceID, fmt.Sprintf("%d bytes uploaded by user %s within %s, baseline %d bytes",
+		total, ev.Username, r.window, w.baseline))
+}
+
+func (r *fsRule) newAlert(ev *db.FsEvent, w *subjectWindow, instanceID, message string) *db.Alert {
+	w.lastAlert = ev.Timestamp
+	return &db.Alert{
+		Timestamp:  ev.Timestamp,
+		Rule:       r.Name,
+		Subject:    ev.Username,
+		Events:     len(w.entries),
+		Message:    message,
+		InstanceID: instanceID,
+		Severity:   r.Severity,
+		EventIDs:   w.eventIDs(),
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package detection
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+)
+
+func TestParseFsRules(t *testing.T) {
+	rules, err := ParseFsRules("")
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+	rules, err = ParseFsRules(`[{"name":"deletes","type":"mass_deletion","roles":["customers"],"window":"5m","threshold":100},
+{"name":"uploads","type":"upload_volume","window":"1h","multiplier":10,"min_bytes":1024,"baseline":"72h","severity":"medium"}]`)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, []string{"customers"}, rules[0].Roles)
+	assert.Equal(t, "72h", rules[1].Baseline)
+
+	for _, data := range []string{
+		`{}`,
+		`[{"type":"mass_deletion","window":"5m","threshold":1}]`,
+		`[{"name":"a","type":"unknown","window":"5m","threshold":1}]`,
+		`[{"name":"a","type":"mass_deletion","window":"5m","threshold":1,"severity":"unknown"}]`,
+		`[{"name":"a","type":"mass_deletion","window":"5","threshold":1}]`,
+		`[{"name":"a","type":"suspicious_renames","window":"5m"}]`,
+		`[{"name":"a","type":"upload_volume","window":"5m"}]`,
+		`[{"name":"a","type":"upload_volume","window":"5m","multiplier":2,"baseline":"1m"}]`,
+	} {
+		_, err = ParseFsRules(data)
+		assert.Error(t, err, data)
+	}
+}
+
+func TestFsRuleMatches(t *testing.T) {
+	r, err := newFsRule(FsRule{Name: "renames", Type: FsRuleSuspiciousRenames, Window: "1m", Threshold: 1,
+		Roles: []string{"role1", ""}, Extensions: []string{"LOCKED", ".crypt"}})
+	require.NoError(t, err)
+	assert.Equal(t, db.SeverityHigh, r.Severity)
+	assert.True(t, r.matches(&db.FsEvent{Action: "rename", Status: 1, Username: "user1",
+		VirtualPath: "/doc.txt", VirtualTargetPath: "/doc.txt.locked"}))
+	assert.True(t, r.matches(&db.FsEvent{Action: "rename", Status: 1, Username: "user1", Role: "role1",
+		VirtualPath: "/doc.txt", VirtualTargetPath: "/doc.crypt"}))
+	// different role, failed event, same or not listed extension
+	assert.False(t, r.matches(&db.FsEvent{Action: "rename", Status: 1, Username: "user1", Role: "role2",
+		VirtualPath: "/doc.txt", VirtualTargetPath: "/doc.crypt"}))
+	assert.False(t, r.matches(&db.FsEvent{Action: "rename", Status: 2, Username: "user1",
+		VirtualPath: "/doc.txt", VirtualTargetPath: "/doc.crypt"}))
+	assert.False(t, r.matches(&db.FsEvent{Action: "rename", Status: 1, Username: "user1",
+		VirtualPath: "/a.crypt", VirtualTargetPath: "/b.CRYPT"}))
+	assert.False(t, r.matches(&db.FsEvent{Action: "rename", Status: 1, Username: "user1",
+		VirtualPath: "/doc.txt", VirtualTargetPath: "/doc.pdf"}))
+	assert.False(t, r.matches(&db.FsEvent{Action: "delete", Status: 1, Username: "user1",
+		VirtualPath: "/doc.crypt"}))
+
+	r, err = newFsRule(FsRule{Name: "renames", Type: FsRuleSuspiciousRenames, Window: "1m", Threshold: 1})
+	require.NoError(t, err)
+	assert.True(t, r.matches(&db.FsEvent{Action: "rename", Status: 1, Username: "user1", Role: "role2",
+		VirtualPath: "/doc.txt", VirtualTargetPath: "/doc.pdf"}))
+	assert.False(t, r.matches(&db.FsEvent{Action: "rename", Status: 1, Username: "user1",
+		VirtualPath: "/doc.txt", VirtualTargetPath: "/doc"}))
+
+	r, err = newFsRule(FsRule{Name: "deletes", Type: FsRuleMassDeletion, Window: "1m", Threshold: 1})
+	require.NoError(t, err)
+	assert.True(t, r.matches(&db.FsEvent{Action: "delete", Status: 1, Username: "user1"}))
+	assert.True(t, r.matches(&db.FsEvent{Action: "rmdir", Status: 1, Username: "user1"}))
+	assert.False(t, r.matches(&db.FsEvent{Action: "upload", Status: 1, Username: "user1"}))
+	assert.False(t, r.matches(&db.FsEvent{Action: "delete", Status: 1}))
+}
+
+func TestMassDeletion(t *testing.T) {
+	e, saved := newTestEngine(t, Config{
+		InstanceID: "sftpgo1",
+		FsRules: []FsRule{
+			{Name: "deletes", Type: FsRuleMassDeletion, Window: "1m", Threshold: 3, Roles: []string{"role1"}},
+			{Name: "critical_deletes", Type: FsRuleMassDeletion, Window: "1m", Threshold: 5,
+				Severity: db.SeverityCritical},
+		},
+	})
+	ts := time.Now().UnixNano()
+	for idx, action := range []string{"delete", "upload", "rmdir"} {
+		require.NoError(t, e.WriteFsEvent(&db.FsEvent{ID: action + string(rune('a'+idx)), Timestamp: ts + int64(idx),
+			Action: action, Status: 1, Username: "user1", Role: "role1"}))
+	}
+	// a retried event is counted once
+	require.NoError(t, e.WriteFsEvent(&db.FsEvent{ID: "rmdirc", Timestamp: ts + 2, Action: "rmdir", Status: 1,
+		Username: "user1", Role: "role1"}))
+	assert.Len(t, *saved, 0)
+	require.NoError(t, e.WriteFsEvent(&db.FsEvent{ID: "deleted", Timestamp: ts + 3, Action: "delete", Status: 1,
+		Username: "user1", Role: "role1"}))
+	require.Len(t, *saved, 1)
+	assert.Equal(t, &db.Alert{
+		Timestamp:  ts + 3,
+		Rule:       "deletes",
+		Subject:    "user1",
+		Events:     3,
+		Message:    "3 deletes by user user1 within 1m0s",
+		InstanceID: "sftpgo1",
+		Severity:   db.SeverityHigh,
+		EventIDs:   []string{"deletea", "rmdirc", "deleted"},
+	}, (*saved)[0])
+	// the users without role only match the second rule
+	for idx := range 5 {
+		require.NoError(t, e.WriteFsEvent(&db.FsEvent{Timestamp: ts + int64(idx), Action: "delete", Status: 1,
+			Username: "user2"}))
+	}
+	require.Len(t, *saved, 2)
+	assert.Equal(t, "critical_deletes", (*saved)[1].Rule)
+	assert.Equal(t, "user2", (*saved)[1].Subject)
+	assert.Equal(t, db.SeverityCritical, (*saved)[1].Severity)
+	// log events don't match the fs rules
+	require.NoError(t, e.WriteLogEvent(&db.LogEvent{Timestamp: ts, Event: 1, Username: "user1"}))
+	assert.Len(t, *saved, 2)
+}
+
+func TestUploadVolume(t *testing.T) {
+	e, saved := newTestEngine(t, Config{
+		FsRules: []FsRule{
+			{Name: "uploads", Type: FsRuleUploadVolume, Window: "1h", Multiplier: 10, MinBytes: 1000,
+				Baseline: "10h"},
+		},
+	})
+	var queries int
+	var baselineErr error
+	e.uploadedBytes = func(username string, from, to time.Time) (int64, error) {
+		queries++
+		assert.Equal(t, 10*time.Hour, to.Sub(from))
+		// the baseline is read without holding the engine lock
+		if assert.True(t, e.mu.TryLock()) {
+			e.mu.Unlock()
+		}
+		if username == "user2" {
+			return 0, baselineErr
+		}
+		// 500 bytes per hour
+		return 5000, nil
+	}
+	ts := time.Now().UnixNano()
+	upload := func(username string, offset time.Duration, size int64) error {
+		return e.WriteFsEvent(&db.FsEvent{Timestamp: ts + int64(offset), Action: "upload", Status: 1,
+			Username: username, FileSize: size, ID: username + offset.String()})
+	}
+	// below min bytes, the baseline is not required
+	require.NoError(t, upload("user1", 0, 999))
+	assert.Equal(t, 0, queries)
+	require.NoError(t, upload("user1", time.Minute, 1))
+	assert.Equal(t, 1, queries)
+	assert.Empty(t, *saved)
+	require.NoError(t, upload("user1", 2*time.Minute, 3999))
+	assert.Empty(t, *saved)
+	// the baseline is cached
+	require.NoError(t, upload("user1", 3*time.Minute, 1))
+	assert.Equal(t, 1, queries)
+	require.Len(t, *saved, 1)
+	assert.Equal(t, "5000 bytes uploaded by user user1 within 1h0m0s, baseline 500 bytes", (*saved)[0].Message)
+	assert.Equal(t, 4, (*saved)[0].Events)
+	assert.Len(t, (*saved)[0].EventIDs, 4)
+	// a single alert is raised within the window
+	require.NoError(t, upload("user1", 4*time.Minute, 10000))
+	assert.Len(t, *saved, 1)
+
+	// a user without uploads within the baseline period is not analyzed
+	require.NoError(t, upload("user2", 0, 100000))
+	assert.Equal(t, 2, queries)
+	assert.Len(t, *saved, 1)
+	baselineErr = errors.New("baseline error")
+	assert.NoError(t, upload("user2", 2*time.Hour, 100))
+	assert.Equal(t, 2, queries)
+	assert.Error(t, upload("user2", 2*time.Hour+time.Minute, 100000))
+	assert.Equal(t, 3, queries)
+	assert.Len(t, *saved, 1)
+}
+
+func TestRestoreUploadVolume(t *testing.T) {
+	e, saved := newTestEngine(t, Config{
+		FsRules: []FsRule{
+			{Name: "uploads", Type: FsRuleUploadVolume, Window: "1h", Multiplier: 10, MinBytes: 1000,
+				Baseline: "10h"},
+		},
+	})
+	var queries int
+	e.uploadedBytes = func(_ string, _, _ time.Time) (int64, error) {
+		queries++
+		// 500 bytes per hour
+		return 5000, nil
+	}
+	ts := time.Now().UnixNano()
+	var events []db.FsEvent
+	for idx := range 5 {
+		events = append(events, db.FsEvent{ID: fmt.Sprintf("id%d", idx), Timestamp: ts + int64(idx), Action: "upload",
+			Status: 1, Username: "user1", FileSize: 1000})
+	}
+	// the restore doesn't read the baselines
+	e.restoreFs(events)
+	assert.Equal(t, 0, queries)
+	assert.Len(t, e.fsRules[0].subjects["user1"].entries, 5)
+	assert.Empty(t, *saved)
+	// the baseline is read for the first event received
+	require.NoError(t, e.WriteFsEvent(&db.FsEvent{ID: "id5", Timestamp: ts + 5, Action: "upload", Status: 1,
+		Username: "user1", FileSize: 1000}))
+	assert.Equal(t, 1, queries)
+	require.Len(t, *saved, 1)
+	assert.Equal(t, 6, (*saved)[0].Events)
+}
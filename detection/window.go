@@ -0,0 +1,175 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package detection
+
+import (
+	"slices"
+	"time"
+)
+
+// maxAlertEventIDs defines the maximum number of event IDs saved in an alert
+const maxAlertEventIDs = 100
+
+// windowEntry defines an event within a sliding window
+type windowEntry struct {
+	timestamp int64
+	id        string
+	value     int64
+}
+
+// subjectWindow defines the events within the window for a subject
+type subjectWindow struct {
+	entries   []windowEntry
+	lastAlert int64
+	// baseline and baselineExpiration cache the baseline for the rules
+	// comparing the events with the history of the subject
+	baseline           int64
+	baselineExpiration int64
+}
+
+// add appends the entry and removes the entries older than start. If limit is
+// greater than 0 at most limit entries are kept, the oldest ones are removed
+// first. It returns false, without appending the entry, if an entry with the
+// same ID is already within the window, for example a retried notification
+// or an event restored on startup
+func (w *subjectWindow) add(entry windowEntry, start int64, limit int) bool {
+	w.entries = slices.DeleteFunc(w.entries, func(e windowEntry) bool {
+		return e.timestamp <= start
+	})
+	if entry.id != "" && slices.ContainsFunc(w.entries, func(e windowEntry) bool {
+		return e.id == entry.id
+	}) {
+		return false
+	}
+	w.entries = append(w.entries, entry)
+	if limit > 0 && len(w.entries) > limit {
+		w.entries = w.entries[len(w.entries)-limit:]
+	}
+	return true
+}
+
+func (w *subjectWindow) sum() int64 {
+	var result int64
+	for _, e := range w.entries {
+		result += e.value
+	}
+	return result
+}
+
+// sumSince returns the sum of the values for the entries after start
+func (w *subjectWindow) sumSince(start int64) int64 {
+	var result int64
+	for _, e := range w.entries {
+		if e.timestamp > start {
+			result += e.value
+		}
+	}
+	return result
+}
+
+// eventIDs returns the IDs of the most recent events within the window
+func (w *subjectWindow) eventIDs() []string {
+	var result []string
+	for _, e := range w.entries[max(0, len(w.entries)-maxAlertEventIDs):] {
+		if e.id != "" {
+			result = append(result, e.id)
+		}
+	}
+	return result
+}
+
+// slidingWindows tracks the events for each subject within a sliding window
+type slidingWindows struct {
+	window    int64
+	subjects  map[string]*subjectWindow
+	lastSweep int64
+}
+
+func newSlidingWindows(window time.Duration) slidingWindows {
+	return slidingWindows{
+		window:   int64(window),
+		subjects: make(map[string]*subjectWindow),
+	}
+}
+
+func (s *slidingWindows) getSubject(subject string) *subjectWindow {
+	w, ok := s.subjects[subject]
+	if !ok {
+		w = &subjectWindow{}
+		s.subjects[subject] = w
+	}
+	return w
+}
+
+// add records an event for the subject and returns its window and false if the
+// event was already recorded, limit is the maximum number of events to keep, 0
+// means no limit
+func (s *slidingWindows) add(subject string, entry windowEntry, limit int) (*subjectWindow, bool) {
+	s.sweep(entry.timestamp)
+	w := s.getSubject(subject)
+	added := w.add(entry, entry.timestamp-s.window, limit)
+	return w, added
+}
+
+// sweep removes the subjects without events and alerts within the window, it
+// runs at most once per window
+func (s *slidingWindows) sweep(now int64) {
+	if now-s.lastSweep < s.window {
+		return
+	}
+	s.lastSweep = now
+	start := now - s.window
+	for subject, w := range s.subjects {
+		if w.lastAlert <= start && (len(w.entries) == 0 || w.entries[len(w.entries)-1].timestamp <= start) {
+			delete(s.subjects, subject)
+		}
+	}
+}
+
+// canAlert returns true if no alert was raised for the window within the
+// sliding window ending at timestamp
+func (s *slidingWindows) canAlert(w *subjectWindow, timestamp int64) bool {
+	return w.lastAlert <= timestamp-s.window
+}
+
+// thresholdRule counts the events for each subject within a sliding window
+type thresholdRule struct {
+	slidingWindows
+	name      string
+	threshold int
+	// description is used in the alert message
+	description string
+}
+
+func newThresholdRule(name, description string, window time.Duration, threshold int) *thresholdRule {
+	return &thresholdRule{
+		slidingWindows: newSlidingWindows(window),
+		name:           name,
+		threshold:      threshold,
+		description:    description,
+	}
+}
+
+// add records an event for the subject. It returns the subject window and
+// true if the threshold is reached and no alert was raised for the subject
+// within the window. Events already within the window are ignored
+func (r *thresholdRule) add(subject string, timestamp int64, id string) (*subjectWindow, bool) {
+	w, added := r.slidingWindows.add(subject, windowEntry{timestamp: timestamp, id: id}, r.threshold)
+	if !added || len(w.entries) < r.threshold || !r.canAlert(w, timestamp) {
+		return w, false
+	}
+	w.lastAlert = timestamp
+	return w, true
+}
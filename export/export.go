@@ -0,0 +1,365 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package export allows to export the stored events as parquet files
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+// Supported tables
+const (
+	TableFsEvents       = "fs"
+	TableProviderEvents = "provider"
+	TableLogEvents      = "log"
+)
+
+const (
+	exportPageSize       = 1000
+	hiveDefaultPartition = "__HIVE_DEFAULT_PARTITION__"
+	defaultRowGroupSize  = 10000
+	defaultRowsPerFile   = 100000
+)
+
+// Config defines the configuration for an export
+type Config struct {
+	// OutputDir is the root directory for the exported files
+	OutputDir string
+	// From and To define the time range to export, To is excluded
+	From time.Time
+	To   time.Time
+	// Tables defines the tables to export, all the tables if empty
+	Tables       []string
+	RowGroupSize int
+	Compression  string
+	// RowsPerFile defines the maximum number of rows written to
+	// each file of a partition before the checkpoint is updated
+	RowsPerFile int
+	// CheckpointFile allows to resume an interrupted export, optional
+	CheckpointFile string
+	// CreatedBy is saved inside the parquet files metadata
+	CreatedBy string
+}
+
+func (c *Config) validate() error {
+	if c.OutputDir == "" {
+		return errors.New("an output directory is required")
+	}
+	if !c.From.Before(c.To) {
+		return fmt.Errorf("invalid time range, %v is not before %v", c.From, c.To)
+	}
+	if len(c.Tables) == 0 {
+		c.Tables = []string{TableFsEvents, TableProviderEvents, TableLogEvents}
+	}
+	for _, table := range c.Tables {
+		if !slices.Contains([]string{TableFsEvents, TableProviderEvents, TableLogEvents}, table) {
+			return fmt.Errorf("unsupported table %q", table)
+		}
+	}
+	if c.RowGroupSize <= 0 {
+		c.RowGroupSize = defaultRowGroupSize
+	}
+	if c.Compression == "" {
+		c.Compression = CompressionSnappy
+	}
+	if _, ok := parquetCodecs[c.Compression]; !ok {
+		return fmt.Errorf("unsupported compression %q", c.Compression)
+	}
+	if c.RowsPerFile <= 0 {
+		c.RowsPerFile = defaultRowsPerFile
+	}
+	return nil
+}
+
+type tableCheckpoint struct {
+	Timestamp int64  `json:"timestamp"`
+	ID        string `json:"id"`
+	Completed bool   `json:"completed"`
+}
+
+type checkpoint struct {
+	From   int64                       `json:"from"`
+	To     int64                       `json:"to"`
+	Tables map[string]*tableCheckpoint `json:"tables"`
+}
+
+func loadCheckpoint(c *Config) (*checkpoint, error) {
+	cp := &checkpoint{
+		From:   c.From.UnixNano(),
+		To:     c.To.UnixNano(),
+		Tables: make(map[string]*tableCheckpoint),
+	}
+	if c.CheckpointFile == "" {
+		return cp, nil
+	}
+	data, err := os.ReadFile(c.CheckpointFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cp, nil
+		}
+		return nil, fmt.Errorf("unable to read checkpoint file %q: %w", c.CheckpointFile, err)
+	}
+	var saved checkpoint
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("unable to parse checkpoint file %q: %w", c.CheckpointFile, err)
+	}
+	if saved.From != cp.From || saved.To != cp.To {
+		return nil, fmt.Errorf("checkpoint file %q refers to a different time range, remove it to start a new export",
+			c.CheckpointFile)
+	}
+	if saved.Tables != nil {
+		cp.Tables = saved.Tables
+	}
+	return cp, nil
+}
+
+func (cp *checkpoint) save(name string) error {
+	if name == "" {
+		return nil
+	}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmpName := name + ".tmp"
+	if err := os.WriteFile(tmpName, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, name)
+}
+
+// Run exports the events matching the specified configuration
+func Run(c Config) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	cp, err := loadCheckpoint(&c)
+	if err != nil {
+		return err
+	}
+	for _, table := range c.Tables {
+		tableCp, ok := cp.Tables[table]
+		if !ok {
+			tableCp = &tableCheckpoint{}
+			cp.Tables[table] = tableCp
+		}
+		if tableCp.Completed {
+			logger.AppLogger.Info("table already exported, skipping", "table", table)
+			continue
+		}
+		switch table {
+		case TableFsEvents:
+			err = exportTable(&c, cp, tableCp, func(ev *db.FsEvent) (int64, string, string) {
+				return ev.Timestamp, ev.ID, ev.InstanceID
+			})
+		case TableProviderEvents:
+			err = exportTable(&c, cp, tableCp, func(ev *db.ProviderEvent) (int64, string, string) {
+				return ev.Timestamp, ev.ID, ev.InstanceID
+			})
+		case TableLogEvents:
+			err = exportTable(&c, cp, tableCp, func(ev *db.LogEvent) (int64, string, string) {
+				return ev.Timestamp, ev.ID, ev.InstanceID
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type tabler interface {
+	TableName() string
+}
+
+type partitionFile struct {
+	file   *os.File
+	buf    *bufio.Writer
+	writer *parquet.Writer
+	name   string
+}
+
+func (f *partitionFile) close() error {
+	err := f.writer.Close()
+	if err == nil {
+		err = f.buf.Flush()
+	}
+	if errClose := f.file.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		os.Remove(f.file.Name())
+		return err
+	}
+	return os.Rename(f.file.Name(), f.name)
+}
+
+// exportRun writes the rows between two checkpoints. A run has a file for
+// each partition, the file names are derived from the ID of the first row
+// in the run so a resumed export overwrites any partially written file
+type exportRun struct {
+	config *Config
+	dir    string
+	fileID string
+	files  map[string]*partitionFile
+	rows   int
+}
+
+func (r *exportRun) write(row any, timestamp int64, instanceID string) error {
+	if instanceID == "" {
+		instanceID = hiveDefaultPartition
+	}
+	partition := filepath.Join(r.dir,
+		"date="+time.Unix(0, timestamp).UTC().Format("2006-01-02"),
+		"instance_id="+escapePartitionValue(instanceID))
+	f, ok := r.files[partition]
+	if !ok {
+		if err := os.MkdirAll(partition, 0755); err != nil {
+			return err
+		}
+		name := filepath.Join(partition, fmt.Sprintf("part-%s.parquet", r.fileID))
+		file, err := os.Create(name + ".tmp")
+		if err != nil {
+			return err
+		}
+		buf := bufio.NewWriter(file)
+		writer, err := newParquetWriter(buf, row, r.config.RowGroupSize, r.config.Compression, r.config.CreatedBy)
+		if err != nil {
+			file.Close()
+			os.Remove(file.Name())
+			return err
+		}
+		f = &partitionFile{
+			file:   file,
+			buf:    buf,
+			writer: writer,
+			name:   name,
+		}
+		r.files[partition] = f
+	}
+	r.rows++
+	return f.writer.Write(row)
+}
+
+// abort removes the temporary files for the current run
+func (r *exportRun) abort() {
+	for _, f := range r.files {
+		f.file.Close()
+		os.Remove(f.file.Name())
+	}
+	r.files = make(map[string]*partitionFile)
+}
+
+func (r *exportRun) close() error {
+	var result error
+	for _, f := range r.files {
+		if err := f.close(); err != nil && result == nil {
+			result = err
+		}
+	}
+	r.files = make(map[string]*partitionFile)
+	r.rows = 0
+	r.fileID = ""
+	return result
+}
+
+func exportTable[T any, PT interface {
+	*T
+	tabler
+}](c *Config, cp *checkpoint, tableCp *tableCheckpoint, getKey func(PT) (int64, string, string)) error {
+	tableName := PT(new(T)).TableName()
+	logger.AppLogger.Info("exporting table", "table", tableName, "from", c.From, "to", c.To)
+	run := &exportRun{
+		config: c,
+		dir:    filepath.Join(c.OutputDir, tableName),
+		files:  make(map[string]*partitionFile),
+	}
+	total := 0
+	for {
+		rows, err := getRowsPage[T](c, tableCp)
+		if err != nil {
+			run.abort()
+			return fmt.Errorf("unable to get events from table %q: %w", tableName, err)
+		}
+		for idx := range rows {
+			row := PT(&rows[idx])
+			timestamp, id, instanceID := getKey(row)
+			if run.fileID == "" {
+				run.fileID = id
+			}
+			if err := run.write(row, timestamp, instanceID); err != nil {
+				run.abort()
+				return fmt.Errorf("unable to write events from table %q: %w", tableName, err)
+			}
+			tableCp.Timestamp = timestamp
+			tableCp.ID = id
+		}
+		total += len(rows)
+		tableCp.Completed = len(rows) < exportPageSize
+		if run.rows >= c.RowsPerFile || tableCp.Completed {
+			if err := run.close(); err != nil {
+				return fmt.Errorf("unable to write events from table %q: %w", tableName, err)
+			}
+			if err := cp.save(c.CheckpointFile); err != nil {
+				return fmt.Errorf("unable to save checkpoint: %w", err)
+			}
+		}
+		if tableCp.Completed {
+			logger.AppLogger.Info("table exported", "table", tableName, "rows", total)
+			return nil
+		}
+	}
+}
+
+func getRowsPage[T any](c *Config, tableCp *tableCheckpoint) ([]T, error) {
+	sess, cancel := db.GetDefaultSession()
+	defer cancel()
+
+	sess = db.WhereEventTime(sess, c.From, c.To)
+	if tableCp.ID != "" {
+		sess = sess.Where("timestamp > ? OR (timestamp = ? AND id > ?)", tableCp.Timestamp,
+			tableCp.Timestamp, tableCp.ID)
+	}
+	var rows []T
+	err := sess.Order("timestamp ASC, id ASC").Limit(exportPageSize).Find(&rows).Error
+	return rows, err
+}
+
+// escapePartitionValue escapes the characters not allowed in Hive
+// partition values
+func escapePartitionValue(value string) string {
+	var sb strings.Builder
+	for _, c := range []byte(value) {
+		if c < 0x20 || c == 0x7f || strings.IndexByte("\"#%'*/:=?\\{[]^", c) >= 0 {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		} else {
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
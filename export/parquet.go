@@ -0,0 +1,72 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package export
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+	"github.com/parquet-go/parquet-go/compress/gzip"
+	"github.com/parquet-go/parquet-go/compress/snappy"
+	"github.com/parquet-go/parquet-go/compress/uncompressed"
+	"github.com/parquet-go/parquet-go/compress/zstd"
+)
+
+// Supported compression codecs
+const (
+	CompressionNone   = "none"
+	CompressionSnappy = "snappy"
+	CompressionGzip   = "gzip"
+	CompressionZstd   = "zstd"
+)
+
+var parquetCodecs = map[string]compress.Codec{
+	CompressionNone:   &uncompressed.Codec{},
+	CompressionSnappy: &snappy.Codec{},
+	CompressionGzip:   &gzip.Codec{},
+	CompressionZstd:   &zstd.Codec{},
+}
+
+// newParquetWriter returns a writer for rows of the same type as model. The
+// schema is derived from the parquet struct tags of the model
+func newParquetWriter(w io.Writer, model any, rowGroupSize int, compression, createdBy string) (*parquet.Writer, error) {
+	codec, ok := parquetCodecs[compression]
+	if !ok {
+		return nil, fmt.Errorf("unsupported compression %q", compression)
+	}
+	if rowGroupSize <= 0 {
+		return nil, fmt.Errorf("invalid row group size %d", rowGroupSize)
+	}
+	schema, err := getParquetSchema(model)
+	if err != nil {
+		return nil, err
+	}
+	return parquet.NewWriter(w, schema, parquet.Compression(codec),
+		parquet.MaxRowsPerRowGroup(int64(rowGroupSize)), &parquet.WriterConfig{CreatedBy: createdBy}), nil
+}
+
+// getParquetSchema returns the schema for the model, parquet panics for the
+// unsupported types
+func getParquetSchema(model any) (schema *parquet.Schema, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("unsupported model %T: %v", model, r)
+		}
+	}()
+
+	return parquet.SchemaOf(model), nil
+}
@@ -0,0 +1,114 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+)
+
+func TestParquetSchema(t *testing.T) {
+	schema, err := getParquetSchema(&db.FsEvent{})
+	require.NoError(t, err)
+	require.Len(t, schema.Columns(), 24)
+	col, ok := schema.Lookup("id")
+	require.True(t, ok)
+	assert.NotNil(t, col.Node.Type().LogicalType().UTF8)
+	col, ok = schema.Lookup("timestamp")
+	require.True(t, ok)
+	assert.Equal(t, parquet.Int64, col.Node.Type().Kind())
+	timestamp := col.Node.Type().LogicalType().Timestamp
+	require.NotNil(t, timestamp)
+	assert.True(t, timestamp.IsAdjustedToUTC)
+	assert.NotNil(t, timestamp.Unit.Nanos)
+	// the other int64 columns are not timestamps
+	col, ok = schema.Lookup("file_size")
+	require.True(t, ok)
+	assert.Nil(t, col.Node.Type().LogicalType().Timestamp)
+	col, ok = schema.Lookup("status")
+	require.True(t, ok)
+	assert.Equal(t, parquet.Int64, col.Node.Type().Kind())
+	_, ok = schema.Lookup("EventTime")
+	assert.False(t, ok)
+
+	schema, err = getParquetSchema(&db.ProviderEvent{})
+	require.NoError(t, err)
+	col, ok = schema.Lookup("object_data")
+	require.True(t, ok)
+	assert.Equal(t, parquet.ByteArray, col.Node.Type().Kind())
+	assert.Nil(t, col.Node.Type().LogicalType())
+
+	_, err = getParquetSchema(struct{ C chan int }{})
+	assert.Error(t, err)
+	_, err = newParquetWriter(io.Discard, db.LogEvent{}, 10, "lz4", "")
+	assert.Error(t, err)
+	_, err = newParquetWriter(io.Discard, db.LogEvent{}, 0, CompressionNone, "")
+	assert.Error(t, err)
+}
+
+func TestParquetWriter(t *testing.T) {
+	codecs := map[string]format.CompressionCodec{
+		CompressionNone:   format.Uncompressed,
+		CompressionSnappy: format.Snappy,
+		CompressionGzip:   format.Gzip,
+		CompressionZstd:   format.Zstd,
+	}
+	for compression, codec := range codecs {
+		t.Run(compression, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := newParquetWriter(&buf, &db.LogEvent{}, 2, compression, "test")
+			require.NoError(t, err)
+			var events []db.LogEvent
+			for i := 0; i < 5; i++ {
+				ev := db.LogEvent{
+					ID:        fmt.Sprintf("id%d", i),
+					Timestamp: 1767225600123456789 + int64(i),
+					// int values larger than 32 bits are not truncated
+					Event:    1<<40 + i,
+					Username: "user àèé",
+					ASN:      4294967295,
+				}
+				require.NoError(t, w.Write(&ev))
+				events = append(events, ev)
+			}
+			require.NoError(t, w.Close())
+
+			f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			require.NoError(t, err)
+			assert.Equal(t, int64(5), f.NumRows())
+			assert.Len(t, f.RowGroups(), 3)
+			assert.Equal(t, "test", f.Metadata().CreatedBy)
+			assert.Equal(t, codec, f.Metadata().RowGroups[0].Columns[0].MetaData.Codec)
+
+			rows, err := parquet.Read[db.LogEvent](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			require.NoError(t, err)
+			assert.Equal(t, events, rows)
+		})
+	}
+}
+
+func TestEscapePartitionValue(t *testing.T) {
+	assert.Equal(t, "sftpgo1", escapePartitionValue("sftpgo1"))
+	assert.Equal(t, "node%2F1%3Da", escapePartitionValue("node/1=a"))
+}
@@ -0,0 +1,240 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package geoip resolves the client IPs using local MaxMind databases
+package geoip
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/oschwald/maxminddb-golang"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+// reloadDelay defines how long to wait, after the last change, before
+// reloading a database, so a file being written is not loaded
+var reloadDelay = 2 * time.Second
+
+// record defines the fields read from the databases, the country, city and
+// ASN databases contain a subset of them
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	ASN uint32 `maxminddb:"autonomous_system_number"`
+}
+
+// Resolver resolves the IP addresses using one or more MaxMind databases, for
+// example GeoLite2 City and ASN. The databases are reloaded when they change
+// on disk. It implements db.GeoIPResolver
+type Resolver struct {
+	paths   []string
+	mu      sync.RWMutex
+	readers []*maxminddb.Reader
+	watcher *fsnotify.Watcher
+	timers  map[string]*time.Timer
+	done    chan struct{}
+}
+
+// NewResolver returns a resolver for the specified database files
+func NewResolver(paths []string) (*Resolver, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("at least a GeoIP database is required")
+	}
+	r := &Resolver{
+		timers: make(map[string]*time.Timer),
+		done:   make(chan struct{}),
+	}
+	for _, p := range paths {
+		p, err := filepath.Abs(p)
+		if err != nil {
+			return nil, err
+		}
+		reader, err := open(p)
+		if err != nil {
+			r.closeReaders()
+			return nil, err
+		}
+		r.paths = append(r.paths, p)
+		r.readers = append(r.readers, reader)
+	}
+	if err := r.watch(); err != nil {
+		r.closeReaders()
+		return nil, fmt.Errorf("unable to watch the GeoIP databases: %w", err)
+	}
+	return r, nil
+}
+
+// open loads the database in memory instead of mapping it, so a database
+// overwritten in place cannot corrupt the current reader
+func open(path string) (*maxminddb.Reader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read GeoIP database %q: %w", path, err)
+	}
+	reader, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open GeoIP database %q: %w", path, err)
+	}
+	logger.AppLogger.Info("GeoIP database loaded", "path", path, "type", reader.Metadata.DatabaseType,
+		"build time", time.Unix(int64(reader.Metadata.BuildEpoch), 0).UTC())
+	return reader, nil
+}
+
+// watch watches the directories, so the databases replaced using a rename are
+// detected too
+func (r *Resolver) watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	var dirs []string
+	for _, p := range r.paths {
+		dir := filepath.Dir(p)
+		if slices.Contains(dirs, dir) {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return err
+		}
+		dirs = append(dirs, dir)
+	}
+	r.watcher = watcher
+	go r.handleEvents()
+	return nil
+}
+
+func (r *Resolver) handleEvents() {
+	for {
+		select {
+		case <-r.done:
+			return
+		case ev, ok := <-r.watcher.Events:
+			if !ok {
+				return
+			}
+			if !ev.Has(fsnotify.Create) && !ev.Has(fsnotify.Write) {
+				continue
+			}
+			idx := slices.Index(r.paths, filepath.Clean(ev.Name))
+			if idx < 0 {
+				continue
+			}
+			r.mu.Lock()
+			if t, ok := r.timers[ev.Name]; ok {
+				t.Stop()
+			}
+			r.timers[ev.Name] = time.AfterFunc(reloadDelay, func() {
+				r.reload(idx)
+			})
+			r.mu.Unlock()
+		case err, ok := <-r.watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.AppLogger.Warn("GeoIP databases watcher error", "error", err)
+		}
+	}
+}
+
+// reload replaces the database at the specified index, the current database
+// is kept if the new one cannot be opened
+func (r *Resolver) reload(idx int) {
+	reader, err := open(r.paths[idx])
+	if err != nil {
+		logger.AppLogger.Error("unable to reload GeoIP database, the previous version is used", "error", err)
+		return
+	}
+	r.mu.Lock()
+	select {
+	case <-r.done:
+		r.mu.Unlock()
+		reader.Close()
+		return
+	default:
+	}
+	previous := r.readers[idx]
+	r.readers[idx] = reader
+	r.mu.Unlock()
+
+	previous.Close()
+}
+
+// Lookup implements db.GeoIPResolver. The fields found in the databases are
+// merged, the first database wins for the fields found in more databases
+func (r *Resolver) Lookup(ip string) (db.GeoLocation, error) {
+	var location db.GeoLocation
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return location, fmt.Errorf("invalid IP address %q", ip)
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var errs []error
+	for _, reader := range r.readers {
+		var rec record
+		if err := reader.Lookup(parsed, &rec); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if location.CountryCode == "" {
+			location.CountryCode = rec.Country.ISOCode
+		}
+		if location.City == "" {
+			location.City = rec.City.Names["en"]
+		}
+		if location.ASN == 0 {
+			location.ASN = rec.ASN
+		}
+	}
+	return location, errors.Join(errs...)
+}
+
+// Close stops watching the databases and closes them
+func (r *Resolver) Close() error {
+	close(r.done)
+	err := r.watcher.Close()
+	r.mu.Lock()
+	for _, t := range r.timers {
+		t.Stop()
+	}
+	r.mu.Unlock()
+	r.closeReaders()
+	return err
+}
+
+func (r *Resolver) closeReaders() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, reader := range r.readers {
+		reader.Close()
+	}
+	r.readers = nil
+}
@@ -0,0 +1,227 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+)
+
+// mmdbWriter writes minimal IPv4 MaxMind databases for the tests, see
+// https://maxmind.github.io/MaxMind-DB/
+type mmdbWriter struct {
+	// nodes contains the records for each node: -1 is empty, values >= 0 are
+	// node indexes and values <= -2 are data offsets encoded as -(offset+2)
+	nodes [][2]int
+	data  bytes.Buffer
+}
+
+func (w *mmdbWriter) encodeControl(dataType, size int) {
+	var ctrl []byte
+	if dataType > 7 {
+		ctrl = []byte{0, byte(dataType - 7)}
+	} else {
+		ctrl = []byte{byte(dataType << 5)}
+	}
+	switch {
+	case size < 29:
+		ctrl[0] |= byte(size)
+		w.data.Write(ctrl)
+	default:
+		ctrl[0] |= 29
+		w.data.Write(ctrl)
+		w.data.WriteByte(byte(size - 29))
+	}
+}
+
+func (w *mmdbWriter) encode(value any) {
+	switch v := value.(type) {
+	case string:
+		w.encodeControl(2, len(v))
+		w.data.WriteString(v)
+	case uint16:
+		w.encodeControl(5, 2)
+		w.data.Write(binary.BigEndian.AppendUint16(nil, v))
+	case uint32:
+		w.encodeControl(6, 4)
+		w.data.Write(binary.BigEndian.AppendUint32(nil, v))
+	case uint64:
+		w.encodeControl(9, 8)
+		w.data.Write(binary.BigEndian.AppendUint64(nil, v))
+	case []any:
+		w.encodeControl(11, len(v))
+		for _, item := range v {
+			w.encode(item)
+		}
+	case map[string]any:
+		w.encodeControl(7, len(v))
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			w.encode(k)
+			w.encode(v[k])
+		}
+	default:
+		panic("unsupported type")
+	}
+}
+
+func (w *mmdbWriter) insert(cidr string, value map[string]any) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	offset := w.data.Len()
+	w.encode(value)
+	ones, _ := network.Mask.Size()
+	ip := network.IP.To4()
+	node := 0
+	for i := range ones {
+		bit := (ip[i/8] >> (7 - i%8)) & 1
+		if i == ones-1 {
+			w.nodes[node][bit] = -(offset + 2)
+			return
+		}
+		if w.nodes[node][bit] < 0 {
+			w.nodes = append(w.nodes, [2]int{-1, -1})
+			w.nodes[node][bit] = len(w.nodes) - 1
+		}
+		node = w.nodes[node][bit]
+	}
+}
+
+func (w *mmdbWriter) bytes(databaseType string) []byte {
+	var buf bytes.Buffer
+	nodeCount := len(w.nodes)
+	for _, node := range w.nodes {
+		for _, record := range node {
+			value := nodeCount
+			switch {
+			case record >= 0:
+				value = record
+			case record <= -2:
+				value = nodeCount + 16 + (-record - 2)
+			}
+			buf.Write([]byte{byte(value >> 16), byte(value >> 8), byte(value)})
+		}
+	}
+	buf.Write(make([]byte, 16))
+	buf.Write(w.data.Bytes())
+	buf.WriteString("\xAB\xCD\xEFMaxMind.com")
+	w.data.Reset()
+	w.encode(map[string]any{
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(24),
+		"ip_version":                  uint16(4),
+		"database_type":               databaseType,
+		"languages":                   []any{"en"},
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(time.Now().Unix()),
+		"description":                 map[string]any{"en": "test database"},
+	})
+	buf.Write(w.data.Bytes())
+	return buf.Bytes()
+}
+
+func writeTestDatabase(t *testing.T, path, databaseType string, networks map[string]map[string]any) {
+	w := &mmdbWriter{nodes: [][2]int{{-1, -1}}}
+	for cidr, value := range networks {
+		w.insert(cidr, value)
+	}
+	tmp := path + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, w.bytes(databaseType), 0600))
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+func cityRecord(country, city string) map[string]any {
+	return map[string]any{
+		"country": map[string]any{"iso_code": country},
+		"city":    map[string]any{"names": map[string]any{"en": city}},
+	}
+}
+
+func TestResolver(t *testing.T) {
+	reloadDelay = 100 * time.Millisecond
+	dir := t.TempDir()
+	cityPath := filepath.Join(dir, "GeoLite2-City.mmdb")
+	asnPath := filepath.Join(dir, "GeoLite2-ASN.mmdb")
+	writeTestDatabase(t, cityPath, "GeoLite2-City", map[string]map[string]any{
+		"81.2.69.0/24": cityRecord("GB", "London"),
+		"10.0.0.0/8":   {"country": map[string]any{"iso_code": "IT"}},
+	})
+	writeTestDatabase(t, asnPath, "GeoLite2-ASN", map[string]map[string]any{
+		"81.2.0.0/16": {"autonomous_system_number": uint32(20712),
+			"autonomous_system_organization": "Andrews & Arnold Ltd"},
+	})
+
+	_, err := NewResolver(nil)
+	assert.Error(t, err)
+	_, err = NewResolver([]string{filepath.Join(dir, "missing.mmdb")})
+	assert.Error(t, err)
+
+	r, err := NewResolver([]string{cityPath, asnPath})
+	require.NoError(t, err)
+	var _ db.GeoIPResolver = r
+
+	location, err := r.Lookup("81.2.69.160")
+	require.NoError(t, err)
+	assert.Equal(t, db.GeoLocation{CountryCode: "GB", City: "London", ASN: 20712}, location)
+	location, err = r.Lookup("10.1.2.3")
+	require.NoError(t, err)
+	assert.Equal(t, db.GeoLocation{CountryCode: "IT"}, location)
+	location, err = r.Lookup("192.168.1.1")
+	require.NoError(t, err)
+	assert.True(t, location.IsEmpty())
+	_, err = r.Lookup("invalid")
+	assert.Error(t, err)
+	// IPv6 addresses cannot be resolved using IPv4 only databases
+	_, err = r.Lookup("2001:db8::1")
+	assert.Error(t, err)
+
+	// the databases are reloaded when replaced
+	writeTestDatabase(t, cityPath, "GeoLite2-City", map[string]map[string]any{
+		"81.2.69.0/24": cityRecord("GB", "Manchester"),
+	})
+	assert.Eventually(t, func() bool {
+		location, err := r.Lookup("81.2.69.160")
+		return err == nil && location.City == "Manchester"
+	}, 5*time.Second, 50*time.Millisecond)
+	location, err = r.Lookup("10.1.2.3")
+	require.NoError(t, err)
+	assert.True(t, location.IsEmpty())
+	// invalid databases are ignored
+	require.NoError(t, os.WriteFile(cityPath, []byte("invalid"), 0600))
+	time.Sleep(3 * reloadDelay)
+	location, err = r.Lookup("81.2.69.160")
+	require.NoError(t, err)
+	assert.Equal(t, "Manchester", location.City)
+
+	require.NoError(t, r.Close())
+}
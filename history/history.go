@@ -0,0 +1,122 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package history prints the changelog for a provider object
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+)
+
+// Write writes a changelog for the specified provider events, that must refer
+// to the same object and be ordered by time. The stored diffs are used if
+// available, otherwise the diffs are computed from the consecutive versions
+func Write(w io.Writer, events []db.ProviderEvent) error {
+	var previous []byte
+	for _, ev := range events {
+		if err := writeHeader(w, &ev); err != nil {
+			return err
+		}
+		var lines []string
+		switch {
+		case ev.Action == "delete":
+			lines = append(lines, "object deleted")
+		case ev.ObjectDiff != nil || (previous != nil && json.Valid(ev.ObjectData)):
+			ops, err := getOperations(&ev, previous)
+			if err != nil {
+				lines = append(lines, fmt.Sprintf("unable to get changes: %v", err))
+			} else {
+				lines = append(lines, formatOperations(ops, previous)...)
+			}
+		case ev.Action == "add":
+			lines = append(lines, "object created")
+		default:
+			lines = append(lines, "changes not available")
+		}
+		for _, line := range lines {
+			if _, err := fmt.Fprintf(w, "    %s\n", line); err != nil {
+				return err
+			}
+		}
+		if json.Valid(ev.ObjectData) {
+			previous = ev.ObjectData
+		}
+	}
+	return nil
+}
+
+func writeHeader(w io.Writer, ev *db.ProviderEvent) error {
+	var sb strings.Builder
+	sb.WriteString(time.Unix(0, ev.Timestamp).UTC().Format(time.RFC3339Nano))
+	sb.WriteString(" ")
+	sb.WriteString(ev.Action)
+	if ev.Username != "" {
+		sb.WriteString(" by ")
+		sb.WriteString(ev.Username)
+	}
+	if ev.IP != "" {
+		sb.WriteString(" from ")
+		sb.WriteString(ev.IP)
+	}
+	if ev.Role != "" {
+		sb.WriteString(" role ")
+		sb.WriteString(ev.Role)
+	}
+	if ev.InstanceID != "" {
+		sb.WriteString(" instance ")
+		sb.WriteString(ev.InstanceID)
+	}
+	_, err := fmt.Fprintln(w, sb.String())
+	return err
+}
+
+func getOperations(ev *db.ProviderEvent, previous []byte) ([]db.PatchOperation, error) {
+	if ev.ObjectDiff != nil {
+		var ops []db.PatchOperation
+		err := json.Unmarshal(ev.ObjectDiff, &ops)
+		return ops, err
+	}
+	return db.DiffJSON(previous, ev.ObjectData)
+}
+
+func formatOperations(ops []db.PatchOperation, previous []byte) []string {
+	if len(ops) == 0 {
+		return []string{"no changes"}
+	}
+	lines := make([]string, 0, len(ops))
+	for _, op := range ops {
+		path := op.Path
+		if path == "" {
+			path = "/"
+		}
+		oldValue, hasOldValue := db.GetJSONPointer(previous, op.Path)
+		switch {
+		case op.Op == db.PatchOpRemove && hasOldValue:
+			lines = append(lines, fmt.Sprintf("%s %s (was %s)", op.Op, path, oldValue))
+		case op.Op == db.PatchOpRemove:
+			lines = append(lines, fmt.Sprintf("%s %s", op.Op, path))
+		case op.Op == db.PatchOpReplace && hasOldValue:
+			lines = append(lines, fmt.Sprintf("%s %s: %s -> %s", op.Op, path, oldValue, op.Value))
+		default:
+			lines = append(lines, fmt.Sprintf("%s %s: %s", op.Op, path, op.Value))
+		}
+	}
+	return lines
+}
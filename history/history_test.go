@@ -0,0 +1,95 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package history
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+)
+
+func TestWrite(t *testing.T) {
+	ts := time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC).UnixNano()
+	events := []db.ProviderEvent{
+		{
+			Timestamp:  ts,
+			Action:     "update",
+			Username:   "admin",
+			ObjectData: []byte(`{"username":"user1","status":1}`),
+		},
+		{
+			Timestamp:  ts + int64(time.Hour),
+			Action:     "update",
+			Username:   "admin",
+			IP:         "127.0.0.1",
+			Role:       "role1",
+			InstanceID: "sftpgo1",
+			ObjectData: []byte(`{"username":"user1","status":0,"home_dir":"/srv/user1"}`),
+			// the stored diff is used
+			ObjectDiff: []byte(`[{"op":"replace","path":"/status","value":0}]`),
+		},
+		{
+			Timestamp:  ts + 2*int64(time.Hour),
+			Action:     "update",
+			Username:   "admin",
+			ObjectData: []byte(`{"username":"user1","status":0,"description":"desc"}`),
+		},
+		{
+			Timestamp:  ts + 3*int64(time.Hour),
+			Action:     "update",
+			Username:   "admin",
+			ObjectData: []byte(`{"username":"user1","status":0,"description":"desc"}`),
+		},
+		{
+			Timestamp:  ts + 4*int64(time.Hour),
+			Action:     "delete",
+			Username:   "admin",
+			ObjectData: []byte(`{"username":"user1","status":0,"description":"desc"}`),
+		},
+		{
+			Timestamp:  ts + 5*int64(time.Hour),
+			Action:     "add",
+			Username:   "admin",
+			ObjectData: []byte(`invalid`),
+		},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, events))
+	expected := `2026-03-14T10:00:00Z update by admin
+    changes not available
+2026-03-14T11:00:00Z update by admin from 127.0.0.1 role role1 instance sftpgo1
+    replace /status: 1 -> 0
+2026-03-14T12:00:00Z update by admin
+    remove /home_dir (was "/srv/user1")
+    add /description: "desc"
+2026-03-14T13:00:00Z update by admin
+    no changes
+2026-03-14T14:00:00Z delete by admin
+    object deleted
+2026-03-14T15:00:00Z add by admin
+    object created
+`
+	assert.Equal(t, expected, buf.String())
+
+	buf.Reset()
+	events[0].ObjectDiff = []byte(`invalid`)
+	require.NoError(t, Write(&buf, events[:1]))
+	assert.Contains(t, buf.String(), "unable to get changes")
+}
@@ -0,0 +1,243 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package report generates activity reports from the stored events
+package report
+
+import (
+	"bytes"
+	"cmp"
+	"embed"
+	"encoding/csv"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+)
+
+// Supported formats
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatCSV      = "csv"
+)
+
+//go:embed templates
+var templatesFS embed.FS
+
+var defaultTemplates = map[string]string{
+	FormatMarkdown: "templates/user.md.tmpl",
+	FormatHTML:     "templates/user.html.tmpl",
+	FormatCSV:      "templates/user.csv.tmpl",
+}
+
+// UserConfig defines the configuration for a user report
+type UserConfig struct {
+	Username string
+	// From and To define the time range, To is excluded
+	From time.Time
+	To   time.Time
+	// DirDepth defines the number of path components used to group the
+	// virtual paths in directories, 0 means the parent directory
+	DirDepth int
+	// Limit defines the maximum number of directories, failed operations and
+	// login failures to include
+	Limit int
+}
+
+// UserReport defines the activity of a user within a time range
+type UserReport struct {
+	Username    string
+	From        time.Time
+	To          time.Time
+	Generated   time.Time
+	Actions     []db.ActionStats
+	Directories []db.PathStats
+	Protocols   []db.ValueCount
+	IPs         []db.ValueCount
+	// FailedOperations contains the most recent failed operations, use
+	// TotalFailures for the total
+	FailedOperations []db.FsEvent
+	LoginFailures    int64
+	// RecentLoginFailures contains the most recent login failures
+	RecentLoginFailures []db.LogEvent
+}
+
+// TotalFailures returns the number of failed filesystem operations
+func (r *UserReport) TotalFailures() int64 {
+	var total int64
+	for _, a := range r.Actions {
+		total += a.Failures
+	}
+	return total
+}
+
+// GetUser returns the activity report for a user
+func GetUser(config UserConfig) (*UserReport, error) {
+	if config.Limit <= 0 {
+		config.Limit = 10
+	}
+	r := &UserReport{
+		Username:  config.Username,
+		From:      config.From.UTC(),
+		To:        config.To.UTC(),
+		Generated: time.Now().UTC(),
+	}
+	var err error
+	if r.Actions, err = db.GetUserActionStats(config.Username, config.From, config.To); err != nil {
+		return nil, err
+	}
+	paths, err := db.GetUserPathStats(config.Username, config.From, config.To)
+	if err != nil {
+		return nil, err
+	}
+	r.Directories = groupDirectories(paths, config.DirDepth, config.Limit)
+	if r.Protocols, err = db.GetUserFsValueCounts(config.Username, "protocol", config.From, config.To); err != nil {
+		return nil, err
+	}
+	if r.IPs, err = db.GetUserFsValueCounts(config.Username, "ip", config.From, config.To); err != nil {
+		return nil, err
+	}
+	r.FailedOperations, err = db.GetUserFailedFsEvents(config.Username, config.From, config.To, config.Limit)
+	if err != nil {
+		return nil, err
+	}
+	r.LoginFailures, r.RecentLoginFailures, err = db.GetUserLoginFailures(config.Username, config.From, config.To,
+		config.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// getDirectory returns the directory for the specified virtual path
+func getDirectory(virtualPath string, depth int) string {
+	dir := path.Dir(path.Clean("/" + virtualPath))
+	if depth <= 0 || dir == "/" {
+		return dir
+	}
+	parts := strings.Split(dir[1:], "/")
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// groupDirectories groups the path totals by directory and returns the
+// directories with the most events
+func groupDirectories(paths []db.PathStats, depth, limit int) []db.PathStats {
+	dirs := make(map[string]*db.PathStats)
+	for _, p := range paths {
+		dir := getDirectory(p.Path, depth)
+		stats, ok := dirs[dir]
+		if !ok {
+			stats = &db.PathStats{Path: dir}
+			dirs[dir] = stats
+		}
+		stats.Count += p.Count
+		stats.Bytes += p.Bytes
+	}
+	result := make([]db.PathStats, 0, len(dirs))
+	for _, stats := range dirs {
+		result = append(result, *stats)
+	}
+	slices.SortFunc(result, func(a, b db.PathStats) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Path, b.Path)
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+func formatTimestamp(timestamp int64) string {
+	return time.Unix(0, timestamp).UTC().Format(time.RFC3339)
+}
+
+func formatCSV(values ...any) (string, error) {
+	record := make([]string, 0, len(values))
+	for _, v := range values {
+		record = append(record, fmt.Sprint(v))
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(record); err != nil {
+		return "", err
+	}
+	w.Flush()
+	return strings.TrimSuffix(buf.String(), "\n"), w.Error()
+}
+
+var markdownReplacer = strings.NewReplacer("|", "\\|", "\r", " ", "\n", " ")
+
+// templateFuncs are available to the default and custom templates
+var templateFuncs = map[string]any{
+	"timestamp": formatTimestamp,
+	"time": func(t time.Time) string {
+		return t.Format(time.RFC3339)
+	},
+	"logEvent": db.GetLogEventName,
+	"csv":      formatCSV,
+	"markdown": markdownReplacer.Replace,
+}
+
+type template interface {
+	Execute(w io.Writer, data any) error
+}
+
+func loadTemplate(format, templatePath string) (template, error) {
+	var content []byte
+	var err error
+	if templatePath != "" {
+		content, err = os.ReadFile(templatePath)
+	} else {
+		name, ok := defaultTemplates[format]
+		if !ok {
+			return nil, fmt.Errorf("unsupported format %q", format)
+		}
+		content, err = templatesFS.ReadFile(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case FormatHTML:
+		return htmltemplate.New("report").Funcs(templateFuncs).Parse(string(content))
+	case FormatMarkdown, FormatCSV:
+		return texttemplate.New("report").Funcs(templateFuncs).Parse(string(content))
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// Write writes the report in the specified format. If templatePath is not
+// empty, the template is read from this file instead of the default one. HTML
+// templates are parsed using html/template, so the values are escaped
+func (r *UserReport) Write(w io.Writer, format, templatePath string) error {
+	tmpl, err := loadTemplate(format, templatePath)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, r)
+}
@@ -0,0 +1,124 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package report
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+)
+
+func getTestReport() *UserReport {
+	ts := time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC)
+	return &UserReport{
+		Username:  "user1",
+		From:      time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:        time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+		Generated: ts,
+		Actions: []db.ActionStats{
+			{Action: "upload", Count: 2, Bytes: 300, Failures: 1},
+			{Action: "delete", Count: 1, Bytes: 100},
+		},
+		Directories: []db.PathStats{
+			{Path: "/in|box", Count: 3, Bytes: 400},
+		},
+		Protocols: []db.ValueCount{
+			{Value: "SFTP", Count: 3},
+		},
+		IPs: []db.ValueCount{
+			{Value: "127.0.0.1", Count: 3},
+		},
+		FailedOperations: []db.FsEvent{
+			{Timestamp: ts.UnixNano(), Action: "upload", VirtualPath: "/in|box/file.txt", FileSize: 100,
+				Status: 3, Protocol: "SFTP", IP: "127.0.0.1"},
+		},
+		LoginFailures: 2,
+		RecentLoginFailures: []db.LogEvent{
+			{Timestamp: ts.UnixNano(), Event: 1, Protocol: "SSH", IP: "127.0.0.1", Message: "<invalid>, credentials"},
+		},
+	}
+}
+
+func TestGroupDirectories(t *testing.T) {
+	assert.Equal(t, "/", getDirectory("/file.txt", 0))
+	assert.Equal(t, "/", getDirectory("file.txt", 2))
+	assert.Equal(t, "/a/b/c", getDirectory("/a/b/c/file.txt", 0))
+	assert.Equal(t, "/a/b", getDirectory("/a/b/c/file.txt", 2))
+	assert.Equal(t, "/a", getDirectory("/a/file.txt", 2))
+
+	paths := []db.PathStats{
+		{Path: "/a/b/file1", Count: 2, Bytes: 10},
+		{Path: "/a/c/file2", Count: 3, Bytes: 20},
+		{Path: "/d/file3", Count: 4, Bytes: 30},
+		{Path: "/file4", Count: 1, Bytes: 40},
+	}
+	assert.Equal(t, []db.PathStats{
+		{Path: "/a", Count: 5, Bytes: 30},
+		{Path: "/d", Count: 4, Bytes: 30},
+	}, groupDirectories(paths, 1, 2))
+	assert.Equal(t, []db.PathStats{
+		{Path: "/d", Count: 4, Bytes: 30},
+		{Path: "/a/c", Count: 3, Bytes: 20},
+		{Path: "/a/b", Count: 2, Bytes: 10},
+		{Path: "/", Count: 1, Bytes: 40},
+	}, groupDirectories(paths, 0, 10))
+}
+
+func TestWrite(t *testing.T) {
+	r := getTestReport()
+	assert.Equal(t, int64(1), r.TotalFailures())
+
+	var buf bytes.Buffer
+	require.NoError(t, r.Write(&buf, FormatMarkdown, ""))
+	assert.Contains(t, buf.String(), "From 2026-03-01T00:00:00Z to 2026-04-01T00:00:00Z")
+	assert.Contains(t, buf.String(), "| upload | 2 | 300 | 1 |")
+	assert.Contains(t, buf.String(), `| /in\|box | 3 | 400 |`)
+	assert.Contains(t, buf.String(), `| 2026-03-14T10:00:00Z | upload | /in\|box/file.txt | SFTP | 127.0.0.1 | 3 |`)
+	assert.Contains(t, buf.String(), "| 2026-03-14T10:00:00Z | login_failed | SSH | 127.0.0.1 | <invalid>, credentials |")
+
+	buf.Reset()
+	require.NoError(t, r.Write(&buf, FormatHTML, ""))
+	assert.Contains(t, buf.String(), "<td>&lt;invalid&gt;, credentials</td>")
+	assert.NotContains(t, buf.String(), "<invalid>")
+
+	buf.Reset()
+	require.NoError(t, r.Write(&buf, FormatCSV, ""))
+	assert.Equal(t, `section,name,count,bytes,failures,time,details
+action,upload,2,300,1,,
+action,delete,1,100,0,,
+directory,/in|box,3,400,,,
+protocol,SFTP,3,,,,
+ip,127.0.0.1,3,,,,
+failed_operation,upload,1,100,3,2026-03-14T10:00:00Z,/in|box/file.txt SFTP 127.0.0.1
+login_failure,login_failed,1,,,2026-03-14T10:00:00Z,"SSH 127.0.0.1 <invalid>, credentials"
+`, buf.String())
+
+	templatePath := filepath.Join(t.TempDir(), "custom.tmpl")
+	require.NoError(t, os.WriteFile(templatePath, []byte(`{{.Username}}: {{.TotalFailures}}`), 0600))
+	buf.Reset()
+	require.NoError(t, r.Write(&buf, FormatMarkdown, templatePath))
+	assert.Equal(t, "user1: 1", buf.String())
+
+	assert.Error(t, r.Write(&buf, "pdf", ""))
+	assert.Error(t, r.Write(&buf, "pdf", templatePath))
+	assert.Error(t, r.Write(&buf, FormatCSV, filepath.Join(t.TempDir(), "missing")))
+}
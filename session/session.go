@@ -0,0 +1,165 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package session reconstructs the user sessions from the fs and log events
+package session
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+)
+
+// fsStatusOK is the SFTPGo status for successful fs events
+const fsStatusOK = 1
+
+// Entry defines a timeline entry, either Fs or Log is set
+type Entry struct {
+	Timestamp int64
+	Fs        *db.FsEvent
+	Log       *db.LogEvent
+}
+
+// Session defines a session with its timeline
+type Session struct {
+	db.SessionSummary
+	Timeline []Entry
+}
+
+// Duration returns the session duration
+func (s *Session) Duration() time.Duration {
+	return time.Duration(s.End - s.Start)
+}
+
+// Get returns the specified session. The log events for the same username, IP
+// and protocol within the session time range, extended by the specified
+// window in both directions, are added to the timeline
+func Get(sessionID string, window time.Duration) (*Session, error) {
+	summary, err := db.GetSessionSummary(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if summary.SessionID == "" {
+		return nil, fmt.Errorf("session %q not found", sessionID)
+	}
+	fsEvents, err := db.GetSessionFsEvents(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	logEvents, err := db.GetSessionLogEvents(summary.Username, summary.IP, summary.Protocol,
+		summary.Start-int64(window), summary.End+int64(window))
+	if err != nil {
+		return nil, err
+	}
+	return New(summary, fsEvents, logEvents), nil
+}
+
+// New returns a session merging the specified events in a timeline ordered by
+// time, log events come first for the same timestamp
+func New(summary db.SessionSummary, fsEvents []db.FsEvent, logEvents []db.LogEvent) *Session {
+	s := &Session{
+		SessionSummary: summary,
+		Timeline:       make([]Entry, 0, len(fsEvents)+len(logEvents)),
+	}
+	for idx := range logEvents {
+		s.Timeline = append(s.Timeline, Entry{Timestamp: logEvents[idx].Timestamp, Log: &logEvents[idx]})
+	}
+	for idx := range fsEvents {
+		s.Timeline = append(s.Timeline, Entry{Timestamp: fsEvents[idx].Timestamp, Fs: &fsEvents[idx]})
+	}
+	slices.SortStableFunc(s.Timeline, func(a, b Entry) int {
+		return cmp.Compare(a.Timestamp, b.Timestamp)
+	})
+	return s
+}
+
+func formatTime(timestamp int64) string {
+	return time.Unix(0, timestamp).UTC().Format(time.RFC3339Nano)
+}
+
+func formatFsEvent(ev *db.FsEvent) string {
+	var sb strings.Builder
+	sb.WriteString(ev.Action)
+	if ev.SSHCmd != "" {
+		sb.WriteString(" ")
+		sb.WriteString(ev.SSHCmd)
+	}
+	if ev.VirtualPath != "" {
+		sb.WriteString(" ")
+		sb.WriteString(ev.VirtualPath)
+	}
+	if ev.VirtualTargetPath != "" {
+		sb.WriteString(" -> ")
+		sb.WriteString(ev.VirtualTargetPath)
+	}
+	if ev.FileSize > 0 {
+		fmt.Fprintf(&sb, " %d bytes", ev.FileSize)
+	}
+	if ev.Elapsed > 0 {
+		fmt.Fprintf(&sb, " in %s", time.Duration(ev.Elapsed)*time.Millisecond)
+	}
+	if ev.Status != fsStatusOK {
+		fmt.Fprintf(&sb, " failed, status %d", ev.Status)
+	}
+	return sb.String()
+}
+
+func formatLogEvent(ev *db.LogEvent) string {
+	text := db.GetLogEventName(ev.Event)
+	if ev.Message != "" {
+		text += ": " + ev.Message
+	}
+	return text
+}
+
+// Write writes the session totals and timeline
+func (s *Session) Write(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "Session %s\nUser: %s, IP: %s, Protocol: %s\nStart: %s, End: %s, Duration: %s\n"+
+		"Uploaded: %d bytes, Downloaded: %d bytes, Files: %d, Failures: %d\n\n",
+		s.SessionID, s.Username, s.IP, s.Protocol, formatTime(s.Start), formatTime(s.End), s.Duration(),
+		s.BytesUploaded, s.BytesDownloaded, s.Files, s.Failures)
+	if err != nil {
+		return err
+	}
+	for _, entry := range s.Timeline {
+		var text string
+		if entry.Fs != nil {
+			text = formatFsEvent(entry.Fs)
+		} else {
+			text = formatLogEvent(entry.Log)
+		}
+		if _, err := fmt.Fprintf(w, "%s %s\n", formatTime(entry.Timestamp), text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteTop writes the specified session totals as a table
+func WriteTop(w io.Writer, sessions []db.SessionSummary) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SESSION\tUSERNAME\tIP\tPROTOCOL\tSTART\tDURATION\tUPLOADED\tDOWNLOADED\tFILES\tFAILURES")
+	for _, s := range sessions {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\n", s.SessionID, s.Username, s.IP, s.Protocol,
+			formatTime(s.Start), time.Duration(s.End-s.Start), s.BytesUploaded, s.BytesDownloaded, s.Files,
+			s.Failures)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,103 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package session
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+)
+
+func TestWrite(t *testing.T) {
+	ts := time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC).UnixNano()
+	summary := db.SessionSummary{
+		SessionID:       "sid",
+		Username:        "user1",
+		IP:              "127.0.0.1",
+		Protocol:        "SFTP",
+		Start:           ts,
+		End:             ts + int64(time.Minute),
+		BytesUploaded:   100,
+		BytesDownloaded: 50,
+		Files:           2,
+		Failures:        1,
+	}
+	fsEvents := []db.FsEvent{
+		{
+			Timestamp:   ts + int64(time.Second),
+			Action:      "upload",
+			VirtualPath: "/file.txt",
+			FileSize:    100,
+			Elapsed:     1000,
+			Status:      1,
+		},
+		{
+			Timestamp:         ts + int64(30*time.Second),
+			Action:            "rename",
+			VirtualPath:       "/file.txt",
+			VirtualTargetPath: "/dir/file.txt",
+			Status:            2,
+		},
+		{
+			Timestamp:   ts + int64(time.Minute),
+			Action:      "download",
+			VirtualPath: "/dir/file.txt",
+			FileSize:    50,
+			Status:      1,
+		},
+	}
+	logEvents := []db.LogEvent{
+		{
+			Timestamp: ts - int64(time.Second),
+			Event:     1,
+			Message:   "invalid credentials",
+		},
+		{
+			Timestamp: ts + int64(time.Minute),
+			Event:     5,
+		},
+	}
+	s := New(summary, fsEvents, logEvents)
+	require.Len(t, s.Timeline, 5)
+	assert.Equal(t, time.Minute, s.Duration())
+	// log events come first for the same timestamp
+	assert.NotNil(t, s.Timeline[3].Log)
+	assert.NotNil(t, s.Timeline[4].Fs)
+
+	var buf bytes.Buffer
+	require.NoError(t, s.Write(&buf))
+	assert.Equal(t, `Session sid
+User: user1, IP: 127.0.0.1, Protocol: SFTP
+Start: 2026-03-14T10:00:00Z, End: 2026-03-14T10:01:00Z, Duration: 1m0s
+Uploaded: 100 bytes, Downloaded: 50 bytes, Files: 2, Failures: 1
+
+2026-03-14T09:59:59Z login_failed: invalid credentials
+2026-03-14T10:00:01Z upload /file.txt 100 bytes in 1s
+2026-03-14T10:00:30Z rename /file.txt -> /dir/file.txt failed, status 2
+2026-03-14T10:01:00Z login_ok
+2026-03-14T10:01:00Z download /dir/file.txt 50 bytes
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, WriteTop(&buf, []db.SessionSummary{summary}))
+	assert.Equal(t, `SESSION  USERNAME  IP         PROTOCOL  START                 DURATION  UPLOADED  DOWNLOADED  FILES  FAILURES
+sid      user1     127.0.0.1  SFTP      2026-03-14T10:00:00Z  1m0s      100       50          2      1
+`, buf.String())
+}
@@ -0,0 +1,118 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package jsonl implements a sink that appends the events to a file, one JSON
+// object per line
+package jsonl
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+)
+
+// Config defines the configuration for the JSONL sink
+type Config struct {
+	// Path defines the file the events are appended to, it is created if
+	// it does not exist
+	Path string
+}
+
+type fsEventLine struct {
+	EventType string `json:"event_type"`
+	*db.FsEvent
+}
+
+type providerEventLine struct {
+	EventType string `json:"event_type"`
+	*db.ProviderEvent
+}
+
+type logEventLine struct {
+	EventType string `json:"event_type"`
+	*db.LogEvent
+}
+
+// Sink appends the events to a JSONL file
+type Sink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewSink returns a new JSONL sink
+func NewSink(config Config) (*Sink, error) {
+	if config.Path == "" {
+		return nil, errors.New("a file path is required")
+	}
+	file, err := os.OpenFile(config.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &Sink{
+		file: file,
+	}, nil
+}
+
+// Name implements db.Sink
+func (s *Sink) Name() string {
+	return "jsonl"
+}
+
+func (s *Sink) write(ev any) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return errors.New("jsonl sink closed")
+	}
+	_, err = s.file.Write(data)
+	return err
+}
+
+// WriteFsEvent implements db.Sink
+func (s *Sink) WriteFsEvent(ev *db.FsEvent) error {
+	return s.write(fsEventLine{EventType: db.EventTypeFs, FsEvent: ev})
+}
+
+// WriteProviderEvent implements db.Sink
+func (s *Sink) WriteProviderEvent(ev *db.ProviderEvent) error {
+	return s.write(providerEventLine{EventType: db.EventTypeProvider, ProviderEvent: ev})
+}
+
+// WriteLogEvent implements db.Sink
+func (s *Sink) WriteLogEvent(ev *db.LogEvent) error {
+	return s.write(logEventLine{EventType: db.EventTypeLog, LogEvent: ev})
+}
+
+// Close closes the underlying file
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,109 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package jsonl
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+)
+
+func TestSink(t *testing.T) {
+	_, err := NewSink(Config{})
+	assert.Error(t, err)
+	_, err = NewSink(Config{Path: filepath.Join(t.TempDir(), "missing", "events.jsonl")})
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink, err := NewSink(Config{Path: path})
+	require.NoError(t, err)
+	assert.Equal(t, "jsonl", sink.Name())
+
+	fsEvent := &db.FsEvent{
+		ID:          "fs-id",
+		Timestamp:   time.Now().UnixNano(),
+		Action:      "upload",
+		Username:    "user",
+		VirtualPath: "/file.txt",
+		FileSize:    100,
+		Status:      1,
+	}
+	providerEvent := &db.ProviderEvent{
+		ID:         "provider-id",
+		Timestamp:  time.Now().UnixNano(),
+		Action:     "add",
+		ObjectType: "user",
+		ObjectName: "user",
+		ObjectData: []byte(`{"username":"user"}`),
+	}
+	logEvent := &db.LogEvent{
+		ID:        "log-id",
+		Timestamp: time.Now().UnixNano(),
+		Event:     1,
+		Message:   "login failed",
+	}
+	require.NoError(t, sink.WriteFsEvent(fsEvent))
+	require.NoError(t, sink.WriteProviderEvent(providerEvent))
+	require.NoError(t, sink.WriteLogEvent(logEvent))
+	require.NoError(t, sink.Close())
+	assert.Error(t, sink.WriteFsEvent(fsEvent))
+	assert.NoError(t, sink.Close())
+	// the events are appended to the existing file
+	sink, err = NewSink(Config{Path: path})
+	require.NoError(t, err)
+	require.NoError(t, sink.WriteFsEvent(fsEvent))
+	require.NoError(t, sink.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var lines []map[string]json.RawMessage
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var line map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	require.NoError(t, scanner.Err())
+	require.Len(t, lines, 4)
+	for idx, eventType := range []string{db.EventTypeFs, db.EventTypeProvider, db.EventTypeLog, db.EventTypeFs} {
+		assert.Equal(t, `"`+eventType+`"`, string(lines[idx]["event_type"]))
+	}
+
+	var fsEventRead db.FsEvent
+	data, err := json.Marshal(lines[0])
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &fsEventRead))
+	assert.Equal(t, *fsEvent, fsEventRead)
+	var providerEventRead db.ProviderEvent
+	data, err = json.Marshal(lines[1])
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &providerEventRead))
+	assert.Equal(t, *providerEvent, providerEventRead)
+	var logEventRead db.LogEvent
+	data, err = json.Marshal(lines[2])
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &logEventRead))
+	assert.Equal(t, *logEvent, logEventRead)
+}
@@ -0,0 +1,249 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package kafka implements a sink that publishes the events to Apache Kafka
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+// Supported partitioning keys
+const (
+	PartitionKeyUsername   = "username"
+	PartitionKeyInstanceID = "instance_id"
+)
+
+// Supported SASL mechanisms
+const (
+	SASLMechanismPlain       = "PLAIN"
+	SASLMechanismScramSHA256 = "SCRAM-SHA-256"
+	SASLMechanismScramSHA512 = "SCRAM-SHA-512"
+)
+
+const (
+	fsEventsTopic       = "fs-events"
+	providerEventsTopic = "provider-events"
+	logEventsTopic      = "log-events"
+)
+
+// Config defines the configuration for the Kafka sink
+type Config struct {
+	// Brokers defines the bootstrap brokers as host:port
+	Brokers []string
+	// TopicPrefix is prepended to the topic names: fs-events,
+	// provider-events and log-events
+	TopicPrefix string
+	// PartitionKey defines the record key: username or instance_id
+	PartitionKey string
+	// Acks defines the required acknowledgments: 0 none, 1 leader only,
+	// -1 all in-sync replicas
+	Acks int
+	// TLSConfig enables TLS if not nil
+	TLSConfig *tls.Config
+	// SASLMechanism enables SASL authentication if not empty
+	SASLMechanism string
+	SASLUsername  string
+	SASLPassword  string
+	// Timeout defines the network timeout and the maximum time to deliver
+	// a record, including the retries
+	Timeout  time.Duration
+	ClientID string
+}
+
+func (c *Config) validate() error {
+	if len(c.Brokers) == 0 {
+		return errors.New("at least a broker is required")
+	}
+	switch c.PartitionKey {
+	case "":
+		c.PartitionKey = PartitionKeyUsername
+	case PartitionKeyUsername, PartitionKeyInstanceID:
+	default:
+		return fmt.Errorf("unsupported partition key %q", c.PartitionKey)
+	}
+	if c.Acks < -1 || c.Acks > 1 {
+		return fmt.Errorf("invalid acks %d", c.Acks)
+	}
+	switch c.SASLMechanism {
+	case "", SASLMechanismPlain, SASLMechanismScramSHA256, SASLMechanismScramSHA512:
+	default:
+		return fmt.Errorf("unsupported SASL mechanism %q", c.SASLMechanism)
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 10 * time.Second
+	}
+	if c.ClientID == "" {
+		c.ClientID = "sftpgo-plugin-eventstore"
+	}
+	return nil
+}
+
+func (c *Config) getSASLMechanism() sasl.Mechanism {
+	switch c.SASLMechanism {
+	case SASLMechanismPlain:
+		return plain.Auth{User: c.SASLUsername, Pass: c.SASLPassword}.AsMechanism()
+	case SASLMechanismScramSHA256:
+		return scram.Auth{User: c.SASLUsername, Pass: c.SASLPassword}.AsSha256Mechanism()
+	case SASLMechanismScramSHA512:
+		return scram.Auth{User: c.SASLUsername, Pass: c.SASLPassword}.AsSha512Mechanism()
+	default:
+		return nil
+	}
+}
+
+func (c *Config) getClientOptions() []kgo.Opt {
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(c.Brokers...),
+		kgo.ClientID(c.ClientID),
+		kgo.DialTimeout(c.Timeout),
+		kgo.ProduceRequestTimeout(c.Timeout),
+		kgo.AllowAutoTopicCreation(),
+		kgo.WithLogger(kgoLogger{}),
+	}
+	switch c.Acks {
+	case 0:
+		opts = append(opts, kgo.RequiredAcks(kgo.NoAck()), kgo.DisableIdempotentWrite())
+	case 1:
+		opts = append(opts, kgo.RequiredAcks(kgo.LeaderAck()), kgo.DisableIdempotentWrite())
+	default:
+		opts = append(opts, kgo.RequiredAcks(kgo.AllISRAcks()))
+	}
+	if c.TLSConfig != nil {
+		opts = append(opts, kgo.DialTLSConfig(c.TLSConfig))
+	}
+	if mechanism := c.getSASLMechanism(); mechanism != nil {
+		opts = append(opts, kgo.SASL(mechanism))
+	}
+	return opts
+}
+
+// kgoLogger forwards the Kafka client warnings and errors to the app logger
+type kgoLogger struct{}
+
+func (kgoLogger) Level() kgo.LogLevel {
+	return kgo.LogLevelWarn
+}
+
+func (kgoLogger) Log(level kgo.LogLevel, msg string, keyvals ...any) {
+	if level == kgo.LogLevelError {
+		logger.AppLogger.Error("kafka: "+msg, keyvals...)
+		return
+	}
+	logger.AppLogger.Warn("kafka: "+msg, keyvals...)
+}
+
+// Sink publishes the events as JSON to per-type Kafka topics. The records
+// produced concurrently are batched by the Kafka client, each write waits
+// for the acknowledgment of its own record
+type Sink struct {
+	config *Config
+	client *kgo.Client
+}
+
+// NewSink returns a new Kafka sink
+func NewSink(config Config) (*Sink, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	client, err := kgo.NewClient(config.getClientOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create Kafka client: %w", err)
+	}
+	return &Sink{
+		config: &config,
+		client: client,
+	}, nil
+}
+
+// Name implements db.Sink
+func (s *Sink) Name() string {
+	return "kafka"
+}
+
+func (s *Sink) getKey(username, instanceID string) []byte {
+	key := username
+	if s.config.PartitionKey == PartitionKeyInstanceID {
+		key = instanceID
+	}
+	if key == "" {
+		return nil
+	}
+	return []byte(key)
+}
+
+func (s *Sink) getRecord(topic string, key []byte, timestamp int64, ev any) (*kgo.Record, error) {
+	value, err := json.Marshal(ev)
+	if err != nil {
+		return nil, err
+	}
+	return &kgo.Record{
+		Topic:     s.config.TopicPrefix + topic,
+		Key:       key,
+		Value:     value,
+		Timestamp: time.Unix(0, timestamp),
+	}, nil
+}
+
+func (s *Sink) publish(topic string, key []byte, timestamp int64, ev any) error {
+	record, err := s.getRecord(topic, key, timestamp, ev)
+	if err != nil {
+		return err
+	}
+	// the record delivery timeout is not used, the client computes it from
+	// the record timestamp, which is the event time
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+
+	return s.client.ProduceSync(ctx, record).FirstErr()
+}
+
+// WriteFsEvent implements db.Sink
+func (s *Sink) WriteFsEvent(ev *db.FsEvent) error {
+	return s.publish(fsEventsTopic, s.getKey(ev.Username, ev.InstanceID), ev.Timestamp, ev)
+}
+
+// WriteProviderEvent implements db.Sink
+func (s *Sink) WriteProviderEvent(ev *db.ProviderEvent) error {
+	return s.publish(providerEventsTopic, s.getKey(ev.Username, ev.InstanceID), ev.Timestamp, ev)
+}
+
+// WriteLogEvent implements db.Sink
+func (s *Sink) WriteLogEvent(ev *db.LogEvent) error {
+	return s.publish(logEventsTopic, s.getKey(ev.Username, ev.InstanceID), ev.Timestamp, ev)
+}
+
+// Close flushes the buffered records, waiting at most for the given timeout,
+// and closes the broker connections
+func (s *Sink) Close(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := s.client.Flush(ctx)
+	s.client.Close()
+	return err
+}
@@ -0,0 +1,215 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+)
+
+func TestConfigValidation(t *testing.T) {
+	_, err := NewSink(Config{})
+	assert.Error(t, err)
+	_, err = NewSink(Config{Brokers: []string{"127.0.0.1:9092"}, PartitionKey: "ip"})
+	assert.Error(t, err)
+	_, err = NewSink(Config{Brokers: []string{"127.0.0.1:9092"}, Acks: 2})
+	assert.Error(t, err)
+	_, err = NewSink(Config{Brokers: []string{"127.0.0.1:9092"}, SASLMechanism: "GSSAPI"})
+	assert.Error(t, err)
+	s, err := NewSink(Config{Brokers: []string{"127.0.0.1:9092"}})
+	require.NoError(t, err)
+	assert.Equal(t, PartitionKeyUsername, s.config.PartitionKey)
+	assert.Equal(t, "kafka", s.Name())
+	s.client.Close()
+
+	for _, mechanism := range []string{SASLMechanismPlain, SASLMechanismScramSHA256, SASLMechanismScramSHA512} {
+		c := Config{SASLMechanism: mechanism, SASLUsername: "user", SASLPassword: "pass"}
+		m := c.getSASLMechanism()
+		require.NotNil(t, m)
+		assert.Equal(t, mechanism, m.Name())
+	}
+	for _, acks := range []int{-1, 0, 1} {
+		s, err := NewSink(Config{Brokers: []string{"127.0.0.1:9092"}, Acks: acks, SASLMechanism: SASLMechanismPlain})
+		require.NoError(t, err)
+		s.client.Close()
+	}
+}
+
+func TestRecord(t *testing.T) {
+	s := &Sink{
+		config: &Config{
+			TopicPrefix:  "sftpgo-",
+			PartitionKey: PartitionKeyUsername,
+		},
+	}
+	timestamp := time.Now().UnixNano()
+	ev := &db.FsEvent{
+		ID:         "id",
+		Timestamp:  timestamp,
+		Action:     "upload",
+		Username:   "user1",
+		InstanceID: "sftpgo1",
+	}
+	r, err := s.getRecord(fsEventsTopic, s.getKey(ev.Username, ev.InstanceID), ev.Timestamp, ev)
+	require.NoError(t, err)
+	assert.Equal(t, "sftpgo-fs-events", r.Topic)
+	assert.Equal(t, []byte("user1"), r.Key)
+	assert.Equal(t, time.Unix(0, timestamp), r.Timestamp)
+	var decoded db.FsEvent
+	require.NoError(t, json.Unmarshal(r.Value, &decoded))
+	assert.Equal(t, "upload", decoded.Action)
+	// no username, the key must be null
+	assert.Nil(t, s.getKey("", "sftpgo1"))
+
+	s.config.PartitionKey = PartitionKeyInstanceID
+	assert.Equal(t, []byte("sftpgo1"), s.getKey("user1", "sftpgo1"))
+	assert.Nil(t, s.getKey("user1", ""))
+}
+
+func TestSinkBrokerUnavailable(t *testing.T) {
+	s, err := NewSink(Config{
+		Brokers: []string{"127.0.0.1:1"},
+		Timeout: time.Second,
+	})
+	require.NoError(t, err)
+
+	err = s.WriteFsEvent(&db.FsEvent{Username: "user"})
+	assert.Error(t, err)
+	assert.NoError(t, s.Close(time.Second))
+}
+
+func newTestCluster(t *testing.T, opts ...kfake.Opt) []string {
+	cluster, err := kfake.NewCluster(append([]kfake.Opt{
+		kfake.AllowAutoTopicCreation(),
+		kfake.DefaultNumPartitions(3),
+	}, opts...)...)
+	require.NoError(t, err)
+	t.Cleanup(cluster.Close)
+	return cluster.ListenAddrs()
+}
+
+func TestSink(t *testing.T) {
+	brokers := newTestCluster(t)
+	prefix := "test-"
+	s, err := NewSink(Config{
+		Brokers:     brokers,
+		TopicPrefix: prefix,
+		Acks:        -1,
+	})
+	require.NoError(t, err)
+
+	timestamp := time.Now().UnixNano()
+	for _, username := range []string{"user1", "user2", "user1"} {
+		err = s.WriteFsEvent(&db.FsEvent{
+			ID:        "id",
+			Timestamp: timestamp,
+			Action:    "upload",
+			Username:  username,
+		})
+		require.NoError(t, err)
+	}
+	err = s.WriteProviderEvent(&db.ProviderEvent{
+		Username:   "admin",
+		ObjectType: "user",
+		ObjectData: []byte(`{"a":"b"}`),
+	})
+	require.NoError(t, err)
+	// the delivery timeout does not depend on the event time
+	err = s.WriteLogEvent(&db.LogEvent{
+		Event: 1,
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Close(time.Second))
+
+	consumer, err := kgo.NewClient(
+		kgo.SeedBrokers(brokers...),
+		kgo.ConsumeTopics(prefix+fsEventsTopic, prefix+providerEventsTopic, prefix+logEventsTopic),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+	)
+	require.NoError(t, err)
+	defer consumer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	records := make(map[string][]*kgo.Record)
+	numRecords := 0
+	for numRecords < 5 {
+		fetches := consumer.PollFetches(ctx)
+		require.NoError(t, ctx.Err())
+		fetches.EachRecord(func(r *kgo.Record) {
+			records[r.Topic] = append(records[r.Topic], r)
+			numRecords++
+		})
+	}
+	fsRecords := records[prefix+fsEventsTopic]
+	require.Len(t, fsRecords, 3)
+	partitions := make(map[string]int32)
+	for _, r := range fsRecords {
+		var ev db.FsEvent
+		require.NoError(t, json.Unmarshal(r.Value, &ev))
+		assert.Equal(t, string(r.Key), ev.Username)
+		assert.Equal(t, "upload", ev.Action)
+		assert.Equal(t, time.Unix(0, timestamp).UnixMilli(), r.Timestamp.UnixMilli())
+		if p, ok := partitions[ev.Username]; ok {
+			assert.Equal(t, p, r.Partition)
+		}
+		partitions[ev.Username] = r.Partition
+	}
+	require.Len(t, records[prefix+providerEventsTopic], 1)
+	var providerEvent db.ProviderEvent
+	require.NoError(t, json.Unmarshal(records[prefix+providerEventsTopic][0].Value, &providerEvent))
+	assert.Equal(t, []byte(`{"a":"b"}`), providerEvent.ObjectData)
+	require.Len(t, records[prefix+logEventsTopic], 1)
+	assert.Nil(t, records[prefix+logEventsTopic][0].Key)
+}
+
+func TestSinkSASL(t *testing.T) {
+	for _, mechanism := range []string{SASLMechanismPlain, SASLMechanismScramSHA256, SASLMechanismScramSHA512} {
+		t.Run(mechanism, func(t *testing.T) {
+			brokers := newTestCluster(t, kfake.EnableSASL(), kfake.Superuser(mechanism, "user", "pass"))
+			s, err := NewSink(Config{
+				Brokers:       brokers,
+				SASLMechanism: mechanism,
+				SASLUsername:  "user",
+				SASLPassword:  "pass",
+				Timeout:       5 * time.Second,
+			})
+			require.NoError(t, err)
+			assert.NoError(t, s.WriteLogEvent(&db.LogEvent{Event: 1}))
+			require.NoError(t, s.Close(time.Second))
+
+			s, err = NewSink(Config{
+				Brokers:       brokers,
+				SASLMechanism: mechanism,
+				SASLUsername:  "user",
+				SASLPassword:  "wrong",
+				Timeout:       time.Second,
+			})
+			require.NoError(t, err)
+			assert.Error(t, s.WriteLogEvent(&db.LogEvent{Event: 1}))
+			require.NoError(t, s.Close(time.Second))
+		})
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package nats implements a sink that publishes the events to NATS JetStream
+package nats
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+)
+
+// Config defines the configuration for the NATS sink
+type Config struct {
+	// URLs defines the NATS servers, for example nats://127.0.0.1:4222
+	URLs []string
+	// SubjectPrefix is the first token of the subjects, default "sftpgo"
+	SubjectPrefix string
+	// CredentialsFile is the path to a NATS credentials file (optional)
+	CredentialsFile string
+	// TLSConfig enables TLS if not nil
+	TLSConfig *tls.Config
+	// StreamName is the JetStream stream to create if CreateStream is set
+	StreamName string
+	// CreateStream enables the stream creation, capturing all the subjects
+	// with our prefix, if it does not exist
+	CreateStream bool
+	// Timeout defines the connection and publish timeout
+	Timeout time.Duration
+	// ClientName is sent to the server to identify the connection
+	ClientName string
+}
+
+func (c *Config) validate() error {
+	if len(c.URLs) == 0 {
+		return errors.New("at least a NATS server URL is required")
+	}
+	if c.SubjectPrefix == "" {
+		c.SubjectPrefix = "sftpgo"
+	}
+	if strings.ContainsAny(c.SubjectPrefix, "*> \t\r\n") || strings.HasPrefix(c.SubjectPrefix, ".") ||
+		strings.HasSuffix(c.SubjectPrefix, ".") || strings.Contains(c.SubjectPrefix, "..") {
+		return fmt.Errorf("invalid subject prefix %q", c.SubjectPrefix)
+	}
+	if c.CreateStream && c.StreamName == "" {
+		return errors.New("a stream name is required to create the stream")
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 10 * time.Second
+	}
+	if c.ClientName == "" {
+		c.ClientName = "sftpgo-plugin-eventstore"
+	}
+	return nil
+}
+
+// Sink publishes the events as JSON to JetStream, the event ID is used as
+// message ID so JetStream can discard duplicates within its dedup window
+type Sink struct {
+	config *Config
+	conn   *nats.Conn
+	js     jetstream.JetStream
+	closed chan struct{}
+}
+
+// NewSink connects to the NATS servers and returns a new sink
+func NewSink(config Config) (*Sink, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	closed := make(chan struct{})
+	opts := []nats.Option{
+		nats.Name(config.ClientName),
+		nats.Timeout(config.Timeout),
+		nats.MaxReconnects(-1),
+		nats.ClosedHandler(func(_ *nats.Conn) {
+			close(closed)
+		}),
+	}
+	if config.CredentialsFile != "" {
+		opts = append(opts, nats.UserCredentials(config.CredentialsFile))
+	}
+	if config.TLSConfig != nil {
+		opts = append(opts, nats.Secure(config.TLSConfig))
+	}
+	conn, err := nats.Connect(strings.Join(config.URLs, ","), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to NATS: %w", err)
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	s := &Sink{
+		config: &config,
+		conn:   conn,
+		js:     js,
+		closed: closed,
+	}
+	if config.CreateStream {
+		if err := s.createStream(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// createStream creates the stream if it does not exist, an existing stream
+// is never modified
+func (s *Sink) createStream() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+
+	_, err := s.js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     s.config.StreamName,
+		Subjects: []string{s.config.SubjectPrefix + ".>"},
+		Storage:  jetstream.FileStorage,
+	})
+	if err != nil && !errors.Is(err, jetstream.ErrStreamNameAlreadyInUse) {
+		return fmt.Errorf("unable to create stream %q: %w", s.config.StreamName, err)
+	}
+	return nil
+}
+
+// Name implements db.Sink
+func (s *Sink) Name() string {
+	return "nats"
+}
+
+func (s *Sink) getSubject(tokens ...string) string {
+	var sb strings.Builder
+	sb.WriteString(s.config.SubjectPrefix)
+	for _, token := range tokens {
+		sb.WriteByte('.')
+		sb.WriteString(escapeToken(token))
+	}
+	return sb.String()
+}
+
+func (s *Sink) publish(subject, id string, ev any) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+
+	_, err = s.js.Publish(ctx, subject, data, jetstream.WithMsgID(id))
+	if err != nil {
+		return fmt.Errorf("unable to publish to subject %q: %w", subject, err)
+	}
+	return nil
+}
+
+// WriteFsEvent implements db.Sink
+func (s *Sink) WriteFsEvent(ev *db.FsEvent) error {
+	return s.publish(s.getSubject("fs", ev.Action, ev.Username), ev.ID, ev)
+}
+
+// WriteProviderEvent implements db.Sink
+func (s *Sink) WriteProviderEvent(ev *db.ProviderEvent) error {
+	return s.publish(s.getSubject("provider", ev.ObjectType, ev.Action), ev.ID, ev)
+}
+
+// WriteLogEvent implements db.Sink
+func (s *Sink) WriteLogEvent(ev *db.LogEvent) error {
+	return s.publish(s.getSubject("log", strconv.Itoa(ev.Event)), ev.ID, ev)
+}
+
+// Close flushes the pending messages and closes the connection, the
+// connection is closed anyway after the given timeout
+func (s *Sink) Close(timeout time.Duration) error {
+	if err := s.conn.Drain(); err != nil {
+		if errors.Is(err, nats.ErrConnectionClosed) {
+			return nil
+		}
+		s.conn.Close()
+		return err
+	}
+	select {
+	case <-s.closed:
+		return nil
+	case <-time.After(timeout):
+		s.conn.Close()
+		return errors.New("timeout draining the NATS connection")
+	}
+}
+
+// escapeToken makes the given value usable as a single subject token,
+// the characters not allowed within a token and "%" are percent-encoded
+// and an empty value is replaced with "_"
+func escapeToken(value string) string {
+	if value == "" {
+		return "_"
+	}
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '.', c == '*', c == '>', c == '%', c <= ' ', c == 0x7f:
+			fmt.Fprintf(&sb, "%%%02X", c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,303 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nats-server/v2/server"
+	natstest "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nats-io/nkeys"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+)
+
+// runServer starts an embedded NATS server with JetStream enabled
+func runServer(t *testing.T, configure func(*server.Options)) *server.Server {
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	if configure != nil {
+		configure(&opts)
+	}
+	s := natstest.RunServer(&opts)
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+func newJetStream(t *testing.T, s *server.Server) jetstream.JetStream {
+	conn, err := nats.Connect(s.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(conn.Close)
+	js, err := jetstream.New(conn)
+	require.NoError(t, err)
+	return js
+}
+
+func TestEscapeToken(t *testing.T) {
+	assert.Equal(t, "_", escapeToken(""))
+	assert.Equal(t, "upload", escapeToken("upload"))
+	assert.Equal(t, "user%2Ename", escapeToken("user.name"))
+	assert.Equal(t, "a%2A%3Eb%25c", escapeToken("a*>b%c"))
+	assert.Equal(t, "first%20last%09", escapeToken("first last\t"))
+	assert.Equal(t, "user@example%2Ecom", escapeToken("user@example.com"))
+}
+
+func TestValidation(t *testing.T) {
+	_, err := NewSink(Config{})
+	assert.Error(t, err)
+	_, err = NewSink(Config{URLs: []string{"nats://127.0.0.1:4222"}, SubjectPrefix: "events.>"})
+	assert.ErrorContains(t, err, "invalid subject prefix")
+	_, err = NewSink(Config{URLs: []string{"nats://127.0.0.1:4222"}, SubjectPrefix: "events."})
+	assert.ErrorContains(t, err, "invalid subject prefix")
+	_, err = NewSink(Config{URLs: []string{"nats://127.0.0.1:4222"}, CreateStream: true})
+	assert.ErrorContains(t, err, "stream name is required")
+
+	c := Config{URLs: []string{"nats://127.0.0.1:4222"}}
+	require.NoError(t, c.validate())
+	assert.Equal(t, "sftpgo", c.SubjectPrefix)
+	assert.Equal(t, 10*time.Second, c.Timeout)
+	c = Config{URLs: []string{"nats://127.0.0.1:4222"}, SubjectPrefix: "org.sftpgo"}
+	assert.NoError(t, c.validate())
+}
+
+func TestSink(t *testing.T) {
+	s := runServer(t, nil)
+	sink, err := NewSink(Config{
+		URLs:         []string{s.ClientURL()},
+		StreamName:   "EVENTS",
+		CreateStream: true,
+		Timeout:      5 * time.Second,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "nats", sink.Name())
+	connz, err := s.Connz(&server.ConnzOptions{})
+	require.NoError(t, err)
+	require.Len(t, connz.Conns, 1)
+	assert.Equal(t, "sftpgo-plugin-eventstore", connz.Conns[0].Name)
+
+	ctx := context.Background()
+	stream, err := newJetStream(t, s).Stream(ctx, "EVENTS")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sftpgo.>"}, stream.CachedInfo().Config.Subjects)
+	assert.Equal(t, jetstream.FileStorage, stream.CachedInfo().Config.Storage)
+
+	fsEvent := &db.FsEvent{
+		ID:          "fs-id",
+		Timestamp:   time.Now().UnixNano(),
+		Action:      "upload",
+		Username:    "user.name",
+		VirtualPath: "/file.txt",
+		Status:      1,
+	}
+	providerEvent := &db.ProviderEvent{
+		ID:         "provider-id",
+		Timestamp:  time.Now().UnixNano(),
+		Action:     "update",
+		Username:   "admin",
+		ObjectType: "user",
+		ObjectName: "user.name",
+		ObjectData: []byte(`{"username":"user.name"}`),
+	}
+	logEvent := &db.LogEvent{
+		ID:        "log-id",
+		Timestamp: time.Now().UnixNano(),
+		Event:     1,
+		IP:        "127.0.0.1",
+	}
+	require.NoError(t, sink.WriteFsEvent(fsEvent))
+	require.NoError(t, sink.WriteProviderEvent(providerEvent))
+	require.NoError(t, sink.WriteLogEvent(logEvent))
+	// the message ID allows JetStream to discard a duplicate publish
+	require.NoError(t, sink.WriteFsEvent(fsEvent))
+
+	info, err := stream.Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), info.State.Msgs)
+
+	msg, err := stream.GetMsg(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "sftpgo.fs.upload.user%2Ename", msg.Subject)
+	assert.Equal(t, fsEvent.ID, msg.Header.Get(jetstream.MsgIDHeader))
+	var fsEventReceived db.FsEvent
+	require.NoError(t, json.Unmarshal(msg.Data, &fsEventReceived))
+	assert.Equal(t, *fsEvent, fsEventReceived)
+
+	msg, err = stream.GetMsg(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "sftpgo.provider.user.update", msg.Subject)
+	assert.Equal(t, providerEvent.ID, msg.Header.Get(jetstream.MsgIDHeader))
+	var providerEventReceived db.ProviderEvent
+	require.NoError(t, json.Unmarshal(msg.Data, &providerEventReceived))
+	assert.Equal(t, *providerEvent, providerEventReceived)
+
+	msg, err = stream.GetMsg(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, "sftpgo.log.1", msg.Subject)
+	assert.Equal(t, logEvent.ID, msg.Header.Get(jetstream.MsgIDHeader))
+	var logEventReceived db.LogEvent
+	require.NoError(t, json.Unmarshal(msg.Data, &logEventReceived))
+	assert.Equal(t, *logEvent, logEventReceived)
+
+	require.NoError(t, sink.Close(time.Second))
+	assert.True(t, sink.conn.IsClosed())
+	// closing again is a no-op
+	assert.NoError(t, sink.Close(time.Second))
+}
+
+func TestExistingStream(t *testing.T) {
+	s := runServer(t, nil)
+	ctx := context.Background()
+	stream, err := newJetStream(t, s).CreateStream(ctx, jetstream.StreamConfig{
+		Name:     "EVENTS",
+		Subjects: []string{"org.sftpgo.>"},
+		Storage:  jetstream.MemoryStorage,
+	})
+	require.NoError(t, err)
+
+	sink, err := NewSink(Config{
+		URLs:          []string{s.ClientURL()},
+		SubjectPrefix: "org.sftpgo",
+		StreamName:    "EVENTS",
+		CreateStream:  true,
+	})
+	require.NoError(t, err)
+	defer sink.Close(time.Second)
+
+	require.NoError(t, sink.WriteFsEvent(&db.FsEvent{ID: "id", Action: "delete"}))
+	info, err := stream.Info(ctx)
+	require.NoError(t, err)
+	// the existing stream is not modified
+	assert.Equal(t, jetstream.MemoryStorage, info.Config.Storage)
+	assert.Equal(t, uint64(1), info.State.Msgs)
+	msg, err := stream.GetMsg(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "org.sftpgo.fs.delete._", msg.Subject)
+}
+
+func TestPublishError(t *testing.T) {
+	s := runServer(t, nil)
+	_, err := newJetStream(t, s).CreateStream(context.Background(), jetstream.StreamConfig{
+		Name:     "EVENTS",
+		Subjects: []string{"sftpgo.>"},
+		MaxMsgs:  1,
+		Discard:  jetstream.DiscardNew,
+	})
+	require.NoError(t, err)
+	sink, err := NewSink(Config{
+		URLs: []string{s.ClientURL()},
+	})
+	require.NoError(t, err)
+	defer sink.Close(time.Second)
+
+	require.NoError(t, sink.WriteLogEvent(&db.LogEvent{ID: "id1", Event: 2}))
+	err = sink.WriteLogEvent(&db.LogEvent{ID: "id2", Event: 2})
+	assert.ErrorContains(t, err, "maximum messages exceeded")
+	// no stream captures the subject
+	sink, err = NewSink(Config{
+		URLs:          []string{s.ClientURL()},
+		SubjectPrefix: "other",
+		Timeout:       time.Second,
+	})
+	require.NoError(t, err)
+	defer sink.Close(time.Second)
+
+	assert.Error(t, sink.WriteLogEvent(&db.LogEvent{ID: "id3", Event: 2}))
+}
+
+func TestCredentials(t *testing.T) {
+	operator, err := nkeys.CreateOperator()
+	require.NoError(t, err)
+	operatorKey, err := operator.PublicKey()
+	require.NoError(t, err)
+	operatorJWT, err := jwt.NewOperatorClaims(operatorKey).Encode(operator)
+	require.NoError(t, err)
+	operatorClaims, err := jwt.DecodeOperatorClaims(operatorJWT)
+	require.NoError(t, err)
+	resolver := &server.MemAccResolver{}
+	// writeCreds writes the credentials for a new user of a new account, the
+	// account is known by the server if trusted is true
+	writeCreds := func(trusted bool) string {
+		account, err := nkeys.CreateAccount()
+		require.NoError(t, err)
+		accountKey, err := account.PublicKey()
+		require.NoError(t, err)
+		accountJWT, err := jwt.NewAccountClaims(accountKey).Encode(operator)
+		require.NoError(t, err)
+		if trusted {
+			require.NoError(t, resolver.Store(accountKey, accountJWT))
+		}
+		user, err := nkeys.CreateUser()
+		require.NoError(t, err)
+		userKey, err := user.PublicKey()
+		require.NoError(t, err)
+		userJWT, err := jwt.NewUserClaims(userKey).Encode(account)
+		require.NoError(t, err)
+		seed, err := user.Seed()
+		require.NoError(t, err)
+		creds, err := jwt.FormatUserConfig(userJWT, seed)
+		require.NoError(t, err)
+		credsFile := filepath.Join(t.TempDir(), "user.creds")
+		require.NoError(t, os.WriteFile(credsFile, creds, 0600))
+		return credsFile
+	}
+	validCreds := writeCreds(true)
+	invalidCreds := writeCreds(false)
+	s := runServer(t, func(opts *server.Options) {
+		opts.JetStream = false
+		opts.TrustedOperators = []*jwt.OperatorClaims{operatorClaims}
+		opts.AccountResolver = resolver
+	})
+
+	sink, err := NewSink(Config{
+		URLs:            []string{s.ClientURL()},
+		CredentialsFile: validCreds,
+	})
+	require.NoError(t, err)
+	require.NoError(t, sink.Close(time.Second))
+
+	_, err = NewSink(Config{
+		URLs:            []string{s.ClientURL()},
+		CredentialsFile: invalidCreds,
+		Timeout:         time.Second,
+	})
+	assert.ErrorContains(t, err, "unable to connect to NATS")
+}
+
+func TestServerUnavailable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	_, err = NewSink(Config{
+		URLs:    []string{"nats://" + addr},
+		Timeout: time.Second,
+	})
+	assert.ErrorContains(t, err, "unable to connect to NATS")
+}
@@ -0,0 +1,604 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package opensearch implements a sink that indexes the events into
+// OpenSearch or Elasticsearch daily indices using the bulk API
+package opensearch
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+const (
+	indexDateFormat     = "2006.01.02"
+	maxErrorBodySize    = 1024
+	maxBackoff          = time.Minute
+	retentionCheckDelay = time.Hour
+)
+
+// Config defines the configuration for the OpenSearch sink
+type Config struct {
+	// URLs defines the cluster nodes, requests are sent to the first
+	// available node
+	URLs []string
+	// Username and Password enable basic authentication if set
+	Username string
+	Password string
+	// APIKey enables Elasticsearch API key authentication if set
+	APIKey string
+	// IndexPrefix is prepended to the index names: fs-YYYY.MM.DD,
+	// provider-YYYY.MM.DD and log-YYYY.MM.DD
+	IndexPrefix string
+	// Shards and Replicas are set in the index templates
+	Shards   int
+	Replicas int
+	// LifecyclePolicy is an existing Elasticsearch ILM or OpenSearch ISM
+	// policy to apply to the created indices (optional)
+	LifecyclePolicy string
+	// RetentionDays enables the deletion of the indices older than the
+	// specified number of days, 0 means no deletion
+	RetentionDays int
+	// BatchSize is the maximum number of documents sent in a bulk request
+	BatchSize int
+	// FlushInterval is the maximum time a document waits before being sent
+	FlushInterval time.Duration
+	// QueueSize is the maximum number of documents waiting to be sent, new
+	// documents are rejected with an error if the queue is full
+	QueueSize int
+	// MaxRetries defines how many times a bulk request is retried
+	MaxRetries int
+	// RetryInterval is the initial retry delay, it doubles for each retry
+	RetryInterval time.Duration
+	Timeout       time.Duration
+	TLSConfig     *tls.Config
+}
+
+func (c *Config) validate() error {
+	if len(c.URLs) == 0 {
+		return errors.New("at least an OpenSearch URL is required")
+	}
+	for idx, u := range c.URLs {
+		if _, err := url.Parse(u); err != nil {
+			return fmt.Errorf("invalid OpenSearch URL %q: %w", u, err)
+		}
+		c.URLs[idx] = strings.TrimRight(u, "/")
+	}
+	if c.IndexPrefix == "" {
+		c.IndexPrefix = "sftpgo-"
+	}
+	if c.IndexPrefix != strings.ToLower(c.IndexPrefix) || strings.ContainsAny(c.IndexPrefix, `\/*?"<>| ,#:`) {
+		return fmt.Errorf("invalid index prefix %q", c.IndexPrefix)
+	}
+	if c.Shards <= 0 {
+		c.Shards = 1
+	}
+	if c.Replicas < 0 {
+		return fmt.Errorf("invalid number of replicas %d", c.Replicas)
+	}
+	if c.RetentionDays < 0 {
+		return fmt.Errorf("invalid retention %d", c.RetentionDays)
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 500
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = 5 * time.Second
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = 10000
+	}
+	if c.MaxRetries < 0 {
+		return fmt.Errorf("invalid max retries %d", c.MaxRetries)
+	}
+	if c.RetryInterval <= 0 {
+		c.RetryInterval = time.Second
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 30 * time.Second
+	}
+	return nil
+}
+
+// document is a pending bulk index operation
+type document struct {
+	index  string
+	id     string
+	source []byte
+}
+
+// fsEventDoc, providerEventDoc and logEventDoc replace the timestamp in
+// nanoseconds with milliseconds, as expected by the date type, the original
+// value is preserved in timestamp_ns
+type fsEventDoc struct {
+	*db.FsEvent
+	Timestamp   int64 `json:"timestamp"`
+	TimestampNs int64 `json:"timestamp_ns"`
+}
+
+type providerEventDoc struct {
+	*db.ProviderEvent
+	Timestamp   int64 `json:"timestamp"`
+	TimestampNs int64 `json:"timestamp_ns"`
+}
+
+type logEventDoc struct {
+	*db.LogEvent
+	Timestamp   int64 `json:"timestamp"`
+	TimestampNs int64 `json:"timestamp_ns"`
+}
+
+// Sink indexes the events asynchronously using bulk requests
+type Sink struct {
+	config *Config
+	client *http.Client
+	queue  chan *document
+	// done stops the retention checker, abort stops the pending retries
+	done    chan struct{}
+	abort   chan struct{}
+	stopped sync.WaitGroup
+	// ism is true if the lifecycle policy is an OpenSearch ISM policy, it
+	// is attached to the indices after they are created. policyIndices
+	// are the indices with the policy attached, only the flusher uses it
+	ism           bool
+	policyIndices map[string]bool
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewSink installs the index templates and returns a new OpenSearch sink
+func NewSink(config Config) (*Sink, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.TLSConfig != nil {
+		transport.TLSClientConfig = config.TLSConfig
+	}
+	s := &Sink{
+		config: &config,
+		client: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: transport,
+		},
+		queue:         make(chan *document, config.QueueSize),
+		done:          make(chan struct{}),
+		abort:         make(chan struct{}),
+		policyIndices: make(map[string]bool),
+	}
+	if config.LifecyclePolicy != "" {
+		isOpenSearch, err := s.isOpenSearch()
+		if err != nil {
+			return nil, err
+		}
+		s.ism = isOpenSearch
+	}
+	if err := s.installTemplates(); err != nil {
+		return nil, err
+	}
+	s.stopped.Add(1)
+	go s.flusher()
+	if config.RetentionDays > 0 {
+		s.stopped.Add(1)
+		go s.retentionChecker()
+	}
+	return s, nil
+}
+
+// Name implements db.Sink
+func (s *Sink) Name() string {
+	return "opensearch"
+}
+
+// do sends a request trying the configured nodes in order, it returns the
+// response status code and body
+func (s *Sink) do(method, path, contentType string, body []byte) (int, []byte, error) {
+	var lastErr error
+	for _, u := range s.config.URLs {
+		req, err := http.NewRequest(method, u+path, bytes.NewReader(body))
+		if err != nil {
+			return 0, nil, err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if s.config.APIKey != "" {
+			req.Header.Set("Authorization", "ApiKey "+s.config.APIKey)
+		} else if s.config.Username != "" {
+			req.SetBasicAuth(s.config.Username, s.config.Password)
+		}
+		resp, err := s.client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return resp.StatusCode, respBody, nil
+	}
+	return 0, nil, lastErr
+}
+
+func getResponseError(status int, body []byte) error {
+	if len(body) > maxErrorBodySize {
+		body = body[:maxErrorBodySize]
+	}
+	return fmt.Errorf("unexpected status code %d: %s", status, bytes.TrimSpace(body))
+}
+
+// isOpenSearch returns true if the cluster is OpenSearch, false if it is
+// Elasticsearch
+func (s *Sink) isOpenSearch() (bool, error) {
+	status, body, err := s.do(http.MethodGet, "/", "", nil)
+	if err != nil {
+		return false, fmt.Errorf("unable to get the cluster info: %w", err)
+	}
+	if status != http.StatusOK {
+		return false, fmt.Errorf("unable to get the cluster info: %w", getResponseError(status, body))
+	}
+	var info struct {
+		Version struct {
+			Distribution string `json:"distribution"`
+		} `json:"version"`
+	}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return false, fmt.Errorf("unable to decode the cluster info: %w", err)
+	}
+	return info.Version.Distribution == "opensearch", nil
+}
+
+func (s *Sink) installTemplates() error {
+	for _, indexType := range indexTypes {
+		template, err := s.getIndexTemplate(indexType)
+		if err != nil {
+			return err
+		}
+		name := s.config.IndexPrefix + indexType
+		status, body, err := s.do(http.MethodPut, "/_index_template/"+url.PathEscape(name), "application/json",
+			template)
+		if err != nil {
+			return fmt.Errorf("unable to install index template %q: %w", name, err)
+		}
+		if status != http.StatusOK {
+			return fmt.Errorf("unable to install index template %q: %w", name, getResponseError(status, body))
+		}
+	}
+	return nil
+}
+
+func (s *Sink) getIndexName(indexType string, timestamp int64) string {
+	return s.config.IndexPrefix + indexType + "-" + time.Unix(0, timestamp).UTC().Format(indexDateFormat)
+}
+
+func (s *Sink) enqueue(indexType, id string, timestamp int64, doc any) error {
+	source, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return errors.New("opensearch sink closed")
+	}
+	select {
+	case s.queue <- &document{index: s.getIndexName(indexType, timestamp), id: id, source: source}:
+		return nil
+	default:
+		return errors.New("opensearch queue full, document discarded")
+	}
+}
+
+// WriteFsEvent implements db.Sink
+func (s *Sink) WriteFsEvent(ev *db.FsEvent) error {
+	return s.enqueue(indexTypeFs, ev.ID, ev.Timestamp, &fsEventDoc{
+		FsEvent:     ev,
+		Timestamp:   time.Unix(0, ev.Timestamp).UnixMilli(),
+		TimestampNs: ev.Timestamp,
+	})
+}
+
+// WriteProviderEvent implements db.Sink
+func (s *Sink) WriteProviderEvent(ev *db.ProviderEvent) error {
+	return s.enqueue(indexTypeProvider, ev.ID, ev.Timestamp, &providerEventDoc{
+		ProviderEvent: ev,
+		Timestamp:     time.Unix(0, ev.Timestamp).UnixMilli(),
+		TimestampNs:   ev.Timestamp,
+	})
+}
+
+// WriteLogEvent implements db.Sink
+func (s *Sink) WriteLogEvent(ev *db.LogEvent) error {
+	return s.enqueue(indexTypeLog, ev.ID, ev.Timestamp, &logEventDoc{
+		LogEvent:    ev,
+		Timestamp:   time.Unix(0, ev.Timestamp).UnixMilli(),
+		TimestampNs: ev.Timestamp,
+	})
+}
+
+func (s *Sink) flusher() {
+	defer s.stopped.Done()
+
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*document, 0, s.config.BatchSize)
+	for {
+		select {
+		case doc, ok := <-s.queue:
+			if !ok {
+				s.flush(batch)
+				return
+			}
+			batch = append(batch, doc)
+			if len(batch) >= s.config.BatchSize {
+				s.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			s.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// flush sends the given documents retrying the failed requests and the
+// documents rejected because the cluster is overloaded or unavailable
+func (s *Sink) flush(docs []*document) {
+	if len(docs) == 0 {
+		return
+	}
+	if s.ism {
+		defer s.addLifecyclePolicy(docs)
+	}
+	retryInterval := s.config.RetryInterval
+	for attempt := 0; len(docs) > 0; attempt++ {
+		if attempt > 0 {
+			if attempt > s.config.MaxRetries {
+				logger.AppLogger.Error("unable to index documents, max retries exceeded", "discarded", len(docs))
+				return
+			}
+			select {
+			case <-time.After(retryInterval):
+			case <-s.abort:
+				logger.AppLogger.Error("unable to index documents, sink closed", "discarded", len(docs))
+				return
+			}
+			retryInterval = min(2*retryInterval, maxBackoff)
+		}
+		var err error
+		docs, err = s.bulk(docs)
+		if err != nil {
+			logger.AppLogger.Warn("bulk request failed", "documents", len(docs), "attempt", attempt+1,
+				"error", err)
+		}
+	}
+}
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+// bulk sends a bulk request and returns the documents to retry
+func (s *Sink) bulk(docs []*document) ([]*document, error) {
+	var body bytes.Buffer
+	for _, doc := range docs {
+		action, err := json.Marshal(map[string]any{
+			"index": map[string]string{"_index": doc.index, "_id": doc.id},
+		})
+		if err != nil {
+			return nil, err
+		}
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(doc.source)
+		body.WriteByte('\n')
+	}
+	status, respBody, err := s.do(http.MethodPost, "/_bulk", "application/x-ndjson", body.Bytes())
+	if err != nil {
+		return docs, err
+	}
+	if status != http.StatusOK {
+		err := getResponseError(status, respBody)
+		if status == http.StatusTooManyRequests || status >= 500 {
+			return docs, err
+		}
+		logger.AppLogger.Error("bulk request rejected", "discarded", len(docs), "error", err)
+		return nil, nil
+	}
+	var resp bulkResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return docs, fmt.Errorf("unable to decode bulk response: %w", err)
+	}
+	if !resp.Errors {
+		return nil, nil
+	}
+	if len(resp.Items) != len(docs) {
+		return docs, fmt.Errorf("unexpected bulk response items %d, expected %d", len(resp.Items), len(docs))
+	}
+	var retry []*document
+	for idx, item := range resp.Items {
+		for _, result := range item {
+			switch {
+			case result.Status < 300:
+			case result.Status == http.StatusTooManyRequests || result.Status >= 500:
+				retry = append(retry, docs[idx])
+			default:
+				logger.AppLogger.Error("unable to index document", "index", docs[idx].index, "id", docs[idx].id,
+					"status", result.Status, "error", string(result.Error))
+			}
+		}
+	}
+	if len(retry) > 0 {
+		return retry, fmt.Errorf("%d documents rejected with a retriable status", len(retry))
+	}
+	return nil, nil
+}
+
+// addLifecyclePolicy attaches the ISM policy to the indices of the given
+// documents, if not already done. OpenSearch does not support the policy
+// in the index templates
+func (s *Sink) addLifecyclePolicy(docs []*document) {
+	for _, doc := range docs {
+		if s.policyIndices[doc.index] {
+			continue
+		}
+		if err := s.addIndexPolicy(doc.index); err != nil {
+			logger.AppLogger.Warn("unable to add the lifecycle policy", "index", doc.index, "error", err)
+			// the next flush will try again
+			continue
+		}
+		s.policyIndices[doc.index] = true
+	}
+}
+
+func (s *Sink) addIndexPolicy(index string) error {
+	body, err := json.Marshal(map[string]string{"policy_id": s.config.LifecyclePolicy})
+	if err != nil {
+		return err
+	}
+	status, respBody, err := s.do(http.MethodPost, "/_plugins/_ism/add/"+url.PathEscape(index), "application/json", body)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return getResponseError(status, respBody)
+	}
+	var resp struct {
+		FailedIndices []struct {
+			Reason string `json:"reason"`
+		} `json:"failed_indices"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return fmt.Errorf("unable to decode the response: %w", err)
+	}
+	for _, failed := range resp.FailedIndices {
+		// the policy is already attached, for example before a restart
+		if !strings.Contains(failed.Reason, "already has a policy") {
+			return errors.New(failed.Reason)
+		}
+	}
+	return nil
+}
+
+func (s *Sink) retentionChecker() {
+	defer s.stopped.Done()
+
+	for {
+		if err := s.deleteOldIndices(time.Now()); err != nil {
+			logger.AppLogger.Error("unable to delete old indices", "error", err)
+		}
+		select {
+		case <-time.After(retentionCheckDelay):
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// deleteOldIndices deletes the daily indices older than the configured
+// retention, the index date is compared with the UTC date of now
+func (s *Sink) deleteOldIndices(now time.Time) error {
+	status, body, err := s.do(http.MethodGet, "/_cat/indices/"+url.PathEscape(s.config.IndexPrefix)+"*?format=json&h=index",
+		"", nil)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return getResponseError(status, body)
+	}
+	var indices []struct {
+		Index string `json:"index"`
+	}
+	if err := json.Unmarshal(body, &indices); err != nil {
+		return fmt.Errorf("unable to decode indices: %w", err)
+	}
+	limit := now.UTC().Truncate(24*time.Hour).AddDate(0, 0, -s.config.RetentionDays)
+	for _, idx := range indices {
+		if !s.isExpired(idx.Index, limit) {
+			continue
+		}
+		logger.AppLogger.Debug("deleting expired index", "index", idx.Index)
+		status, body, err := s.do(http.MethodDelete, "/"+url.PathEscape(idx.Index), "", nil)
+		if err != nil {
+			return err
+		}
+		if status != http.StatusOK && status != http.StatusNotFound {
+			return fmt.Errorf("unable to delete index %q: %w", idx.Index, getResponseError(status, body))
+		}
+		logger.AppLogger.Info("expired index deleted", "index", idx.Index)
+	}
+	return nil
+}
+
+// isExpired returns true if the given index is one of our daily indices
+// and its date is before the limit
+func (s *Sink) isExpired(index string, limit time.Time) bool {
+	for _, indexType := range indexTypes {
+		date, ok := strings.CutPrefix(index, s.config.IndexPrefix+indexType+"-")
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(indexDateFormat, date)
+		if err != nil {
+			return false
+		}
+		return t.Before(limit)
+	}
+	return false
+}
+
+// Close stops accepting new events and sends the queued documents, the
+// pending retries are aborted after the given timeout
+func (s *Sink) Close(timeout time.Duration) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	close(s.done)
+	s.mu.Unlock()
+
+	timer := time.AfterFunc(timeout, func() {
+		close(s.abort)
+	})
+	s.stopped.Wait()
+	if timer.Stop() {
+		close(s.abort)
+	}
+	return nil
+}
@@ -0,0 +1,462 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package opensearch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+)
+
+// fakeCluster is a local HTTP stand-in implementing the OpenSearch APIs
+// used by our sink
+type fakeCluster struct {
+	server *httptest.Server
+
+	mu        sync.Mutex
+	templates map[string]map[string]any
+	indices   map[string]map[string]map[string]any
+	deleted   []string
+	bulks     int
+	// bulkStatuses are used, in order, as status code for the whole bulk request
+	bulkStatuses []int
+	// itemStatuses are used, in order, as status code for the bulk items
+	itemStatuses []int
+	authHeader   string
+	// distribution is returned in the cluster info, empty for Elasticsearch
+	distribution string
+	// policies are the ISM policies attached to the indices
+	policies   map[string]string
+	policyAdds int
+}
+
+func newFakeCluster(t *testing.T) *fakeCluster {
+	c := &fakeCluster{
+		templates: make(map[string]map[string]any),
+		indices:   make(map[string]map[string]map[string]any),
+		policies:  make(map[string]string),
+	}
+	c.server = httptest.NewServer(http.HandlerFunc(c.handle))
+	t.Cleanup(c.server.Close)
+	return c
+}
+
+func (c *fakeCluster) handle(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.authHeader = r.Header.Get("Authorization")
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/":
+		version := map[string]any{"number": "2.19.0"}
+		if c.distribution != "" {
+			version["distribution"] = c.distribution
+		}
+		json.NewEncoder(w).Encode(map[string]any{"version": version})
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/_plugins/_ism/add/"):
+		c.handleAddPolicy(w, strings.TrimPrefix(r.URL.Path, "/_plugins/_ism/add/"), body)
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/_index_template/"):
+		var template map[string]any
+		if err := json.Unmarshal(body, &template); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		c.templates[strings.TrimPrefix(r.URL.Path, "/_index_template/")] = template
+		w.Write([]byte(`{"acknowledged":true}`))
+	case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+		c.handleBulk(w, r, body)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/_cat/indices/"):
+		prefix := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/_cat/indices/"), "*")
+		var result []map[string]string
+		for name := range c.indices {
+			if strings.HasPrefix(name, prefix) {
+				result = append(result, map[string]string{"index": name})
+			}
+		}
+		json.NewEncoder(w).Encode(result)
+	case r.Method == http.MethodDelete:
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		if _, ok := c.indices[name]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(c.indices, name)
+		c.deleted = append(c.deleted, name)
+		w.Write([]byte(`{"acknowledged":true}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (c *fakeCluster) handleAddPolicy(w http.ResponseWriter, index string, body []byte) {
+	c.policyAdds++
+	var req map[string]string
+	if err := json.Unmarshal(body, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var failed []map[string]string
+	switch {
+	case c.indices[index] == nil:
+		failed = append(failed, map[string]string{"index_name": index, "reason": "no such index"})
+	case c.policies[index] != "":
+		failed = append(failed, map[string]string{"index_name": index,
+			"reason": "This index already has a policy, use the update policy API to update index policies."})
+	default:
+		c.policies[index] = req["policy_id"]
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"updated_indices": 1 - len(failed),
+		"failures":        len(failed) > 0,
+		"failed_indices":  failed,
+	})
+}
+
+func (c *fakeCluster) getPolicies() (map[string]string, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return maps.Clone(c.policies), c.policyAdds
+}
+
+func (c *fakeCluster) handleBulk(w http.ResponseWriter, r *http.Request, body []byte) {
+	c.bulks++
+	if r.Header.Get("Content-Type") != "application/x-ndjson" {
+		w.WriteHeader(http.StatusNotAcceptable)
+		return
+	}
+	if len(c.bulkStatuses) > 0 {
+		status := c.bulkStatuses[0]
+		c.bulkStatuses = c.bulkStatuses[1:]
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error":"rejected"}`))
+			return
+		}
+	}
+	var items []map[string]any
+	hasErrors := false
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		var action map[string]map[string]string
+		if err := json.Unmarshal(scanner.Bytes(), &action); err != nil || !scanner.Scan() {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var doc map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		status := http.StatusCreated
+		if len(c.itemStatuses) > 0 {
+			status = c.itemStatuses[0]
+			c.itemStatuses = c.itemStatuses[1:]
+		}
+		item := map[string]any{"status": status}
+		if status == http.StatusCreated {
+			index := action["index"]["_index"]
+			if c.indices[index] == nil {
+				c.indices[index] = make(map[string]map[string]any)
+			}
+			c.indices[index][action["index"]["_id"]] = doc
+		} else {
+			hasErrors = true
+			item["error"] = map[string]any{"type": "error", "reason": "rejected"}
+		}
+		items = append(items, map[string]any{"index": item})
+	}
+	json.NewEncoder(w).Encode(map[string]any{"took": 1, "errors": hasErrors, "items": items})
+}
+
+func (c *fakeCluster) getIndex(name string) map[string]map[string]any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.indices[name]
+}
+
+func (c *fakeCluster) countDocuments() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for _, docs := range c.indices {
+		n += len(docs)
+	}
+	return n
+}
+
+func newTestSink(t *testing.T, c *fakeCluster, config Config) *Sink {
+	config.URLs = []string{c.server.URL}
+	config.FlushInterval = 50 * time.Millisecond
+	config.RetryInterval = 10 * time.Millisecond
+	sink, err := NewSink(config)
+	require.NoError(t, err)
+	return sink
+}
+
+func TestValidation(t *testing.T) {
+	_, err := NewSink(Config{})
+	assert.Error(t, err)
+	_, err = NewSink(Config{URLs: []string{"http://127.0.0.1:9200"}, IndexPrefix: "Events-"})
+	assert.ErrorContains(t, err, "invalid index prefix")
+	_, err = NewSink(Config{URLs: []string{"http://127.0.0.1:9200"}, Replicas: -1})
+	assert.ErrorContains(t, err, "invalid number of replicas")
+	_, err = NewSink(Config{URLs: []string{"http://127.0.0.1:9200"}, RetentionDays: -1})
+	assert.ErrorContains(t, err, "invalid retention")
+	_, err = NewSink(Config{URLs: []string{"http://127.0.0.1:9200"}, MaxRetries: -1})
+	assert.ErrorContains(t, err, "invalid max retries")
+
+	c := Config{URLs: []string{"http://127.0.0.1:9200/"}}
+	require.NoError(t, c.validate())
+	assert.Equal(t, "http://127.0.0.1:9200", c.URLs[0])
+	assert.Equal(t, "sftpgo-", c.IndexPrefix)
+	assert.Equal(t, 500, c.BatchSize)
+}
+
+func TestTemplates(t *testing.T) {
+	c := newFakeCluster(t)
+	sink := newTestSink(t, c, Config{
+		Replicas:        2,
+		LifecyclePolicy: "sftpgo-policy",
+		Username:        "user",
+		Password:        "pass",
+	})
+	require.NoError(t, sink.Close(time.Second))
+	assert.Equal(t, "Basic dXNlcjpwYXNz", c.authHeader)
+
+	require.Len(t, c.templates, 3)
+	template := c.templates["sftpgo-fs"]
+	require.NotNil(t, template)
+	assert.Equal(t, []any{"sftpgo-fs-*"}, template["index_patterns"])
+	settings := template["template"].(map[string]any)["settings"].(map[string]any)
+	assert.Equal(t, float64(1), settings["number_of_shards"])
+	assert.Equal(t, float64(2), settings["number_of_replicas"])
+	assert.Equal(t, "sftpgo-policy", settings["index.lifecycle.name"])
+	properties := template["template"].(map[string]any)["mappings"].(map[string]any)["properties"].(map[string]any)
+	getType := func(properties map[string]any, name string) string {
+		return properties[name].(map[string]any)["type"].(string)
+	}
+	assert.Equal(t, "keyword", getType(properties, "username"))
+	assert.Equal(t, "keyword", getType(properties, "action"))
+	assert.Equal(t, "ip", getType(properties, "ip"))
+	assert.Equal(t, "date", getType(properties, "timestamp"))
+	assert.Equal(t, "epoch_millis", properties["timestamp"].(map[string]any)["format"])
+	assert.Equal(t, "text", getType(properties, "virtual_path"))
+	assert.Equal(t, "long", getType(properties, "file_size"))
+	assert.NotContains(t, properties, "message")
+
+	properties = c.templates["sftpgo-log"]["template"].(map[string]any)["mappings"].(map[string]any)["properties"].(map[string]any)
+	assert.Equal(t, "text", getType(properties, "message"))
+	assert.Equal(t, "integer", getType(properties, "event"))
+	assert.NotContains(t, properties, "virtual_path")
+	properties = c.templates["sftpgo-provider"]["template"].(map[string]any)["mappings"].(map[string]any)["properties"].(map[string]any)
+	assert.Equal(t, "keyword", getType(properties, "object_name"))
+	assert.Equal(t, "binary", getType(properties, "object_data"))
+
+	sink = newTestSink(t, c, Config{APIKey: "key"})
+	require.NoError(t, sink.Close(time.Second))
+	assert.Equal(t, "ApiKey key", c.authHeader)
+	// OpenSearch does not support the lifecycle policy in the templates
+	c.distribution = "opensearch"
+	sink = newTestSink(t, c, Config{LifecyclePolicy: "sftpgo-policy"})
+	require.NoError(t, sink.Close(time.Second))
+	settings = c.templates["sftpgo-fs"]["template"].(map[string]any)["settings"].(map[string]any)
+	assert.NotContains(t, settings, "index.lifecycle.name")
+}
+
+func TestISMPolicy(t *testing.T) {
+	c := newFakeCluster(t)
+	c.distribution = "opensearch"
+	// the policy is already attached to this index
+	c.indices["sftpgo-log-2026.03.15"] = make(map[string]map[string]any)
+	c.policies["sftpgo-log-2026.03.15"] = "old-policy"
+	sink := newTestSink(t, c, Config{LifecyclePolicy: "sftpgo-policy"})
+	ts := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC).UnixNano()
+	for idx, id := range []string{"id1", "id2", "id3"} {
+		require.NoError(t, sink.WriteLogEvent(&db.LogEvent{ID: id, Timestamp: ts + int64(idx)*int64(12*time.Hour)}))
+	}
+	assert.Eventually(t, func() bool {
+		policies, _ := c.getPolicies()
+		return len(policies) == 2
+	}, 2*time.Second, 20*time.Millisecond)
+	require.NoError(t, sink.WriteLogEvent(&db.LogEvent{ID: "id4", Timestamp: ts}))
+	require.NoError(t, sink.Close(time.Second))
+
+	policies, adds := c.getPolicies()
+	assert.Equal(t, "sftpgo-policy", policies["sftpgo-log-2026.03.14"])
+	assert.Equal(t, "old-policy", policies["sftpgo-log-2026.03.15"])
+	// the policy is added only once for each index
+	assert.Equal(t, 2, adds)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	_, err := NewSink(Config{URLs: []string{server.URL}, LifecyclePolicy: "sftpgo-policy"})
+	assert.ErrorContains(t, err, "unable to get the cluster info")
+}
+
+func TestTemplateError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, err := NewSink(Config{URLs: []string{server.URL}})
+	assert.ErrorContains(t, err, "unable to install index template")
+}
+
+func TestSink(t *testing.T) {
+	c := newFakeCluster(t)
+	sink := newTestSink(t, c, Config{IndexPrefix: "events-", BatchSize: 2})
+	assert.Equal(t, "opensearch", sink.Name())
+
+	ts := time.Date(2026, 3, 14, 23, 59, 59, 123456789, time.UTC).UnixNano()
+	require.NoError(t, sink.WriteFsEvent(&db.FsEvent{
+		ID:          "fs1",
+		Timestamp:   ts,
+		Action:      "upload",
+		Username:    "user",
+		VirtualPath: "/dir/file.txt",
+		IP:          "192.168.1.2",
+		Status:      1,
+	}))
+	require.NoError(t, sink.WriteProviderEvent(&db.ProviderEvent{
+		ID:         "provider1",
+		Timestamp:  ts + int64(time.Second),
+		Action:     "add",
+		ObjectType: "user",
+		ObjectName: "user",
+		ObjectData: []byte(`{}`),
+	}))
+	require.NoError(t, sink.WriteLogEvent(&db.LogEvent{
+		ID:        "log1",
+		Timestamp: ts,
+		Event:     1,
+		Message:   "login failed",
+	}))
+	// the first two events are sent because the batch is full, the last one
+	// after the flush interval
+	assert.Eventually(t, func() bool {
+		return c.countDocuments() == 3
+	}, 2*time.Second, 20*time.Millisecond)
+	require.NoError(t, sink.Close(time.Second))
+	assert.Error(t, sink.WriteLogEvent(&db.LogEvent{ID: "log2"}))
+	assert.Equal(t, 2, c.bulks)
+
+	doc := c.getIndex("events-fs-2026.03.14")["fs1"]
+	require.NotNil(t, doc)
+	assert.Equal(t, float64(time.Unix(0, ts).UnixMilli()), doc["timestamp"])
+	assert.Equal(t, float64(ts), doc["timestamp_ns"])
+	assert.Equal(t, "upload", doc["action"])
+	assert.Equal(t, "/dir/file.txt", doc["virtual_path"])
+	assert.Equal(t, "192.168.1.2", doc["ip"])
+	doc = c.getIndex("events-provider-2026.03.15")["provider1"]
+	require.NotNil(t, doc)
+	assert.Equal(t, "user", doc["object_type"])
+	doc = c.getIndex("events-log-2026.03.14")["log1"]
+	require.NotNil(t, doc)
+	assert.Equal(t, "login failed", doc["message"])
+}
+
+func TestRetries(t *testing.T) {
+	c := newFakeCluster(t)
+	c.bulkStatuses = []int{http.StatusTooManyRequests, http.StatusServiceUnavailable}
+	// the second document is rejected because the cluster is busy, the third one
+	// is invalid and it is not retried, the fourth one fails on an unavailable
+	// shard and it is retried
+	c.itemStatuses = []int{http.StatusCreated, http.StatusTooManyRequests, http.StatusBadRequest,
+		http.StatusServiceUnavailable}
+	sink := newTestSink(t, c, Config{BatchSize: 4, MaxRetries: 5})
+	for _, id := range []string{"id1", "id2", "id3", "id4"} {
+		require.NoError(t, sink.WriteLogEvent(&db.LogEvent{ID: id, Timestamp: time.Now().UnixNano()}))
+	}
+	assert.Eventually(t, func() bool {
+		return c.countDocuments() == 3
+	}, 2*time.Second, 20*time.Millisecond)
+	require.NoError(t, sink.Close(time.Second))
+	assert.Equal(t, 4, c.bulks)
+	index := c.getIndex("sftpgo-log-" + time.Now().UTC().Format(indexDateFormat))
+	assert.Contains(t, index, "id1")
+	assert.Contains(t, index, "id2")
+	assert.NotContains(t, index, "id3")
+	assert.Contains(t, index, "id4")
+}
+
+func TestMaxRetries(t *testing.T) {
+	c := newFakeCluster(t)
+	c.bulkStatuses = []int{http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests}
+	sink := newTestSink(t, c, Config{MaxRetries: 1})
+	require.NoError(t, sink.WriteLogEvent(&db.LogEvent{ID: "id1", Timestamp: time.Now().UnixNano()}))
+	require.NoError(t, sink.Close(time.Second))
+	assert.Equal(t, 2, c.bulks)
+	assert.Equal(t, 0, c.countDocuments())
+}
+
+func TestRetention(t *testing.T) {
+	c := newFakeCluster(t)
+	c.indices["sftpgo-fs-2026.01.01"] = map[string]map[string]any{}
+	sink := newTestSink(t, c, Config{RetentionDays: 3})
+	// the retention checker runs at startup too
+	require.NoError(t, sink.Close(time.Second))
+	assert.Equal(t, []string{"sftpgo-fs-2026.01.01"}, c.deleted)
+
+	c.deleted = nil
+	for _, name := range []string{"sftpgo-fs-2026.03.10", "sftpgo-fs-2026.03.11", "sftpgo-log-2026.03.12",
+		"sftpgo-provider-2026.03.09", "sftpgo-fs-invalid", "sftpgo-other-2026.03.01"} {
+		c.indices[name] = map[string]map[string]any{}
+	}
+	require.NoError(t, sink.deleteOldIndices(time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC)))
+	slices.Sort(c.deleted)
+	assert.Equal(t, []string{"sftpgo-fs-2026.03.10", "sftpgo-provider-2026.03.09"}, c.deleted)
+	assert.Contains(t, c.indices, "sftpgo-fs-2026.03.11")
+	assert.Contains(t, c.indices, "sftpgo-log-2026.03.12")
+	assert.Contains(t, c.indices, "sftpgo-fs-invalid")
+	assert.Contains(t, c.indices, "sftpgo-other-2026.03.01")
+}
+
+func TestQueueFull(t *testing.T) {
+	// the flusher is not started, so the queued documents are never sent
+	s := &Sink{
+		config: &Config{IndexPrefix: "sftpgo-"},
+		queue:  make(chan *document, 1),
+	}
+	require.NoError(t, s.WriteLogEvent(&db.LogEvent{ID: "id1", Timestamp: time.Now().UnixNano()}))
+	err := s.WriteLogEvent(&db.LogEvent{ID: "id2", Timestamp: time.Now().UnixNano()})
+	assert.ErrorContains(t, err, "queue full")
+	assert.Len(t, s.queue, 1)
+}
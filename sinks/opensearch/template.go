@@ -0,0 +1,114 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package opensearch
+
+import (
+	"encoding/json"
+	"maps"
+)
+
+// index types, they are appended to the index prefix
+const (
+	indexTypeFs       = "fs"
+	indexTypeProvider = "provider"
+	indexTypeLog      = "log"
+)
+
+var indexTypes = []string{indexTypeFs, indexTypeProvider, indexTypeLog}
+
+type property map[string]any
+
+var (
+	keywordProperty = property{"type": "keyword", "ignore_above": 1024}
+	// text fields can be searched by words and sorted/aggregated using the keyword sub-field
+	textProperty = property{
+		"type": "text",
+		"fields": map[string]any{
+			"keyword": map[string]any{"type": "keyword", "ignore_above": 4096},
+		},
+	}
+	ipProperty       = property{"type": "ip", "ignore_malformed": true}
+	dateProperty     = property{"type": "date", "format": "epoch_millis"}
+	longProperty     = property{"type": "long"}
+	integerProperty  = property{"type": "integer"}
+	binaryProperty   = property{"type": "binary"}
+	commonProperties = map[string]property{
+		"id":           keywordProperty,
+		"timestamp":    dateProperty,
+		"timestamp_ns": longProperty,
+		"username":     keywordProperty,
+		"ip":           ipProperty,
+		"country_code": keywordProperty,
+		"city":         keywordProperty,
+		"asn":          longProperty,
+		"role":         keywordProperty,
+		"instance_id":  keywordProperty,
+	}
+	indexProperties = map[string]map[string]property{
+		indexTypeFs: {
+			"action":              keywordProperty,
+			"fs_path":             textProperty,
+			"fs_target_path":      textProperty,
+			"virtual_path":        textProperty,
+			"virtual_target_path": textProperty,
+			"ssh_cmd":             keywordProperty,
+			"file_size":           longProperty,
+			"elapsed":             longProperty,
+			"status":              integerProperty,
+			"protocol":            keywordProperty,
+			"session_id":          keywordProperty,
+			"fs_provider":         integerProperty,
+			"bucket":              keywordProperty,
+			"endpoint":            keywordProperty,
+			"open_flags":          integerProperty,
+		},
+		indexTypeProvider: {
+			"action":      keywordProperty,
+			"object_type": keywordProperty,
+			"object_name": keywordProperty,
+			"object_data": binaryProperty,
+		},
+		indexTypeLog: {
+			"event":    integerProperty,
+			"protocol": keywordProperty,
+			"message":  textProperty,
+		},
+	}
+)
+
+// getIndexTemplate returns the composable index template for the given index type
+func (s *Sink) getIndexTemplate(indexType string) ([]byte, error) {
+	properties := maps.Clone(commonProperties)
+	maps.Copy(properties, indexProperties[indexType])
+	settings := map[string]any{
+		"number_of_shards":   s.config.Shards,
+		"number_of_replicas": s.config.Replicas,
+	}
+	// the OpenSearch ISM policies are attached after the indices are created
+	if s.config.LifecyclePolicy != "" && !s.ism {
+		settings["index.lifecycle.name"] = s.config.LifecyclePolicy
+	}
+	return json.Marshal(map[string]any{
+		"index_patterns": []string{s.config.IndexPrefix + indexType + "-*"},
+		"priority":       100,
+		"template": map[string]any{
+			"settings": settings,
+			"mappings": map[string]any{
+				"dynamic":    false,
+				"properties": properties,
+			},
+		},
+	})
+}
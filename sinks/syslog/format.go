@@ -0,0 +1,363 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package syslog
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+)
+
+// Syslog severities
+const (
+	severityError   = 3
+	severityWarning = 4
+	severityNotice  = 5
+	severityInfo    = 6
+)
+
+const (
+	nilValue        = "-"
+	timestampFormat = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// SFTPGo filesystem event statuses
+const (
+	fsStatusOK            = 1
+	fsStatusQuotaExceeded = 3
+)
+
+var facilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+func getFsEventSeverity(ev *db.FsEvent) int {
+	switch ev.Status {
+	case fsStatusOK:
+		return severityInfo
+	case fsStatusQuotaExceeded:
+		return severityWarning
+	default:
+		return severityError
+	}
+}
+
+func getLogEventSeverity(ev *db.LogEvent) int {
+	switch db.GetLogEventName(ev.Event) {
+	case "login_ok":
+		return severityInfo
+	case "login_failed", "login_no_user":
+		return severityWarning
+	default:
+		return severityNotice
+	}
+}
+
+// getCEFSeverity maps a syslog severity to the CEF 0-10 scale
+func getCEFSeverity(severity int) int {
+	switch severity {
+	case severityInfo:
+		return 3
+	case severityNotice:
+		return 5
+	case severityWarning:
+		return 6
+	default:
+		return 8
+	}
+}
+
+// field is a key/value pair included in the formatted messages, empty values
+// are omitted
+type field struct {
+	key   string
+	value string
+}
+
+// message is the formatter independent representation of an event
+type message struct {
+	msgID     string
+	timestamp int64
+	severity  int
+	// signature and name are used for CEF messages
+	signature string
+	name      string
+	text      string
+	fields    []field
+	// cef contains the fields mapped to CEF extension keys
+	cef []field
+}
+
+func formatInt(v int64) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatInt(v, 10)
+}
+
+func fsEventMessage(ev *db.FsEvent) *message {
+	outcome := "success"
+	if ev.Status != fsStatusOK {
+		outcome = "failure"
+	}
+	text := ev.Action + " " + ev.VirtualPath
+	if ev.VirtualTargetPath != "" {
+		text += " -> " + ev.VirtualTargetPath
+	}
+	return &message{
+		msgID:     "fs",
+		timestamp: ev.Timestamp,
+		severity:  getFsEventSeverity(ev),
+		signature: "fs:" + ev.Action,
+		name:      "Filesystem " + ev.Action,
+		text:      text,
+		fields: []field{
+			{"id", ev.ID},
+			{"action", ev.Action},
+			{"username", ev.Username},
+			{"fs_path", ev.FsPath},
+			{"fs_target_path", ev.FsTargetPath},
+			{"virtual_path", ev.VirtualPath},
+			{"virtual_target_path", ev.VirtualTargetPath},
+			{"ssh_cmd", ev.SSHCmd},
+			{"file_size", formatInt(ev.FileSize)},
+			{"elapsed", formatInt(ev.Elapsed)},
+			{"status", strconv.Itoa(ev.Status)},
+			{"protocol", ev.Protocol},
+			{"ip", ev.IP},
+			{"session_id", ev.SessionID},
+			{"fs_provider", strconv.Itoa(ev.FsProvider)},
+			{"bucket", ev.Bucket},
+			{"endpoint", ev.Endpoint},
+			{"open_flags", formatInt(int64(ev.OpenFlags))},
+			{"role", ev.Role},
+			{"instance_id", ev.InstanceID},
+		},
+		cef: []field{
+			{"externalId", ev.ID},
+			{"act", ev.Action},
+			{"suser", ev.Username},
+			{"src", ev.IP},
+			{"app", ev.Protocol},
+			{"fname", path.Base(ev.VirtualPath)},
+			{"filePath", ev.VirtualPath},
+			{"fsize", formatInt(ev.FileSize)},
+			{"outcome", outcome},
+			{"cs1Label", "sessionId"},
+			{"cs1", ev.SessionID},
+			{"cs2Label", "role"},
+			{"cs2", ev.Role},
+			{"cs3Label", "instanceId"},
+			{"cs3", ev.InstanceID},
+			{"cs4Label", "targetPath"},
+			{"cs4", ev.VirtualTargetPath},
+			{"cn1Label", "elapsedMs"},
+			{"cn1", formatInt(ev.Elapsed)},
+		},
+	}
+}
+
+func providerEventMessage(ev *db.ProviderEvent) *message {
+	return &message{
+		msgID:     "provider",
+		timestamp: ev.Timestamp,
+		severity:  severityNotice,
+		signature: "provider:" + ev.ObjectType + ":" + ev.Action,
+		name:      "Provider " + ev.ObjectType + " " + ev.Action,
+		text:      ev.Action + " " + ev.ObjectType + " " + ev.ObjectName,
+		fields: []field{
+			{"id", ev.ID},
+			{"action", ev.Action},
+			{"username", ev.Username},
+			{"ip", ev.IP},
+			{"object_type", ev.ObjectType},
+			{"object_name", ev.ObjectName},
+			{"role", ev.Role},
+			{"instance_id", ev.InstanceID},
+		},
+		cef: []field{
+			{"externalId", ev.ID},
+			{"act", ev.Action},
+			{"suser", ev.Username},
+			{"src", ev.IP},
+			{"cs1Label", "objectType"},
+			{"cs1", ev.ObjectType},
+			{"cs2Label", "role"},
+			{"cs2", ev.Role},
+			{"cs3Label", "instanceId"},
+			{"cs3", ev.InstanceID},
+			{"cs4Label", "objectName"},
+			{"cs4", ev.ObjectName},
+		},
+	}
+}
+
+func logEventMessage(ev *db.LogEvent) *message {
+	eventName := db.GetLogEventName(ev.Event)
+	text := eventName
+	if ev.Message != "" {
+		text += ": " + ev.Message
+	}
+	return &message{
+		msgID:     "log",
+		timestamp: ev.Timestamp,
+		severity:  getLogEventSeverity(ev),
+		signature: "log:" + eventName,
+		name:      strings.ReplaceAll(eventName, "_", " "),
+		text:      text,
+		fields: []field{
+			{"id", ev.ID},
+			{"event", eventName},
+			{"protocol", ev.Protocol},
+			{"username", ev.Username},
+			{"ip", ev.IP},
+			{"role", ev.Role},
+			{"instance_id", ev.InstanceID},
+		},
+		cef: []field{
+			{"externalId", ev.ID},
+			{"act", eventName},
+			{"suser", ev.Username},
+			{"src", ev.IP},
+			{"app", ev.Protocol},
+			{"msg", ev.Message},
+			{"cs2Label", "role"},
+			{"cs2", ev.Role},
+			{"cs3Label", "instanceId"},
+			{"cs3", ev.InstanceID},
+		},
+	}
+}
+
+// formatter builds the syslog messages
+type formatter struct {
+	format   string
+	facility int
+	hostname string
+	appName  string
+	sdID     string
+	version  string
+}
+
+// header returns the RFC 5424 header, including the trailing space
+func (f *formatter) header(m *message) string {
+	ts := time.Unix(0, m.timestamp).UTC().Format(timestampFormat)
+	return fmt.Sprintf("<%d>1 %s %s %s %s %s ", f.facility*8+m.severity, ts, f.hostname, f.appName, nilValue,
+		m.msgID)
+}
+
+func (f *formatter) formatMessage(m *message) []byte {
+	var sb strings.Builder
+	sb.WriteString(f.header(m))
+	if f.format == FormatCEF {
+		sb.WriteString(nilValue)
+		sb.WriteByte(' ')
+		f.writeCEF(&sb, m)
+		return []byte(sb.String())
+	}
+	sb.WriteByte('[')
+	sb.WriteString(f.sdID)
+	for _, fl := range m.fields {
+		if fl.value == "" {
+			continue
+		}
+		sb.WriteByte(' ')
+		sb.WriteString(fl.key)
+		sb.WriteString(`="`)
+		sb.WriteString(escapeSDParam(fl.value))
+		sb.WriteByte('"')
+	}
+	sb.WriteString("] ")
+	sb.WriteString(m.text)
+	return []byte(sb.String())
+}
+
+func (f *formatter) writeCEF(sb *strings.Builder, m *message) {
+	sb.WriteString("CEF:0|SFTPGo|sftpgo-plugin-eventstore|")
+	sb.WriteString(escapeCEFHeader(f.version))
+	sb.WriteByte('|')
+	sb.WriteString(escapeCEFHeader(m.signature))
+	sb.WriteByte('|')
+	sb.WriteString(escapeCEFHeader(m.name))
+	sb.WriteByte('|')
+	sb.WriteString(strconv.Itoa(getCEFSeverity(m.severity)))
+	sb.WriteByte('|')
+	sb.WriteString("rt=")
+	sb.WriteString(strconv.FormatInt(time.Unix(0, m.timestamp).UnixMilli(), 10))
+	for idx, fl := range m.cef {
+		if fl.value == "" {
+			continue
+		}
+		// labels are only useful if the value is set
+		if strings.HasSuffix(fl.key, "Label") && (idx+1 >= len(m.cef) || m.cef[idx+1].value == "") {
+			continue
+		}
+		sb.WriteByte(' ')
+		sb.WriteString(fl.key)
+		sb.WriteByte('=')
+		sb.WriteString(escapeCEFExtension(fl.value))
+	}
+}
+
+// escapeSDParam escapes a structured data parameter value as required by RFC 5424
+func escapeSDParam(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
+
+func escapeCEFHeader(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ").Replace(value)
+}
+
+func escapeCEFExtension(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r", `\r`, "\n", `\n`).Replace(value)
+}
+
+// sanitizeHeaderField returns a valid RFC 5424 header field, only printable
+// US-ASCII characters are allowed
+func sanitizeHeaderField(value string, maxLen int) string {
+	var sb strings.Builder
+	for i := 0; i < len(value) && sb.Len() < maxLen; i++ {
+		if c := value[i]; c > ' ' && c < 0x7f {
+			sb.WriteByte(c)
+		}
+	}
+	if sb.Len() == 0 {
+		return nilValue
+	}
+	return sb.String()
+}
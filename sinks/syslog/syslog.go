@@ -0,0 +1,315 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package syslog implements a sink that forwards the events to a syslog
+// collector using the RFC 5424 or the ArcSight CEF format
+package syslog
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+// Supported message formats
+const (
+	FormatRFC5424 = "rfc5424"
+	FormatCEF     = "cef"
+)
+
+// Supported networks
+const (
+	NetworkUDP = "udp"
+	NetworkTCP = "tcp"
+	NetworkTLS = "tls"
+)
+
+// Config defines the configuration for the syslog sink
+type Config struct {
+	// Network defines the transport: udp, tcp or tls. TCP and TLS messages
+	// use octet-counting framing
+	Network string
+	// Address defines the collector address as host:port
+	Address string
+	// Format defines the message format: rfc5424 or cef
+	Format string
+	// Facility defines the syslog facility name, for example local0
+	Facility string
+	// Hostname is the HOSTNAME header field, default is the OS hostname
+	Hostname string
+	// AppName is the APP-NAME header field, default is sftpgo
+	AppName string
+	// SDID defines the structured data ID for RFC 5424 messages
+	SDID string
+	// Version is the plugin version included in CEF messages
+	Version string
+	// TLSConfig defines the TLS configuration for the tls network
+	TLSConfig *tls.Config
+	// BufferSize is the maximum number of messages buffered while the collector
+	// is unreachable, if the buffer is full the oldest messages are dropped.
+	// UDP writes don't detect an unreachable collector, so over UDP only the
+	// messages waiting to be sent are buffered
+	BufferSize int
+	// RetryInterval is the initial delay before reconnecting, it doubles on
+	// each failure up to one minute
+	RetryInterval time.Duration
+	// Timeout defines the connection and write timeout
+	Timeout time.Duration
+}
+
+func (c *Config) validate() error {
+	if c.Address == "" {
+		return errors.New("the syslog address is required")
+	}
+	switch c.Network {
+	case "":
+		c.Network = NetworkUDP
+	case NetworkUDP, NetworkTCP, NetworkTLS:
+	default:
+		return fmt.Errorf("unsupported network %q", c.Network)
+	}
+	switch c.Format {
+	case "":
+		c.Format = FormatRFC5424
+	case FormatRFC5424, FormatCEF:
+	default:
+		return fmt.Errorf("unsupported format %q", c.Format)
+	}
+	if c.Facility == "" {
+		c.Facility = "local0"
+	}
+	if _, ok := facilities[c.Facility]; !ok {
+		return fmt.Errorf("unsupported facility %q", c.Facility)
+	}
+	if c.Hostname == "" {
+		c.Hostname, _ = os.Hostname()
+	}
+	if c.AppName == "" {
+		c.AppName = "sftpgo"
+	}
+	if c.SDID == "" {
+		c.SDID = "sftpgo@32473"
+	}
+	if c.Version == "" {
+		c.Version = "1.0"
+	}
+	if c.BufferSize <= 0 {
+		c.BufferSize = 10000
+	}
+	if c.RetryInterval <= 0 {
+		c.RetryInterval = time.Second
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 10 * time.Second
+	}
+	return nil
+}
+
+// Sink forwards the events to a syslog collector. Messages are sent
+// asynchronously and buffered in memory while the collector is unreachable
+type Sink struct {
+	config    *Config
+	formatter *formatter
+	buffer    chan []byte
+	done      chan struct{}
+	stopped   chan struct{}
+	// unsent is the number of buffered messages, including the one the
+	// sender is trying to write
+	unsent atomic.Int64
+
+	mu      sync.Mutex
+	closed  bool
+	dropped int
+}
+
+// NewSink returns a new syslog sink and starts the sender
+func NewSink(config Config) (*Sink, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	s := &Sink{
+		config: &config,
+		formatter: &formatter{
+			format:   config.Format,
+			facility: facilities[config.Facility],
+			hostname: sanitizeHeaderField(config.Hostname, 255),
+			appName:  sanitizeHeaderField(config.AppName, 48),
+			sdID:     config.SDID,
+			version:  config.Version,
+		},
+		buffer:  make(chan []byte, config.BufferSize),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go s.sender()
+	return s, nil
+}
+
+// Name implements db.Sink
+func (s *Sink) Name() string {
+	return "syslog"
+}
+
+func (s *Sink) enqueue(m *message) error {
+	msg := s.formatter.formatMessage(m)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errors.New("syslog sink closed")
+	}
+	for {
+		select {
+		case s.buffer <- msg:
+			s.unsent.Add(1)
+			return nil
+		default:
+		}
+		// the buffer is full, drop the oldest message
+		select {
+		case <-s.buffer:
+			s.unsent.Add(-1)
+			s.dropped++
+			if s.dropped == 1 || s.dropped%1000 == 0 {
+				logger.AppLogger.Warn("syslog buffer full, oldest messages dropped", "dropped", s.dropped)
+			}
+		default:
+		}
+	}
+}
+
+// WriteFsEvent implements db.Sink
+func (s *Sink) WriteFsEvent(ev *db.FsEvent) error {
+	return s.enqueue(fsEventMessage(ev))
+}
+
+// WriteProviderEvent implements db.Sink
+func (s *Sink) WriteProviderEvent(ev *db.ProviderEvent) error {
+	return s.enqueue(providerEventMessage(ev))
+}
+
+// WriteLogEvent implements db.Sink
+func (s *Sink) WriteLogEvent(ev *db.LogEvent) error {
+	return s.enqueue(logEventMessage(ev))
+}
+
+func (s *Sink) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: s.config.Timeout}
+	switch s.config.Network {
+	case NetworkTLS:
+		tlsConfig := s.config.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		return tls.DialWithDialer(dialer, "tcp", s.config.Address, tlsConfig)
+	default:
+		return dialer.Dial(s.config.Network, s.config.Address)
+	}
+}
+
+func (s *Sink) write(conn net.Conn, msg []byte) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(s.config.Timeout)); err != nil {
+		return err
+	}
+	if s.config.Network != NetworkUDP {
+		// octet-counting framing as defined in RFC 6587 and RFC 5425
+		msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	}
+	_, err := conn.Write(msg)
+	return err
+}
+
+// sender writes the buffered messages, reconnecting if required. A message
+// is removed from the buffer only after a successful write
+func (s *Sink) sender() {
+	defer close(s.stopped)
+
+	var conn net.Conn
+	var pending []byte
+	retryInterval := s.config.RetryInterval
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	for {
+		if pending == nil {
+			select {
+			case pending = <-s.buffer:
+			case <-s.done:
+				return
+			}
+		}
+		var err error
+		if conn == nil {
+			conn, err = s.dial()
+		}
+		if err == nil {
+			err = s.write(conn, pending)
+			if err == nil {
+				s.unsent.Add(-1)
+				pending = nil
+				retryInterval = s.config.RetryInterval
+				continue
+			}
+			conn.Close()
+			conn = nil
+		}
+		logger.AppLogger.Warn("unable to send syslog message, retrying", "address", s.config.Address,
+			"retry interval", retryInterval, "error", err)
+		select {
+		case <-time.After(retryInterval):
+		case <-s.done:
+			return
+		}
+		retryInterval = min(2*retryInterval, time.Minute)
+	}
+}
+
+// Close stops accepting new events and waits until the buffered messages are
+// sent or the given timeout expires
+func (s *Sink) Close(timeout time.Duration) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	deadline := time.After(timeout)
+	for s.unsent.Load() > 0 {
+		select {
+		case <-deadline:
+			close(s.done)
+			<-s.stopped
+			return fmt.Errorf("unable to send %d buffered syslog messages", s.unsent.Load())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	close(s.done)
+	<-s.stopped
+	return nil
+}
@@ -0,0 +1,376 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package syslog
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+)
+
+var testTimestamp = time.Date(2026, 3, 14, 10, 20, 30, 123456789, time.UTC).UnixNano()
+
+// collector records the messages received over TCP, octet-counting framing
+// is expected
+type collector struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	messages []string
+}
+
+func newCollector(t *testing.T, listener net.Listener) *collector {
+	c := &collector{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go c.handleConn(conn)
+		}
+	}()
+	t.Cleanup(func() {
+		listener.Close()
+	})
+	return c
+}
+
+func (c *collector) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for {
+		size, err := r.ReadString(' ')
+		if err != nil {
+			return
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(size))
+		if err != nil {
+			return
+		}
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			return
+		}
+		c.mu.Lock()
+		c.messages = append(c.messages, string(msg))
+		c.mu.Unlock()
+	}
+}
+
+func (c *collector) getMessages() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string{}, c.messages...)
+}
+
+func getTestFormatter(format string) *formatter {
+	return &formatter{
+		format:   format,
+		facility: facilities["local0"],
+		hostname: "sftpgo-host",
+		appName:  "sftpgo",
+		sdID:     "sftpgo@32473",
+		version:  "1.0.25",
+	}
+}
+
+func TestValidation(t *testing.T) {
+	_, err := NewSink(Config{})
+	assert.ErrorContains(t, err, "address is required")
+	_, err = NewSink(Config{Address: "127.0.0.1:514", Network: "unix"})
+	assert.ErrorContains(t, err, "unsupported network")
+	_, err = NewSink(Config{Address: "127.0.0.1:514", Format: "json"})
+	assert.ErrorContains(t, err, "unsupported format")
+	_, err = NewSink(Config{Address: "127.0.0.1:514", Facility: "local8"})
+	assert.ErrorContains(t, err, "unsupported facility")
+
+	c := Config{Address: "127.0.0.1:514"}
+	require.NoError(t, c.validate())
+	assert.Equal(t, NetworkUDP, c.Network)
+	assert.Equal(t, FormatRFC5424, c.Format)
+	assert.Equal(t, "local0", c.Facility)
+	assert.Equal(t, 10000, c.BufferSize)
+}
+
+func TestSeverity(t *testing.T) {
+	assert.Equal(t, severityInfo, getFsEventSeverity(&db.FsEvent{Status: 1}))
+	assert.Equal(t, severityError, getFsEventSeverity(&db.FsEvent{Status: 2}))
+	assert.Equal(t, severityWarning, getFsEventSeverity(&db.FsEvent{Status: 3}))
+	assert.Equal(t, severityWarning, getLogEventSeverity(&db.LogEvent{Event: 1}))
+	assert.Equal(t, severityWarning, getLogEventSeverity(&db.LogEvent{Event: 2}))
+	assert.Equal(t, severityNotice, getLogEventSeverity(&db.LogEvent{Event: 3}))
+	assert.Equal(t, severityNotice, getLogEventSeverity(&db.LogEvent{Event: 4}))
+	assert.Equal(t, severityInfo, getLogEventSeverity(&db.LogEvent{Event: 5}))
+	assert.Equal(t, severityNotice, getLogEventSeverity(&db.LogEvent{Event: 10}))
+	assert.Equal(t, "event_10", db.GetLogEventName(10))
+	assert.Equal(t, 3, getCEFSeverity(severityInfo))
+	assert.Equal(t, 8, getCEFSeverity(severityError))
+}
+
+func TestRFC5424Format(t *testing.T) {
+	f := getTestFormatter(FormatRFC5424)
+	msg := f.formatMessage(fsEventMessage(&db.FsEvent{
+		ID:          "id1",
+		Timestamp:   testTimestamp,
+		Action:      "upload",
+		Username:    "user",
+		FsPath:      `/srv/data/a "quoted" [name].txt`,
+		VirtualPath: `/a "quoted" [name].txt`,
+		FileSize:    100,
+		Status:      1,
+		Protocol:    "SFTP",
+		IP:          "192.168.1.1",
+		SessionID:   "session",
+		InstanceID:  "node1",
+	}))
+	assert.Equal(t, `<134>1 2026-03-14T10:20:30.123456Z sftpgo-host sftpgo - fs [sftpgo@32473 id="id1" action="upload" `+
+		`username="user" fs_path="/srv/data/a \"quoted\" [name\].txt" virtual_path="/a \"quoted\" [name\].txt" `+
+		`file_size="100" status="1" protocol="SFTP" ip="192.168.1.1" session_id="session" fs_provider="0" `+
+		`instance_id="node1"] upload /a "quoted" [name].txt`, string(msg))
+
+	msg = f.formatMessage(fsEventMessage(&db.FsEvent{
+		ID:        "id2",
+		Timestamp: testTimestamp,
+		Action:    "rename",
+		Username:  "user",
+		Status:    2,
+		Protocol:  "FTP",
+	}))
+	assert.True(t, strings.HasPrefix(string(msg), "<131>1 "), string(msg))
+
+	msg = f.formatMessage(providerEventMessage(&db.ProviderEvent{
+		ID:         "id3",
+		Timestamp:  testTimestamp,
+		Action:     "update",
+		Username:   "admin",
+		IP:         "::1",
+		ObjectType: "user",
+		ObjectName: "user1",
+		ObjectData: []byte(`{"username":"user1"}`),
+	}))
+	assert.Equal(t, `<133>1 2026-03-14T10:20:30.123456Z sftpgo-host sftpgo - provider [sftpgo@32473 id="id3" `+
+		`action="update" username="admin" ip="::1" object_type="user" object_name="user1"] update user user1`, string(msg))
+
+	msg = f.formatMessage(logEventMessage(&db.LogEvent{
+		ID:        "id4",
+		Timestamp: testTimestamp,
+		Event:     1,
+		Protocol:  "SSH",
+		Username:  "user",
+		IP:        "10.0.0.1",
+		Message:   "invalid credentials",
+	}))
+	assert.Equal(t, `<132>1 2026-03-14T10:20:30.123456Z sftpgo-host sftpgo - log [sftpgo@32473 id="id4" `+
+		`event="login_failed" protocol="SSH" username="user" ip="10.0.0.1"] login_failed: invalid credentials`,
+		string(msg))
+}
+
+func TestCEFFormat(t *testing.T) {
+	f := getTestFormatter(FormatCEF)
+	msg := f.formatMessage(fsEventMessage(&db.FsEvent{
+		ID:          "id1",
+		Timestamp:   testTimestamp,
+		Action:      "upload",
+		Username:    "user",
+		VirtualPath: "/dir/a=b.txt",
+		FileSize:    100,
+		Elapsed:     20,
+		Status:      3,
+		Protocol:    "SFTP",
+		IP:          "192.168.1.1",
+		SessionID:   "session",
+	}))
+	assert.Equal(t, `<132>1 2026-03-14T10:20:30.123456Z sftpgo-host sftpgo - fs - CEF:0|SFTPGo|sftpgo-plugin-eventstore|1.0.25|fs:upload|`+
+		`Filesystem upload|6|rt=1773483630123 externalId=id1 act=upload suser=user src=192.168.1.1 app=SFTP `+
+		`fname=a\=b.txt filePath=/dir/a\=b.txt fsize=100 outcome=failure cs1Label=sessionId cs1=session `+
+		`cn1Label=elapsedMs cn1=20`, string(msg))
+
+	msg = f.formatMessage(providerEventMessage(&db.ProviderEvent{
+		ID:         "id2",
+		Timestamp:  testTimestamp,
+		Action:     "delete",
+		Username:   "admin",
+		ObjectType: "folder",
+		ObjectName: "a|b",
+	}))
+	assert.Equal(t, `<133>1 2026-03-14T10:20:30.123456Z sftpgo-host sftpgo - provider - CEF:0|SFTPGo|sftpgo-plugin-eventstore|1.0.25|`+
+		`provider:folder:delete|Provider folder delete|5|rt=1773483630123 externalId=id2 act=delete suser=admin `+
+		`cs1Label=objectType cs1=folder cs4Label=objectName cs4=a|b`, string(msg))
+
+	msg = f.formatMessage(logEventMessage(&db.LogEvent{
+		ID:        "id3",
+		Timestamp: testTimestamp,
+		Event:     5,
+		Protocol:  "SSH",
+		Username:  "user",
+		Message:   "line1\nline2",
+	}))
+	assert.Equal(t, `<134>1 2026-03-14T10:20:30.123456Z sftpgo-host sftpgo - log - CEF:0|SFTPGo|sftpgo-plugin-eventstore|1.0.25|`+
+		`log:login_ok|login ok|3|rt=1773483630123 externalId=id3 act=login_ok suser=user app=SSH msg=line1\nline2`,
+		string(msg))
+
+	assert.Equal(t, `a\|b\\c`, escapeCEFHeader(`a|b\c`))
+	assert.Equal(t, "-", sanitizeHeaderField(" \t", 10))
+	assert.Equal(t, "host", sanitizeHeaderField("ho st name", 4))
+}
+
+func TestUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sink, err := NewSink(Config{
+		Network:  NetworkUDP,
+		Address:  conn.LocalAddr().String(),
+		Hostname: "host",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "syslog", sink.Name())
+	require.NoError(t, sink.WriteLogEvent(&db.LogEvent{ID: "id1", Timestamp: testTimestamp, Event: 2}))
+	require.NoError(t, sink.Close(5*time.Second))
+	assert.Error(t, sink.WriteLogEvent(&db.LogEvent{ID: "id2"}))
+
+	buf := make([]byte, 2048)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Equal(t, `<132>1 2026-03-14T10:20:30.123456Z host sftpgo - log [sftpgo@32473 id="id1" `+
+		`event="login_no_user"] login_no_user`, string(buf[:n]))
+}
+
+func TestTCPBuffering(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	sink, err := NewSink(Config{
+		Network:       NetworkTCP,
+		Address:       addr,
+		Format:        FormatCEF,
+		BufferSize:    3,
+		RetryInterval: 20 * time.Millisecond,
+		Timeout:       time.Second,
+	})
+	require.NoError(t, err)
+	// the collector is unreachable, the messages are buffered and the
+	// oldest one is dropped
+	for i := 1; i <= 4; i++ {
+		require.NoError(t, sink.WriteFsEvent(&db.FsEvent{
+			ID:        "id" + strconv.Itoa(i),
+			Timestamp: testTimestamp,
+			Action:    "upload",
+			Status:    1,
+		}))
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	listener, err = net.Listen("tcp", addr)
+	require.NoError(t, err)
+	c := newCollector(t, listener)
+	require.NoError(t, sink.Close(5*time.Second))
+	assert.Eventually(t, func() bool {
+		return len(c.getMessages()) == 3
+	}, 5*time.Second, 20*time.Millisecond)
+	for idx, msg := range c.getMessages() {
+		assert.Contains(t, msg, "CEF:0|SFTPGo|sftpgo-plugin-eventstore|")
+		assert.Contains(t, msg, "externalId=id"+strconv.Itoa(idx+2)+" ")
+	}
+}
+
+func TestCloseTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	sink, err := NewSink(Config{
+		Network:       NetworkTCP,
+		Address:       addr,
+		RetryInterval: 20 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	require.NoError(t, sink.WriteLogEvent(&db.LogEvent{ID: "id1"}))
+	err = sink.Close(100 * time.Millisecond)
+	assert.ErrorContains(t, err, "unable to send 1 buffered syslog messages")
+}
+
+func TestTLS(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		MinVersion:   tls.VersionTLS12,
+	})
+	require.NoError(t, err)
+	c := newCollector(t, listener)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	sink, err := NewSink(Config{
+		Network:   NetworkTLS,
+		Address:   listener.Addr().String(),
+		TLSConfig: &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12},
+	})
+	require.NoError(t, err)
+	require.NoError(t, sink.WriteProviderEvent(&db.ProviderEvent{
+		ID:         "id1",
+		Timestamp:  testTimestamp,
+		Action:     "add",
+		ObjectType: "user",
+		ObjectName: "user1",
+	}))
+	require.NoError(t, sink.WriteLogEvent(&db.LogEvent{ID: "id2", Timestamp: testTimestamp, Event: 5}))
+	require.NoError(t, sink.Close(5*time.Second))
+	assert.Eventually(t, func() bool {
+		return len(c.getMessages()) == 2
+	}, 5*time.Second, 20*time.Millisecond)
+	messages := c.getMessages()
+	assert.Contains(t, messages[0], ` provider [sftpgo@32473 id="id1" action="add" object_type="user" object_name="user1"]`)
+	assert.Contains(t, messages[1], ` log [sftpgo@32473 id="id2" event="login_ok"] login_ok`)
+}
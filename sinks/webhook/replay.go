@@ -0,0 +1,111 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+const replayPageSize = 100
+
+// ReplayResult defines the replay outcome
+type ReplayResult struct {
+	Delivered int
+	Failed    int
+}
+
+// replayer resends the dead letters, the store functions can be replaced
+// within test cases
+type replayer struct {
+	sender           *sender
+	getDeadLetters   func(after string, limit int, ids []string) ([]db.WebhookDeadLetter, error)
+	updateDeadLetter func(*db.WebhookDeadLetter) error
+	deleteDeadLetter func(id string) error
+}
+
+func newReplayer(config *Config) *replayer {
+	return &replayer{
+		sender:           newSender(config),
+		getDeadLetters:   db.GetWebhookDeadLetters,
+		updateDeadLetter: db.UpdateWebhookDeadLetter,
+		deleteDeadLetter: db.DeleteWebhookDeadLetter,
+	}
+}
+
+// Replay resends the dead letters to their original URL, using the retry
+// policy from the given config. Delivered dead letters are deleted, failed ones
+// are updated with the new attempts and error. If ids is not empty only the
+// specified dead letters are resent, limit is the maximum number of dead
+// letters to resend, 0 means no limit
+func Replay(config Config, ids []string, limit int) (ReplayResult, error) {
+	if err := config.validate(false); err != nil {
+		return ReplayResult{}, err
+	}
+	return newReplayer(&config).replay(ids, limit)
+}
+
+func (r *replayer) replay(ids []string, limit int) (ReplayResult, error) {
+	var result ReplayResult
+	after := ""
+	for {
+		pageSize := replayPageSize
+		if limit > 0 {
+			pageSize = min(pageSize, limit-result.Delivered-result.Failed)
+		}
+		if pageSize <= 0 {
+			return result, nil
+		}
+		deadLetters, err := r.getDeadLetters(after, pageSize, ids)
+		if err != nil {
+			return result, fmt.Errorf("unable to get webhook dead letters: %w", err)
+		}
+		for idx := range deadLetters {
+			d := &deadLetters[idx]
+			after = d.ID
+			attempts, err := r.sender.deliver(context.Background(), &delivery{
+				url:       d.URL,
+				eventType: d.EventType,
+				eventID:   d.EventID,
+				payload:   d.Payload,
+			})
+			if err == nil {
+				logger.AppLogger.Debug("webhook dead letter delivered", "id", d.ID, "url", d.URL,
+					"event id", d.EventID)
+				result.Delivered++
+				if err := r.deleteDeadLetter(d.ID); err != nil {
+					return result, fmt.Errorf("unable to delete webhook dead letter %q: %w", d.ID, err)
+				}
+				continue
+			}
+			logger.AppLogger.Warn("webhook dead letter delivery failed", "id", d.ID, "url", d.URL,
+				"event id", d.EventID, "error", err)
+			result.Failed++
+			d.Timestamp = time.Now().UnixNano()
+			d.Attempts += attempts
+			d.LastError = err.Error()
+			if err := r.updateDeadLetter(d); err != nil {
+				return result, fmt.Errorf("unable to update webhook dead letter %q: %w", d.ID, err)
+			}
+		}
+		if len(deadLetters) < pageSize {
+			return result, nil
+		}
+	}
+}
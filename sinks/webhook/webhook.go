@@ -0,0 +1,365 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package webhook implements a sink that delivers the events to HTTP endpoints
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+// Event types sent in the event type header
+const (
+	EventTypeFs       = db.EventTypeFs
+	EventTypeProvider = db.EventTypeProvider
+	EventTypeLog      = db.EventTypeLog
+	EventTypeAlert    = "alert"
+)
+
+// HTTP headers added to the deliveries
+const (
+	HeaderEventType = "X-SFTPGo-Event-Type"
+	HeaderEventID   = "X-SFTPGo-Event-Id"
+	HeaderTimestamp = "X-SFTPGo-Timestamp"
+	HeaderSignature = "X-SFTPGo-Signature"
+)
+
+const maxErrorBodySize = 512
+
+// Config defines the configuration for the webhook sink
+type Config struct {
+	// URLs defines the endpoints, each event is delivered to all of them
+	URLs []string
+	// Secret is the key used to sign the deliveries
+	Secret string
+	// MaxRetries defines how many times a failed delivery is retried
+	MaxRetries int
+	// InitialBackoff is the delay before the first retry, it doubles for
+	// each subsequent retry up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// QueueSize is the maximum number of pending deliveries, if the queue
+	// is full new deliveries are saved as dead letters
+	QueueSize int
+	// Workers defines the number of concurrent deliveries
+	Workers int
+	// Timeout defines the timeout for each HTTP request
+	Timeout   time.Duration
+	TLSConfig *tls.Config
+	UserAgent string
+}
+
+func (c *Config) validate(requireURLs bool) error {
+	if requireURLs && len(c.URLs) == 0 {
+		return errors.New("at least a webhook URL is required")
+	}
+	if c.Secret == "" {
+		return errors.New("a webhook secret is required")
+	}
+	if c.MaxRetries < 0 {
+		return fmt.Errorf("invalid max retries %d", c.MaxRetries)
+	}
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = time.Second
+	}
+	if c.MaxBackoff < c.InitialBackoff {
+		c.MaxBackoff = max(5*time.Minute, c.InitialBackoff)
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = 1000
+	}
+	if c.Workers <= 0 {
+		c.Workers = 4
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 30 * time.Second
+	}
+	if c.UserAgent == "" {
+		c.UserAgent = "sftpgo-plugin-eventstore"
+	}
+	return nil
+}
+
+// deliveryError is returned for failed deliveries
+type deliveryError struct {
+	err       error
+	retriable bool
+}
+
+func (e *deliveryError) Error() string {
+	return e.err.Error()
+}
+
+func (e *deliveryError) Unwrap() error {
+	return e.err
+}
+
+type delivery struct {
+	url       string
+	eventType string
+	eventID   string
+	payload   []byte
+}
+
+// sender signs and sends the deliveries
+type sender struct {
+	config *Config
+	client *http.Client
+}
+
+func newSender(config *Config) *sender {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.TLSConfig != nil {
+		transport.TLSClientConfig = config.TLSConfig
+	}
+	return &sender{
+		config: config,
+		client: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: transport,
+		},
+	}
+}
+
+// Sign returns the signature for the given timestamp, as unix seconds, and
+// payload. The receivers can compute the same value to verify the deliveries
+func Sign(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *sender) send(ctx context.Context, d *delivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(d.payload))
+	if err != nil {
+		return &deliveryError{err: err}
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", s.config.UserAgent)
+	req.Header.Set(HeaderEventType, d.eventType)
+	req.Header.Set(HeaderEventID, d.eventID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(s.config.Secret, timestamp, d.payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return &deliveryError{err: err, retriable: true}
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	retriable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode == http.StatusRequestTimeout
+	return &deliveryError{
+		err:       fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, bytes.TrimSpace(body)),
+		retriable: retriable,
+	}
+}
+
+func (s *sender) getBackoff(retry int) time.Duration {
+	backoff := s.config.InitialBackoff
+	for i := 1; i < retry && backoff < s.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, s.config.MaxBackoff)
+}
+
+// deliver sends the given delivery retrying on temporary errors, it returns
+// the number of attempts and the last error, if any
+func (s *sender) deliver(ctx context.Context, d *delivery) (int, error) {
+	var err error
+	attempts := 0
+	for retry := 0; retry <= s.config.MaxRetries; retry++ {
+		if retry > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(s.getBackoff(retry)):
+			}
+		}
+		if ctx.Err() != nil {
+			return attempts, errors.Join(err, ctx.Err())
+		}
+		attempts++
+		err = s.send(ctx, d)
+		if err == nil {
+			return attempts, nil
+		}
+		var dErr *deliveryError
+		if errors.As(err, &dErr) && !dErr.retriable {
+			break
+		}
+	}
+	return attempts, err
+}
+
+// Sink delivers the matching events to the configured URLs, the deliveries are
+// asynchronous so the event notifications are never blocked
+type Sink struct {
+	config *Config
+	sender *sender
+	queue  chan *delivery
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.RWMutex
+	closed bool
+	// saveDeadLetter allows to replace the dead letters store within test cases
+	saveDeadLetter func(*db.WebhookDeadLetter) error
+}
+
+// NewSink returns a new webhook sink and starts the delivery workers
+func NewSink(config Config) (*Sink, error) {
+	if err := config.validate(true); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Sink{
+		config:         &config,
+		sender:         newSender(&config),
+		queue:          make(chan *delivery, config.QueueSize),
+		ctx:            ctx,
+		cancel:         cancel,
+		saveDeadLetter: db.SaveWebhookDeadLetter,
+	}
+	for i := 0; i < config.Workers; i++ {
+		s.wg.Add(1)
+		go s.worker()
+	}
+	return s, nil
+}
+
+// Name implements db.Sink
+func (s *Sink) Name() string {
+	return "webhook"
+}
+
+func (s *Sink) worker() {
+	defer s.wg.Done()
+
+	for d := range s.queue {
+		attempts, err := s.sender.deliver(s.ctx, d)
+		if err != nil {
+			s.addDeadLetter(d, attempts, err)
+		}
+	}
+}
+
+func (s *Sink) addDeadLetter(d *delivery, attempts int, err error) {
+	logger.AppLogger.Warn("webhook delivery failed", "url", d.url, "event type", d.eventType,
+		"event id", d.eventID, "attempts", attempts, "error", err)
+	deadLetter := &db.WebhookDeadLetter{
+		Timestamp: time.Now().UnixNano(),
+		URL:       d.url,
+		EventType: d.eventType,
+		EventID:   d.eventID,
+		Payload:   d.payload,
+		Attempts:  attempts,
+		LastError: err.Error(),
+	}
+	if err := s.saveDeadLetter(deadLetter); err != nil {
+		logger.AppLogger.Error("unable to save webhook dead letter", "url", d.url, "event id", d.eventID,
+			"error", err)
+	}
+}
+
+func (s *Sink) enqueue(eventType, eventID string, ev any) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return errors.New("webhook sink closed")
+	}
+	for _, url := range s.config.URLs {
+		d := &delivery{
+			url:       url,
+			eventType: eventType,
+			eventID:   eventID,
+			payload:   payload,
+		}
+		select {
+		case s.queue <- d:
+		default:
+			s.addDeadLetter(d, 0, errors.New("delivery queue full"))
+		}
+	}
+	return nil
+}
+
+// WriteFsEvent implements db.Sink
+func (s *Sink) WriteFsEvent(ev *db.FsEvent) error {
+	return s.enqueue(EventTypeFs, ev.ID, ev)
+}
+
+// WriteProviderEvent implements db.Sink
+func (s *Sink) WriteProviderEvent(ev *db.ProviderEvent) error {
+	return s.enqueue(EventTypeProvider, ev.ID, ev)
+}
+
+// WriteLogEvent implements db.Sink
+func (s *Sink) WriteLogEvent(ev *db.LogEvent) error {
+	return s.enqueue(EventTypeLog, ev.ID, ev)
+}
+
+// WriteAlert delivers the given alert
+func (s *Sink) WriteAlert(alert *db.Alert) error {
+	return s.enqueue(EventTypeAlert, alert.ID, alert)
+}
+
+// Close stops accepting new events and waits for the pending deliveries until
+// the given timeout expires, the pending retries are then aborted and saved
+// as dead letters
+func (s *Sink) Close(timeout time.Duration) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+
+	timer := time.AfterFunc(timeout, s.cancel)
+	defer timer.Stop()
+
+	s.wg.Wait()
+	s.cancel()
+	return nil
+}
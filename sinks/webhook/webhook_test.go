@@ -0,0 +1,359 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+)
+
+const testSecret = "webhook secret"
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// testReceiver records the received requests and replies using the
+// configured status codes, in order, 200 is used when the list is empty
+type testReceiver struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests []receivedRequest
+}
+
+func newTestReceiver(t *testing.T, statuses ...int) *testReceiver {
+	r := &testReceiver{statuses: statuses}
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.mu.Lock()
+		r.requests = append(r.requests, receivedRequest{header: req.Header.Clone(), body: body})
+		status := http.StatusOK
+		if len(r.statuses) > 0 {
+			status = r.statuses[0]
+			r.statuses = r.statuses[1:]
+		}
+		r.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func (r *testReceiver) getRequests() []receivedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]receivedRequest{}, r.requests...)
+}
+
+type deadLetterStore struct {
+	mu          sync.Mutex
+	deadLetters []db.WebhookDeadLetter
+}
+
+func (s *deadLetterStore) save(d *db.WebhookDeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d.ID = strconv.Itoa(len(s.deadLetters) + 1)
+	s.deadLetters = append(s.deadLetters, *d)
+	return nil
+}
+
+func (s *deadLetterStore) get() []db.WebhookDeadLetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]db.WebhookDeadLetter{}, s.deadLetters...)
+}
+
+func newTestSink(t *testing.T, config Config) (*Sink, *deadLetterStore) {
+	config.Secret = testSecret
+	config.InitialBackoff = 10 * time.Millisecond
+	config.MaxBackoff = 40 * time.Millisecond
+	sink, err := NewSink(config)
+	require.NoError(t, err)
+	store := &deadLetterStore{}
+	sink.saveDeadLetter = store.save
+	return sink, store
+}
+
+func checkSignature(t *testing.T, r receivedRequest) {
+	timestamp := r.header.Get(HeaderTimestamp)
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	require.NoError(t, err)
+	assert.InDelta(t, time.Now().Unix(), ts, 60)
+	assert.Equal(t, Sign(testSecret, timestamp, r.body), r.header.Get(HeaderSignature))
+	assert.NotEqual(t, Sign("wrong secret", timestamp, r.body), r.header.Get(HeaderSignature))
+}
+
+func TestValidation(t *testing.T) {
+	_, err := NewSink(Config{Secret: testSecret})
+	assert.Error(t, err)
+	_, err = NewSink(Config{URLs: []string{"http://127.0.0.1"}})
+	assert.ErrorContains(t, err, "secret is required")
+	_, err = NewSink(Config{URLs: []string{"http://127.0.0.1"}, Secret: testSecret, MaxRetries: -1})
+	assert.ErrorContains(t, err, "invalid max retries")
+
+	c := Config{URLs: []string{"http://127.0.0.1"}, Secret: testSecret}
+	require.NoError(t, c.validate(true))
+	assert.Equal(t, time.Second, c.InitialBackoff)
+	assert.Equal(t, 5*time.Minute, c.MaxBackoff)
+	assert.Equal(t, 1000, c.QueueSize)
+	assert.Equal(t, 4, c.Workers)
+
+	s := newSender(&Config{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second})
+	assert.Equal(t, time.Second, s.getBackoff(1))
+	assert.Equal(t, 2*time.Second, s.getBackoff(2))
+	assert.Equal(t, 4*time.Second, s.getBackoff(3))
+	assert.Equal(t, 5*time.Second, s.getBackoff(4))
+	assert.Equal(t, 5*time.Second, s.getBackoff(40))
+}
+
+func TestSink(t *testing.T) {
+	receiver1 := newTestReceiver(t)
+	receiver2 := newTestReceiver(t)
+	sink, store := newTestSink(t, Config{
+		URLs:      []string{receiver1.server.URL, receiver2.server.URL},
+		UserAgent: "test-agent",
+	})
+	assert.Equal(t, "webhook", sink.Name())
+
+	fsEvent := &db.FsEvent{
+		ID:          "fs-id",
+		Timestamp:   time.Now().UnixNano(),
+		Action:      "upload",
+		Username:    "user",
+		VirtualPath: "/file.txt",
+		Status:      1,
+	}
+	logEvent := &db.LogEvent{
+		ID:        "log-id",
+		Timestamp: time.Now().UnixNano(),
+		Event:     1,
+	}
+	require.NoError(t, sink.WriteFsEvent(fsEvent))
+	require.NoError(t, sink.WriteLogEvent(logEvent))
+	alert := &db.Alert{
+		ID:        "alert-id",
+		Timestamp: time.Now().UnixNano(),
+		Rule:      "ip_login_failures",
+		Subject:   "127.0.0.1",
+		Events:    10,
+	}
+	require.NoError(t, sink.WriteAlert(alert))
+	require.NoError(t, sink.Close(5*time.Second))
+	assert.Error(t, sink.WriteFsEvent(fsEvent))
+	assert.NoError(t, sink.Close(5*time.Second))
+	assert.Empty(t, store.get())
+
+	for _, receiver := range []*testReceiver{receiver1, receiver2} {
+		requests := receiver.getRequests()
+		require.Len(t, requests, 3)
+		byType := make(map[string]receivedRequest)
+		for _, r := range requests {
+			checkSignature(t, r)
+			assert.Equal(t, "application/json", r.header.Get("Content-Type"))
+			assert.Equal(t, "test-agent", r.header.Get("User-Agent"))
+			byType[r.header.Get(HeaderEventType)] = r
+		}
+		require.Contains(t, byType, EventTypeFs)
+		assert.Equal(t, fsEvent.ID, byType[EventTypeFs].header.Get(HeaderEventID))
+		var fsEventReceived db.FsEvent
+		require.NoError(t, json.Unmarshal(byType[EventTypeFs].body, &fsEventReceived))
+		assert.Equal(t, *fsEvent, fsEventReceived)
+
+		require.Contains(t, byType, EventTypeLog)
+		assert.Equal(t, logEvent.ID, byType[EventTypeLog].header.Get(HeaderEventID))
+		var logEventReceived db.LogEvent
+		require.NoError(t, json.Unmarshal(byType[EventTypeLog].body, &logEventReceived))
+		assert.Equal(t, *logEvent, logEventReceived)
+
+		require.Contains(t, byType, EventTypeAlert)
+		assert.Equal(t, alert.ID, byType[EventTypeAlert].header.Get(HeaderEventID))
+		var alertReceived db.Alert
+		require.NoError(t, json.Unmarshal(byType[EventTypeAlert].body, &alertReceived))
+		assert.Equal(t, *alert, alertReceived)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	receiver := newTestReceiver(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	sink, store := newTestSink(t, Config{
+		URLs:       []string{receiver.server.URL},
+		MaxRetries: 2,
+	})
+	require.NoError(t, sink.WriteProviderEvent(&db.ProviderEvent{ID: "provider-id", Action: "add"}))
+	require.NoError(t, sink.Close(5*time.Second))
+	assert.Empty(t, store.get())
+	requests := receiver.getRequests()
+	require.Len(t, requests, 3)
+	for _, r := range requests {
+		assert.Equal(t, "provider-id", r.header.Get(HeaderEventID))
+		checkSignature(t, r)
+	}
+}
+
+func TestDeadLetters(t *testing.T) {
+	receiver := newTestReceiver(t, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusInternalServerError, http.StatusBadRequest)
+	sink, store := newTestSink(t, Config{
+		URLs:       []string{receiver.server.URL},
+		MaxRetries: 2,
+		Workers:    1,
+	})
+	// retries exhausted
+	require.NoError(t, sink.WriteFsEvent(&db.FsEvent{ID: "id1", Action: "upload"}))
+	// not retriable status code
+	require.NoError(t, sink.WriteFsEvent(&db.FsEvent{ID: "id2", Action: "upload"}))
+	require.NoError(t, sink.Close(5*time.Second))
+	assert.Len(t, receiver.getRequests(), 4)
+
+	deadLetters := store.get()
+	require.Len(t, deadLetters, 2)
+	assert.Equal(t, receiver.server.URL, deadLetters[0].URL)
+	assert.Equal(t, EventTypeFs, deadLetters[0].EventType)
+	assert.Equal(t, "id1", deadLetters[0].EventID)
+	assert.Equal(t, 3, deadLetters[0].Attempts)
+	assert.Contains(t, deadLetters[0].LastError, "500")
+	var ev db.FsEvent
+	require.NoError(t, json.Unmarshal(deadLetters[0].Payload, &ev))
+	assert.Equal(t, "id1", ev.ID)
+	assert.Equal(t, "id2", deadLetters[1].EventID)
+	assert.Equal(t, 1, deadLetters[1].Attempts)
+	assert.Contains(t, deadLetters[1].LastError, "400")
+}
+
+func TestNonBlocking(t *testing.T) {
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		<-unblock
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sink, store := newTestSink(t, Config{
+		URLs:      []string{server.URL},
+		QueueSize: 1,
+		Workers:   1,
+	})
+	// the first event is delivered by the worker, the second one is queued
+	// and the third one does not fit into the queue
+	require.NoError(t, sink.WriteLogEvent(&db.LogEvent{ID: "id1"}))
+	assert.Eventually(t, func() bool {
+		return len(sink.queue) == 0
+	}, 2*time.Second, 10*time.Millisecond)
+	require.NoError(t, sink.WriteLogEvent(&db.LogEvent{ID: "id2"}))
+	require.NoError(t, sink.WriteLogEvent(&db.LogEvent{ID: "id3"}))
+	deadLetters := store.get()
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, "id3", deadLetters[0].EventID)
+	assert.Equal(t, 0, deadLetters[0].Attempts)
+	assert.Equal(t, "delivery queue full", deadLetters[0].LastError)
+
+	// the pending delivery is aborted and saved as dead letter
+	require.NoError(t, sink.Close(100*time.Millisecond))
+	close(unblock)
+	deadLetters = store.get()
+	require.Len(t, deadLetters, 3)
+	assert.Equal(t, "id1", deadLetters[1].EventID)
+	assert.Equal(t, 1, deadLetters[1].Attempts)
+	assert.Equal(t, "id2", deadLetters[2].EventID)
+	assert.Equal(t, 0, deadLetters[2].Attempts)
+}
+
+func TestReplay(t *testing.T) {
+	receiver := newTestReceiver(t, http.StatusOK, http.StatusBadRequest)
+	config := &Config{Secret: testSecret}
+	require.NoError(t, config.validate(false))
+
+	var deadLetters []db.WebhookDeadLetter
+	for i := 1; i <= 5; i++ {
+		deadLetters = append(deadLetters, db.WebhookDeadLetter{
+			ID:        strconv.Itoa(i),
+			URL:       receiver.server.URL,
+			EventType: EventTypeLog,
+			EventID:   "event" + strconv.Itoa(i),
+			Payload:   []byte(`{"id":"event` + strconv.Itoa(i) + `"}`),
+			Attempts:  1,
+		})
+	}
+	var deleted []string
+	updated := make(map[string]db.WebhookDeadLetter)
+	r := newReplayer(config)
+	r.getDeadLetters = func(after string, limit int, ids []string) ([]db.WebhookDeadLetter, error) {
+		var result []db.WebhookDeadLetter
+		for _, d := range deadLetters {
+			if d.ID > after && (len(ids) == 0 || d.ID == ids[0] || d.ID == ids[len(ids)-1]) && len(result) < limit {
+				result = append(result, d)
+			}
+		}
+		return result, nil
+	}
+	r.updateDeadLetter = func(d *db.WebhookDeadLetter) error {
+		updated[d.ID] = *d
+		return nil
+	}
+	r.deleteDeadLetter = func(id string) error {
+		deleted = append(deleted, id)
+		return nil
+	}
+
+	result, err := r.replay([]string{"2", "4"}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, ReplayResult{Delivered: 1, Failed: 1}, result)
+	assert.Equal(t, []string{"2"}, deleted)
+	require.Contains(t, updated, "4")
+	assert.Equal(t, 2, updated["4"].Attempts)
+	assert.Contains(t, updated["4"].LastError, "400")
+	assert.NotZero(t, updated["4"].Timestamp)
+	requests := receiver.getRequests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, "event2", requests[0].header.Get(HeaderEventID))
+	assert.Equal(t, EventTypeLog, requests[0].header.Get(HeaderEventType))
+	assert.Equal(t, `{"id":"event2"}`, string(requests[0].body))
+	checkSignature(t, requests[0])
+
+	deleted = nil
+	result, err = r.replay(nil, 3)
+	require.NoError(t, err)
+	assert.Equal(t, ReplayResult{Delivered: 3}, result)
+	assert.Equal(t, []string{"1", "2", "3"}, deleted)
+
+	r.deleteDeadLetter = func(_ string) error {
+		return errors.New("delete error")
+	}
+	_, err = r.replay(nil, 0)
+	assert.ErrorContains(t, err, "delete error")
+}
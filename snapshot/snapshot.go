@@ -0,0 +1,130 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package snapshot reconstructs the provider objects as of a point in time
+package snapshot
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+	"github.com/sftpgo/sftpgo-plugin-eventstore/logger"
+)
+
+// dumpKeys maps the provider object types to the keys used in the SFTPGo
+// dump/restore format
+var dumpKeys = map[string]string{
+	"user":          "users",
+	"folder":        "folders",
+	"group":         "groups",
+	"admin":         "admins",
+	"api_key":       "api_keys",
+	"share":         "shares",
+	"event_action":  "event_actions",
+	"event_rule":    "event_rules",
+	"role":          "roles",
+	"ip_list_entry": "ip_lists",
+	"configs":       "configs",
+}
+
+// Object defines the state of a provider object at the snapshot time
+type Object struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	// LastAction is the action of the last event before the snapshot time
+	LastAction   string    `json:"last_action"`
+	LastModified time.Time `json:"last_modified"`
+	ModifiedBy   string    `json:"modified_by,omitempty"`
+	// Data is the object as sent by SFTPGo, it is null if the stored data
+	// is not valid JSON
+	Data json.RawMessage `json:"data"`
+}
+
+// Snapshot defines the provider objects existing at a point in time
+type Snapshot struct {
+	Time    time.Time `json:"time"`
+	Objects []Object  `json:"objects"`
+}
+
+// Get returns the provider objects existing at the specified time. The objects
+// can be filtered by type and name, empty filters match all objects. Only the
+// stored events are considered, so objects not modified within the retention
+// period are not included
+func Get(at time.Time, objectTypes, objectNames []string) (*Snapshot, error) {
+	events, err := db.GetProviderObjectsAt(at, objectTypes, objectNames)
+	if err != nil {
+		return nil, err
+	}
+	return New(at, events), nil
+}
+
+// New returns a snapshot from the last event for each object, as returned by
+// db.GetProviderObjectsAt
+func New(at time.Time, events []db.ProviderEvent) *Snapshot {
+	s := &Snapshot{
+		Time:    at.UTC(),
+		Objects: make([]Object, 0, len(events)),
+	}
+	for _, ev := range events {
+		obj := Object{
+			Type:         ev.ObjectType,
+			Name:         ev.ObjectName,
+			LastAction:   ev.Action,
+			LastModified: time.Unix(0, ev.Timestamp).UTC(),
+			ModifiedBy:   ev.Username,
+		}
+		if json.Valid(ev.ObjectData) {
+			obj.Data = ev.ObjectData
+		}
+		s.Objects = append(s.Objects, obj)
+	}
+	return s
+}
+
+// Write writes the snapshot as JSON
+func (s *Snapshot) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// WriteDump writes the snapshot in the SFTPGo dump/restore format. The
+// version is added to the dump if greater than 0, it should match the dump
+// version of the SFTPGo instance the dump is restored to. Objects without
+// valid data and unsupported object types are skipped
+func (s *Snapshot) WriteDump(w io.Writer, version int) error {
+	dump := make(map[string]any)
+	for _, obj := range s.Objects {
+		key, ok := dumpKeys[obj.Type]
+		if !ok || obj.Data == nil {
+			logger.AppLogger.Warn("object not included in the dump", "type", obj.Type, "name", obj.Name,
+				"supported type", ok)
+			continue
+		}
+		if key == "configs" {
+			dump[key] = obj.Data
+			continue
+		}
+		objects, _ := dump[key].([]json.RawMessage)
+		dump[key] = append(objects, obj.Data)
+	}
+	if version > 0 {
+		dump["version"] = version
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(dump)
+}
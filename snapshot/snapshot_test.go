@@ -0,0 +1,122 @@
+// Copyright (C) 2026 Nicola Murino
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sftpgo/sftpgo-plugin-eventstore/db"
+)
+
+func getTestSnapshot() *Snapshot {
+	at := time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC)
+	ts := at.Add(-time.Hour).UnixNano()
+	return New(at, []db.ProviderEvent{
+		{
+			Timestamp:  ts,
+			Action:     "update",
+			Username:   "admin",
+			ObjectType: "admin",
+			ObjectName: "admin",
+			ObjectData: []byte(`{"username":"admin"}`),
+		},
+		{
+			Timestamp:  ts,
+			Action:     "add",
+			Username:   "admin",
+			ObjectType: "configs",
+			ObjectName: "configs",
+			ObjectData: []byte(`{"sftpd":{}}`),
+		},
+		{
+			Timestamp:  ts,
+			Action:     "add",
+			ObjectType: "unsupported",
+			ObjectName: "obj",
+			ObjectData: []byte(`{"name":"obj"}`),
+		},
+		{
+			Timestamp:  ts,
+			Action:     "update",
+			Username:   "admin",
+			ObjectType: "user",
+			ObjectName: "user1",
+			ObjectData: []byte(`{"username":"user1","permissions":{"/":["*"]}}`),
+		},
+		{
+			Timestamp:  ts + 1,
+			Action:     "add",
+			Username:   "admin",
+			ObjectType: "user",
+			ObjectName: "user2",
+			ObjectData: []byte(`invalid json`),
+		},
+		{
+			Timestamp:  ts + 2,
+			Action:     "add",
+			Username:   "admin",
+			ObjectType: "user",
+			ObjectName: "user3",
+			ObjectData: []byte(`{"username":"user3"}`),
+		},
+	})
+}
+
+func TestWrite(t *testing.T) {
+	s := getTestSnapshot()
+	require.Len(t, s.Objects, 6)
+	assert.Nil(t, s.Objects[4].Data)
+
+	var buf bytes.Buffer
+	require.NoError(t, s.Write(&buf))
+	var snapshot Snapshot
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &snapshot))
+	assert.True(t, s.Time.Equal(snapshot.Time))
+	require.Len(t, snapshot.Objects, 6)
+	assert.Equal(t, "user1", snapshot.Objects[3].Name)
+	assert.Equal(t, "update", snapshot.Objects[3].LastAction)
+	assert.Equal(t, "admin", snapshot.Objects[3].ModifiedBy)
+	assert.True(t, s.Time.Add(-time.Hour).Equal(snapshot.Objects[3].LastModified))
+	assert.JSONEq(t, `{"username":"user1","permissions":{"/":["*"]}}`, string(snapshot.Objects[3].Data))
+	assert.Equal(t, "null", string(snapshot.Objects[4].Data))
+}
+
+func TestWriteDump(t *testing.T) {
+	s := getTestSnapshot()
+
+	var buf bytes.Buffer
+	require.NoError(t, s.WriteDump(&buf, 0))
+	assert.JSONEq(t, `{
+		"admins": [{"username":"admin"}],
+		"configs": {"sftpd":{}},
+		"users": [{"username":"user1","permissions":{"/":["*"]}},{"username":"user3"}]
+	}`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, s.WriteDump(&buf, 17))
+	var dump map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &dump))
+	assert.Equal(t, "17", string(dump["version"]))
+
+	buf.Reset()
+	require.NoError(t, New(s.Time, nil).WriteDump(&buf, 0))
+	assert.JSONEq(t, `{}`, buf.String())
+}